@@ -7,24 +7,176 @@ import (
 const (
 	// TIMESTAMP_STR_FORMAT string = "yyyy-mm-ddThh:mm:ssZhh:mm"
 	// TIMESTAMP_STR_FORMAT string = "2006-01-02T15:04:05-07:00"
-	TIMESTAMP_STR_FORMAT        string = time.RFC3339
-	DEFAULT_TIMEZONE            string = "GMT-3"
-	GIN_CTX_JWT_CLAIM_KEY_NAME  string = "jwtClaims"
-	JWT_TIMEOUT_SECS            int    = 30 * 60
-	OTP_LEN                     int    = 128
-	ORG_INVITE_TIMEOUT_DAYS     int    = 15
-	PASSWORD_RESET_TIMEOUT_DAYS int    = 1
-	MAX_REQUEST_SIZE            int64  = 5 * 1024 * 1024 // 5MB default
+	TIMESTAMP_STR_FORMAT           string = time.RFC3339
+	DEFAULT_TIMEZONE               string = "GMT-3"
+	GIN_CTX_JWT_CLAIM_KEY_NAME     string = "jwtClaims"
+	JWT_TIMEOUT_SECS               int    = 30 * 60
+	OTP_LEN                        int    = 128
+	ORG_INVITE_TIMEOUT_DAYS        int    = 15
+	PASSWORD_RESET_TIMEOUT_DAYS    int    = 1
+	MAX_REQUEST_SIZE               int64  = 5 * 1024 * 1024 // 5MB default
+	PAYMENT_RECONCILE_MINS         int    = 30
+	PAYMENT_ABANDON_HOURS          int    = 24
+	PROMO_CODE_RESERVATION_MINS    int    = 60
+	PAYMENT_RECONCILE_BATCH        int    = 100
+	DISCOUNT_TYPE_PERCENTAGE       string = "percentage"
+	DISCOUNT_TYPE_FIXED            string = "fixed"
+	EMAIL_PROVIDER_RESEND          string = "resend"
+	EMAIL_PROVIDER_SMTP            string = "smtp"
+	SMTP_SECURITY_NONE             string = "none"
+	SMTP_SECURITY_STARTTLS         string = "starttls"
+	SMTP_SECURITY_TLS              string = "tls"
+	SMTP_TIMEOUT_SECS              int    = 30
+	EMAIL_OUTBOX_BATCH             int    = 50
+	EMAIL_OUTBOX_MAX_ATTEMPTS      int    = 10
+	EMAIL_OUTBOX_BACKOFF_SECS      int    = 30
+	EMAIL_OUTBOX_MAX_BACKOFF_HOURS int    = 6
+	EMAIL_OUTBOX_LEASE_MINS        int    = 5
+	EMAIL_STATUS_PENDING           string = "pending"
+	EMAIL_STATUS_SENT              string = "sent"
+	EMAIL_STATUS_DEAD              string = "dead"
+	EMAIL_TEST_SUBJECT_PREFIX      string = "[TEST] "
+	EMAIL_PRIORITY_TRANSACTIONAL   int    = 0
+	EMAIL_PRIORITY_BULK            int    = 1
+
+	EMAIL_TEMPLATE_EMAIL_CONFIRMATION  string = "email-confirmation"
+	EMAIL_TEMPLATE_ACCOUNT_CREATED     string = "account-created"
+	EMAIL_TEMPLATE_ORGANIZATION_INVITE string = "organization-invite"
+	EMAIL_TEMPLATE_PASSWORD_RESET      string = "password-reset"
+	EMAIL_TEMPLATE_PAYMENT_ACCEPTED    string = "payment-accepted"
+	EMAIL_TEMPLATE_ANNOUNCEMENT        string = "announcement"
+
+	ANNOUNCEMENT_AUDIENCE_REGISTRANTS string = "registrants"
+
+	NOTIFICATION_TYPE_ORGANIZATION_INVITE string = "organization-invite"
+	NOTIFICATION_TYPE_PAYMENT_ACCEPTED    string = "payment-accepted"
+	NOTIFICATION_TYPE_EVENT_ANNOUNCEMENT  string = "event-announcement"
+	NOTIFICATION_CHANNEL_IN_APP           string = "in-app"
+	NOTIFICATION_CHANNEL_EMAIL            string = "email"
+	NOTIFICATION_CHANNEL_BOTH             string = "both"
+	DEFAULT_NOTIFICATION_CHANNEL          string = NOTIFICATION_CHANNEL_BOTH
+	NOTIFICATIONS_PAGE_SIZE               int    = 20
+
+	STREAM_PG_CHANNEL             string = "stream_events"
+	STREAM_TOPIC_USER             string = "user:"
+	STREAM_TOPIC_ORGANIZATION     string = "organization:"
+	STREAM_EVENT_NOTIFICATION     string = "notification"
+	STREAM_EVENT_REGISTRATIONS    string = "registrations"
+	STREAM_SUBSCRIBER_BUFFER      int    = 64
+	STREAM_HEARTBEAT_SECS         int    = 25
+	STREAM_PG_PING_SECS           int    = 90
+	STREAM_EVENTS_RETENTION_HOURS int    = 24
+
+	WEBHOOK_EVENT_REGISTRATION_CREATED string = "registration.created"
+	WEBHOOK_EVENT_PAYMENT_COMPLETED    string = "payment.completed"
+	WEBHOOK_EVENT_EVENT_PUBLISHED      string = "event.published"
+	WEBHOOK_STATUS_PENDING             string = "pending"
+	WEBHOOK_STATUS_DELIVERED           string = "delivered"
+	WEBHOOK_STATUS_DEAD                string = "dead"
+	WEBHOOK_SECRET_PREFIX              string = "whsec_"
+	WEBHOOK_SECRET_LEN                 int    = 32
+	WEBHOOK_SIGNATURE_HEADER           string = "X-Webhook-Signature"
+	WEBHOOK_EVENT_HEADER               string = "X-Webhook-Event"
+	WEBHOOK_DELIVERY_HEADER            string = "X-Webhook-Delivery"
+	WEBHOOK_TIMEOUT_SECS               int    = 10
+	WEBHOOK_BATCH                      int    = 50
+	WEBHOOK_MAX_ATTEMPTS               int    = 8
+	WEBHOOK_BACKOFF_SECS               int    = 60
+	WEBHOOK_MAX_BACKOFF_HOURS          int    = 12
+	WEBHOOK_LEASE_MINS                 int    = 5
+	WEBHOOK_DELIVERIES_PAGE_SIZE       int    = 50
+
+	OBJECT_STORAGE_S3          string = "s3"
+	OBJECT_STORAGE_FILESYSTEM  string = "filesystem"
+	OBJECT_URL_EXP_PARAM       string = "exp"
+	OBJECT_URL_SIGNATURE_PARAM string = "sig"
+	OBJECT_URL_SIZE_PARAM      string = "size"
+
+	UPLOAD_PURPOSE_AVATAR       string = "avatar"
+	UPLOAD_PURPOSE_EVENT_BANNER string = "event-banner"
+	UPLOAD_URL_EXP_MINS         int    = 15
+	UPLOAD_AVATAR_MAX_BYTES     int64  = 2 * 1024 * 1024
+	UPLOAD_BANNER_MAX_BYTES     int64  = 10 * 1024 * 1024
+
+	IMAGE_VARIANT_THUMBNAIL string = "thumbnail"
+	IMAGE_VARIANT_MEDIUM    string = "medium"
+	IMAGE_VARIANT_FULL      string = "full"
+	IMAGE_THUMBNAIL_SIZE    int    = 160
+	IMAGE_MEDIUM_SIZE       int    = 640
+	IMAGE_FULL_SIZE         int    = 1920
+	IMAGE_MAX_PIXELS        int    = 40_000_000
+	IMAGE_WEBP_QUALITY      int    = 80
+	IMAGE_JPEG_QUALITY      int    = 85
+
+	ATTACHMENT_VISIBILITY_PUBLIC   string = "public"
+	ATTACHMENT_VISIBILITY_PRIVATE  string = "private"
+	ATTACHMENT_MAX_BYTES           int64  = 50 * 1024 * 1024
+	ATTACHMENT_DEFAULT_QUOTA_BYTES int64  = 1024 * 1024 * 1024
+	ATTACHMENT_URL_EXP_MINS        int    = 15
+
+	OBJECT_GC_PREFIX_PUBLIC  string = "public/"
+	OBJECT_GC_PREFIX_PRIVATE string = "private/"
+
+	REQUEST_ID_HEADER          string = "X-Request-Id"
+	GIN_CTX_REQUEST_ID_KEY     string = "requestId"
+	PROBLEM_CONTENT_TYPE       string = "application/problem+json"
+	PROBLEM_TYPE_DEFAULT       string = "about:blank"
+	PROBLEM_CODE_BAD_REQUEST   string = "badRequest"
+	PROBLEM_CODE_VALIDATION    string = "validationFailed"
+	PROBLEM_CODE_UNAUTHORIZED  string = "unauthorized"
+	PROBLEM_CODE_FORBIDDEN     string = "forbidden"
+	PROBLEM_CODE_NOT_FOUND     string = "notFound"
+	PROBLEM_CODE_CONFLICT      string = "conflict"
+	PROBLEM_CODE_FOREIGN_KEY   string = "foreignKeyViolation"
+	PROBLEM_CODE_CHECK         string = "checkViolation"
+	PROBLEM_CODE_SERIALIZATION string = "serializationFailure"
+	PROBLEM_CODE_TOO_LARGE     string = "tooLarge"
+	PROBLEM_CODE_MEDIA_TYPE    string = "unsupportedMediaType"
+	PROBLEM_CODE_QUOTA         string = "storageQuotaExceeded"
+	PROBLEM_CODE_MISMATCH      string = "uploadMismatch"
+	PROBLEM_CODE_PROMO_CODE    string = "promoCodeInvalid"
+	PROBLEM_CODE_WEBHOOK_URL   string = "webhookUrlForbidden"
+	PROBLEM_CODE_UNIMPLEMENTED string = "notImplemented"
+	PROBLEM_CODE_BAD_GATEWAY   string = "badGateway"
+	PROBLEM_CODE_INTERNAL      string = "internalError"
+
+	LOG_FORMAT_TEXT string = "text"
+	LOG_FORMAT_JSON string = "json"
+
+	LOCALE_PT_BR   string = "pt-BR"
+	LOCALE_EN      string = "en"
+	DEFAULT_LOCALE string = LOCALE_PT_BR
 )
 
 var (
-	PROJECT_NAME                           string = GetEnvVarDefault("PROJECT_NAME", "patos-app")
-	NOREPLY_EMAIL                          string = GetEnvVarDefault("NOREPLY_EMAIL", "no-reply@example.com")
-	APP_HOST_URL                           string = GetEnvVarDefault("APP_HOST_URL", "http://127.0.0.1:8080/")
-	API_HOST_URL                           string = GetEnvVarDefault("API_HOST_URL", "http://127.0.0.1:8080/")
-	JWT_COOKIE_NAME                        string = PROJECT_NAME + "_jwt"
-	PASSWORD_RESET_TIMEOUT_JWT_COOKIE_NAME string = PROJECT_NAME + "_pwreset_jwt"
-	S3_ENDPOINT                            string = GetEnvVarDefault("S3_ENDPOINT", "https://br-se1.magaluobjects.com")
-	S3_REGION                              string = GetEnvVarDefault("S3_REGION", "br-se1")
-	S3_BUCKET                              string = GetEnvVarDefault("S3_BUCKET", PROJECT_NAME+"-gopherbase")
+	PROJECT_NAME                           string   = GetEnvVarDefault("PROJECT_NAME", "patos-app")
+	NOREPLY_EMAIL                          string   = GetEnvVarDefault("NOREPLY_EMAIL", "no-reply@example.com")
+	APP_HOST_URL                           string   = GetEnvVarDefault("APP_HOST_URL", "http://127.0.0.1:8080/")
+	API_HOST_URL                           string   = GetEnvVarDefault("API_HOST_URL", "http://127.0.0.1:8080/")
+	JWT_COOKIE_NAME                        string   = PROJECT_NAME + "_jwt"
+	PASSWORD_RESET_TIMEOUT_JWT_COOKIE_NAME string   = PROJECT_NAME + "_pwreset_jwt"
+	S3_ENDPOINT                            string   = GetEnvVarDefault("S3_ENDPOINT", "https://br-se1.magaluobjects.com")
+	S3_REGION                              string   = GetEnvVarDefault("S3_REGION", "br-se1")
+	S3_BUCKET                              string   = GetEnvVarDefault("S3_BUCKET", PROJECT_NAME+"-gopherbase")
+	PAYMENT_PROVIDER                       string   = GetEnvVarDefault("PAYMENT_PROVIDER", "stripe")
+	EMAIL_PROVIDER                         string   = GetEnvVarDefault("EMAIL_PROVIDER", EMAIL_PROVIDER_RESEND)
+	OBJECT_STORAGE_PROVIDER                string   = GetEnvVarDefault("OBJECT_STORAGE_PROVIDER", OBJECT_STORAGE_S3)
+	SUPPORTED_LOCALES                      []string = []string{LOCALE_PT_BR, LOCALE_EN}
+	NOTIFICATION_TYPES                     []string = []string{NOTIFICATION_TYPE_ORGANIZATION_INVITE, NOTIFICATION_TYPE_PAYMENT_ACCEPTED, NOTIFICATION_TYPE_EVENT_ANNOUNCEMENT}
+	WEBHOOK_EVENT_TYPES                    []string = []string{WEBHOOK_EVENT_REGISTRATION_CREATED, WEBHOOK_EVENT_PAYMENT_COMPLETED, WEBHOOK_EVENT_EVENT_PUBLISHED}
+	UPLOAD_IMAGE_CONTENT_TYPES             []string = []string{"image/png", "image/jpeg"}
+	IMAGE_VARIANTS                         []string = []string{IMAGE_VARIANT_THUMBNAIL, IMAGE_VARIANT_MEDIUM, IMAGE_VARIANT_FULL}
+	PLATFORM_ADMIN_EMAILS                  string   = GetEnvVarDefault("PLATFORM_ADMIN_EMAILS", "")
+	METRICS_ADDR                           string   = GetEnvVarDefault("METRICS_ADDR", "")
+	METRICS_TOKEN                          string   = GetEnvVarDefault("METRICS_TOKEN", "")
+
+	// declared content type of the attachments to the one sniffed from their data,
+	// office documents are zip files
+	ATTACHMENT_CONTENT_TYPES map[string]string = map[string]string{
+		"application/pdf": "application/pdf",
+		"image/png":       "image/png",
+		"image/jpeg":      "image/jpeg",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation": "application/zip",
+		"application/vnd.oasis.opendocument.presentation":                           "application/zip",
+	}
 )
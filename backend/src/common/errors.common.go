@@ -3,6 +3,38 @@ package common
 import "errors"
 
 var (
-	ErrAuth       = errors.New("authError")
-	ErrDbConflict = errors.New("dbConflictError")
+	ErrAuth = errors.New("authError")
+
+	// database errors, see FilterSqlPgError
+	ErrNotFound       = errors.New("notFoundError")
+	ErrConflict       = errors.New("conflictError")
+	ErrForeignKey     = errors.New("foreignKeyError")
+	ErrCheckViolation = errors.New("checkViolationError")
+	ErrSerialization  = errors.New("serializationError")
+
+	ErrPromoCodeInvalid   = errors.New("promoCodeInvalidError")
+	ErrPayoutsUnsupported = errors.New("payoutsUnsupportedError")
+
+	ErrSmtpStartTlsUnsupported = errors.New("smtpStartTlsUnsupportedError")
+	ErrEmailTemplateNotFound   = errors.New("emailTemplateNotFoundError")
+
+	ErrWebhookUrlForbidden = errors.New("webhookUrlForbiddenError")
+
+	ErrAnnouncementAudienceInvalid = errors.New("announcementAudienceInvalidError")
+
+	ErrObjectNotFound         = errors.New("objectNotFoundError")
+	ErrObjectPathInvalid      = errors.New("objectPathInvalidError")
+	ErrObjectSignatureInvalid = errors.New("objectSignatureInvalidError")
+
+	ErrUploadTooLarge           = errors.New("uploadTooLargeError")
+	ErrUploadContentTypeInvalid = errors.New("uploadContentTypeInvalidError")
+	ErrUploadMismatch           = errors.New("uploadMismatchError")
+
+	ErrImageFormatInvalid = errors.New("imageFormatInvalidError")
+	ErrImageTooLarge      = errors.New("imageTooLargeError")
+
+	ErrStorageQuotaExceeded = errors.New("storageQuotaExceededError")
+
+	ErrMigrationInvalid        = errors.New("migrationInvalidError")
+	ErrMigrationCommandUnknown = errors.New("migrationCommandUnknownError")
 )
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerCtxKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger, handlers and services log
+// through `Logger(ctx)` so their records share the request attributes
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, logger)
+}
+
+// Logger returns the logger of ctx, or the default one outside of a request
+func Logger(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerCtxKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+
+	return slog.Default()
+}
@@ -1,28 +1,71 @@
 package common
 
 import (
-	"strings"
-)
+	"database/sql"
+	"errors"
+	"fmt"
 
-const (
-	errUniqueConstraint string = "duplicate key value violates unique constraint"
-	errNoRows           string = "no rows in result"
+	"github.com/lib/pq"
 )
 
-var (
-	conflictErrStrings []string = []string{errUniqueConstraint, errNoRows}
-)
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+var pgErrorKinds = map[pq.ErrorCode]error{
+	"23505": ErrConflict,       // unique_violation
+	"23P01": ErrConflict,       // exclusion_violation
+	"23503": ErrForeignKey,     // foreign_key_violation
+	"23514": ErrCheckViolation, // check_violation
+	"23502": ErrCheckViolation, // not_null_violation
+	"40001": ErrSerialization,  // serialization_failure
+	"40P01": ErrSerialization,  // deadlock_detected
+	// malformed ids, like a path param that is not an UUID, match no row
+	"22P02": ErrNotFound, // invalid_text_representation
+}
+
+// DbError is a database error classified as one of ErrNotFound, ErrConflict,
+// ErrForeignKey, ErrCheckViolation or ErrSerialization, errors.Is matches the
+// kind and Constraint is the violated constraint, if any
+type DbError struct {
+	Kind       error
+	Constraint string
+	Err        error
+}
+
+func (e *DbError) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Kind, e.Constraint, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Err)
+}
 
+func (e *DbError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *DbError) Unwrap() error {
+	return e.Err
+}
+
+// FilterSqlPgError classifies sql.ErrNoRows and the *pq.Error codes into a
+// *DbError, other errors are returned as they are
 func FilterSqlPgError(err error) error {
 	if err == nil {
 		return nil
 	}
 
-	errStr := err.Error()
+	var dbErr *DbError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &DbError{Kind: ErrNotFound, Err: err}
+	}
 
-	for _, v := range conflictErrStrings {
-		if strings.Contains(errStr, v) {
-			return ErrDbConflict
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		kind, ok := pgErrorKinds[pqErr.Code]
+		if ok {
+			return &DbError{Kind: kind, Constraint: pqErr.Constraint, Err: err}
 		}
 	}
 
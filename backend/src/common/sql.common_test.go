@@ -0,0 +1,58 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestFilterSqlPgError(t *testing.T) {
+	otherErr := errors.New("other")
+
+	tests := []struct {
+		name           string
+		err            error
+		want           error
+		wantConstraint string
+	}{
+		{"nil", nil, nil, ""},
+		{"no rows", sql.ErrNoRows, ErrNotFound, ""},
+		{"wrapped no rows", fmt.Errorf("scan: %w", sql.ErrNoRows), ErrNotFound, ""},
+		{"unique", &pq.Error{Code: "23505", Constraint: "users_email_key"}, ErrConflict, "users_email_key"},
+		{"foreign key", &pq.Error{Code: "23503", Constraint: "fk_payments_event"}, ErrForeignKey, "fk_payments_event"},
+		{"check", &pq.Error{Code: "23514", Constraint: "promo_codes_check"}, ErrCheckViolation, "promo_codes_check"},
+		{"not null", &pq.Error{Code: "23502"}, ErrCheckViolation, ""},
+		{"serialization", &pq.Error{Code: "40001"}, ErrSerialization, ""},
+		{"deadlock", &pq.Error{Code: "40P01"}, ErrSerialization, ""},
+		{"unmapped code", &pq.Error{Code: "42P01"}, nil, ""},
+		{"other", otherErr, otherErr, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterSqlPgError(tt.err)
+			if tt.err == nil {
+				if got != nil {
+					t.Errorf("FilterSqlPgError() = %v, want nil", got)
+				}
+				return
+			}
+			if tt.want == nil {
+				if got != tt.err {
+					t.Errorf("FilterSqlPgError() = %v, want it unchanged", got)
+				}
+				return
+			}
+			if !errors.Is(got, tt.want) {
+				t.Errorf("FilterSqlPgError() = %v, want %v", got, tt.want)
+			}
+
+			var dbErr *DbError
+			if errors.As(got, &dbErr) && dbErr.Constraint != tt.wantConstraint {
+				t.Errorf("FilterSqlPgError() constraint = %s, want %s", dbErr.Constraint, tt.wantConstraint)
+			}
+		})
+	}
+}
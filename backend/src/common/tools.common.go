@@ -1,14 +1,16 @@
 package common
 
 import (
+	"html/template"
 	"log/slog"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
-	"text/template"
 )
 
 var LOG_LEVEL string = strings.ToUpper(GetEnvVarDefault("LOG_LEVEL", "INFO"))
+var LOG_FORMAT string = strings.ToLower(GetEnvVarDefault("LOG_FORMAT", LOG_FORMAT_TEXT))
 
 func InitSlogger() {
 	levelsMap := map[string]slog.Level{
@@ -19,13 +21,17 @@ func InitSlogger() {
 		"ERROR":   slog.LevelError,
 	}
 
-	logger := slog.New(slog.NewTextHandler(
-		os.Stdout,
-		&slog.HandlerOptions{
-			AddSource: true,
-			Level:     levelsMap[LOG_LEVEL],
-		},
-	))
+	opts := &slog.HandlerOptions{
+		AddSource: true,
+		Level:     levelsMap[LOG_LEVEL],
+	}
+
+	var handler slog.Handler = slog.NewTextHandler(os.Stdout, opts)
+	if LOG_FORMAT == LOG_FORMAT_JSON {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	logger := slog.New(handler)
 
 	slog.SetDefault(logger)
 }
@@ -46,8 +52,10 @@ func SplitName(fullName string) (string, string) {
 	return firstName, names[1]
 }
 
-func LoadHTMLTemplate(templatePath string) *template.Template {
-	t, err := template.ParseFiles(templatePath)
+// Parses the `templatePaths` into a single template set, so layouts and partials
+// can be shared between templates. Panics on error.
+func LoadHTMLTemplate(funcs template.FuncMap, templatePaths ...string) *template.Template {
+	t, err := template.New(filepath.Base(templatePaths[0])).Funcs(funcs).ParseFiles(templatePaths...)
 	if err != nil {
 		slog.Error(err.Error())
 		panic(err)
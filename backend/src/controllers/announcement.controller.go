@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/models"
+	"github.com/patos-ufscar/quack-week/schemas"
+	"github.com/patos-ufscar/quack-week/services"
+)
+
+type AnnouncementController struct {
+	announcementService services.AnnouncementService
+}
+
+func NewAnnouncementController(
+	announcementService services.AnnouncementService,
+) AnnouncementController {
+	return AnnouncementController{
+		announcementService: announcementService,
+	}
+}
+
+// @Summary CreateAnnouncement
+// @Security JWT
+// @Tags Announcement
+// @Description Emails an announcement to the `audience` of the Event, users who opted out of announcements are skipped. Emails are queued and sent respecting the email provider rate limit.
+// @Consume application/json
+// @Accept json
+// @Produce json
+// @Param	eventId 	path string true "Event Id"
+// @Param	orgId 		path string true "Organization Id"
+// @Param   payload 	body 		schemas.CreateAnnouncement true "announcement json"
+// @Success 200 		{object} 	models.Announcement
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/events/{eventId}/organization/{orgId}/announcements [PUT]
+func (c *AnnouncementController) CreateAnnouncement(ctx *gin.Context) {
+	var createAnnouncement schemas.CreateAnnouncement
+
+	if err := ctx.ShouldBind(&createAnnouncement); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	announcement, err := c.announcementService.CreateAnnouncement(ctx, ctx.Param("orgId"), models.Announcement{
+		EventId:      ctx.Param("eventId"),
+		AuthorUserId: claims.UserId,
+		Subject:      createAnnouncement.Subject,
+		Body:         createAnnouncement.Body,
+		Audience:     createAnnouncement.Audience,
+	})
+	if err != nil {
+		if err == common.ErrAnnouncementAudienceInvalid {
+			fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, announcement)
+}
+
+// @Summary GetAnnouncements
+// @Security JWT
+// @Tags Announcement
+// @Description Gets the announcements of the Event with their delivery stats, newest first
+// @Produce json
+// @Param	eventId 	path string true "Event Id"
+// @Param	orgId 		path string true "Organization Id"
+// @Success 200 		{object} 	[]models.Announcement
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/events/{eventId}/organization/{orgId}/announcements [GET]
+func (c *AnnouncementController) GetAnnouncements(ctx *gin.Context) {
+	announcements, err := c.announcementService.GetAnnouncements(ctx, ctx.Param("orgId"), ctx.Param("eventId"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, announcements)
+}
+
+// @Summary GetAnnouncementRecipients
+// @Security JWT
+// @Tags Announcement
+// @Description Gets the recipients of the announcement and the status of their emails
+// @Produce json
+// @Param	eventId 		path string true "Event Id"
+// @Param	orgId 			path string true "Organization Id"
+// @Param	announcementId 	path string true "Announcement Id"
+// @Success 200 		{object} 	[]models.AnnouncementRecipient
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/events/{eventId}/organization/{orgId}/announcements/{announcementId}/recipients [GET]
+func (c *AnnouncementController) GetAnnouncementRecipients(ctx *gin.Context) {
+	announcementId, err := strconv.ParseUint(ctx.Param("announcementId"), 10, 64)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	recipients, err := c.announcementService.GetAnnouncementRecipients(ctx, ctx.Param("orgId"), ctx.Param("eventId"), announcementId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, recipients)
+}
+
+func (c *AnnouncementController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
+	g := rg.Group("/events/:eventId/organization/:orgId/announcements")
+
+	g.PUT("", authMiddleware.AuthorizeOrganization(true), c.CreateAnnouncement)
+	g.GET("", authMiddleware.AuthorizeOrganization(true), c.GetAnnouncements)
+	g.GET("/:announcementId/recipients", authMiddleware.AuthorizeOrganization(true), c.GetAnnouncementRecipients)
+}
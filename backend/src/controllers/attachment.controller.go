@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/models"
+	"github.com/patos-ufscar/quack-week/schemas"
+	"github.com/patos-ufscar/quack-week/services"
+)
+
+type AttachmentController struct {
+	attachmentService services.AttachmentService
+}
+
+func NewAttachmentController(
+	attachmentService services.AttachmentService,
+) AttachmentController {
+	return AttachmentController{
+		attachmentService: attachmentService,
+	}
+}
+
+// @Summary CreateAttachment
+// @Security JWT
+// @Tags Attachment
+// @Description Reserves the storage for a file of the Event, as slides or PDFs, and gets the presigned url to upload it to,
+// @Description then call the confirm endpoint. The allowed types are PDF, PNG, JPEG, PPTX and ODP.
+// @Consume application/json
+// @Accept json
+// @Produce json
+// @Param	orgId 		path string true "Organization Id"
+// @Param	eventId 	path string true "Event Id"
+// @Param   payload 	body 		schemas.CreateAttachment true "attachment json"
+// @Success 200 		{object} 	schemas.UploadUrl
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 413 		{object} 	schemas.Problem "Request Entity Too Large"
+// @Failure 415 		{object} 	schemas.Problem "Unsupported Media Type"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/events/{eventId}/attachments [PUT]
+func (c *AttachmentController) CreateAttachment(ctx *gin.Context) {
+	var createAttachment schemas.CreateAttachment
+
+	if err := ctx.ShouldBind(&createAttachment); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	attachment, uploadUrl, err := c.attachmentService.CreateAttachment(ctx, models.EventAttachment{
+		EventId:        ctx.Param("eventId"),
+		OrganizationId: *claims.OrganizationId,
+		UploadedBy:     claims.UserId,
+		FileName:       createAttachment.FileName,
+		ContentType:    createAttachment.ContentType,
+		Size:           createAttachment.Size,
+		Visibility:     createAttachment.Visibility,
+	})
+	if err != nil {
+		abortWithUploadError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schemas.UploadUrl{
+		UploadId: attachment.AttachmentId,
+		Url:      uploadUrl,
+		Method:   http.MethodPut,
+		Exp:      attachment.Exp,
+	})
+}
+
+// @Summary ConfirmAttachment
+// @Security JWT
+// @Tags Attachment
+// @Description Publishes the file uploaded to the url of CreateAttachment
+// @Produce json
+// @Param	orgId 			path string true "Organization Id"
+// @Param	eventId 		path string true "Event Id"
+// @Param	attachmentId 	path string true "Attachment Id"
+// @Success 200 		{object} 	models.EventAttachment
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 415 		{object} 	schemas.Problem "Unsupported Media Type"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/events/{eventId}/attachments/{attachmentId}/confirm [POST]
+func (c *AttachmentController) ConfirmAttachment(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	attachment, err := c.attachmentService.ConfirmAttachment(ctx, *claims.OrganizationId, ctx.Param("eventId"), ctx.Param("attachmentId"))
+	if err != nil {
+		abortWithUploadError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attachment)
+}
+
+// @Summary GetOrganizationAttachments
+// @Security JWT
+// @Tags Attachment
+// @Description Gets the files of the Event, private ones included
+// @Produce json
+// @Param	orgId 		path string true "Organization Id"
+// @Param	eventId 	path string true "Event Id"
+// @Success 200 		{object} 	[]models.EventAttachment
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/events/{eventId}/attachments [GET]
+func (c *AttachmentController) GetOrganizationAttachments(ctx *gin.Context) {
+	attachments, err := c.attachmentService.GetAttachments(ctx, ctx.Param("eventId"), true)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attachments)
+}
+
+// @Summary DeleteAttachment
+// @Security JWT
+// @Tags Attachment
+// @Description Deletes a file of the Event, the files uploaded by others only by admins
+// @Produce plain
+// @Param	orgId 			path string true "Organization Id"
+// @Param	eventId 		path string true "Event Id"
+// @Param	attachmentId 	path string true "Attachment Id"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/events/{eventId}/attachments/{attachmentId} [DELETE]
+func (c *AttachmentController) DeleteAttachment(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	err = c.attachmentService.DeleteAttachment(ctx, *claims.OrganizationId, ctx.Param("attachmentId"), claims.UserId, *claims.IsAdmin)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+// @Summary GetAttachments
+// @Tags Attachment
+// @Description Gets the public files of the Event
+// @Produce json
+// @Param	eventId 	path string true "Event Id"
+// @Success 200 		{object} 	[]models.EventAttachment
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/events/{eventId}/attachments [GET]
+func (c *AttachmentController) GetAttachments(ctx *gin.Context) {
+	attachments, err := c.attachmentService.GetAttachments(ctx, ctx.Param("eventId"), false)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attachments)
+}
+
+// @Summary GetAttachmentDownloadUrl
+// @Security JWT
+// @Tags Attachment
+// @Description Gets the url to download a file of the Event, the private ones are signed and only for
+// @Description the Organization of the Event and the Users registered to it
+// @Produce json
+// @Param	eventId 		path string true "Event Id"
+// @Param	attachmentId 	path string true "Attachment Id"
+// @Success 200 		{object} 	schemas.Url
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/events/{eventId}/attachments/{attachmentId}/download [GET]
+func (c *AttachmentController) GetDownloadUrl(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	url, err := c.attachmentService.GetDownloadUrl(ctx, claims.UserId, claims.OrganizationId, ctx.Param("eventId"), ctx.Param("attachmentId"))
+	if err != nil {
+		if err == common.ErrAuth {
+			fiddlers.AbortWithProblem(ctx, http.StatusForbidden, common.PROBLEM_CODE_FORBIDDEN, "")
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schemas.Url{Url: url})
+}
+
+// @Summary GetStorageUsage
+// @Security JWT
+// @Tags Attachment
+// @Description Gets the storage used by the files of the Organization and its quota, in bytes
+// @Produce json
+// @Param	orgId 		path string true "Organization Id"
+// @Success 200 		{object} 	models.StorageUsage
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/storage [GET]
+func (c *AttachmentController) GetStorageUsage(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	usage, err := c.attachmentService.GetStorageUsage(ctx, *claims.OrganizationId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, usage)
+}
+
+// @Summary SetStorageQuota
+// @Security JWT
+// @Tags Attachment
+// @Description Sets the storage quota of an Organization, in bytes. Platform admins only.
+// @Consume application/json
+// @Accept json
+// @Produce plain
+// @Param	orgId 		path string true "Organization Id"
+// @Param   payload 	body 		schemas.SetStorageQuota true "quota json"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/admin/organizations/{orgId}/storage-quota [PUT]
+func (c *AttachmentController) SetStorageQuota(ctx *gin.Context) {
+	var setQuota schemas.SetStorageQuota
+
+	if err := ctx.ShouldBind(&setQuota); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	err := c.attachmentService.SetStorageQuota(ctx, ctx.Param("orgId"), setQuota.QuotaBytes)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+func (c *AttachmentController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
+	g := rg.Group("/organizations/:orgId/events/:eventId/attachments")
+
+	g.PUT("", authMiddleware.AuthorizeOrganization(false), c.CreateAttachment)
+	g.GET("", authMiddleware.AuthorizeOrganization(false), c.GetOrganizationAttachments)
+	g.POST("/:attachmentId/confirm", authMiddleware.AuthorizeOrganization(false), c.ConfirmAttachment)
+	g.DELETE("/:attachmentId", authMiddleware.AuthorizeOrganization(false), c.DeleteAttachment)
+
+	rg.GET("/events/:eventId/attachments", c.GetAttachments)
+	rg.GET("/events/:eventId/attachments/:attachmentId/download", authMiddleware.AuthorizeUser(), c.GetDownloadUrl)
+
+	rg.GET("/organizations/:orgId/storage", authMiddleware.AuthorizeOrganization(false), c.GetStorageUsage)
+	rg.PUT("/admin/organizations/:orgId/storage-quota", authMiddleware.AuthorizeUser(), authMiddleware.AuthorizePlatformAdmin(), c.SetStorageQuota)
+}
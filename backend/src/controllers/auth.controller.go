@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"net/http"
 
-	"log/slog"
-
 	"github.com/gin-gonic/gin"
 	"github.com/patos-ufscar/quack-week/common"
 	"github.com/patos-ufscar/quack-week/fiddlers"
@@ -52,30 +50,30 @@ func NewAuthController(
 // @Param email formData string true "User credentials"
 // @Param password formData string true "User credentials"
 // @Success 200 {object} models.JwtClaimsOutput
-// @Failure 400 string BadRequest
-// @Failure 401 string Unauthorized
-// @Failure 502 string BadGateway
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 401 		{object} 	schemas.Problem "Unauthorized"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/auth/login [POST]
 func (c *AuthController) Login(ctx *gin.Context) {
 	var loginForm schemas.LoginForm
 	if err := ctx.ShouldBind(&loginForm); err != nil {
-		ctx.String(http.StatusBadRequest, err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	user, err := c.userService.GetUser(ctx, loginForm.Email)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while retrieving User user '%s': '%s'", loginForm.Email, err.Error()))
-		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while retrieving User user '%s': '%s'", loginForm.Email, err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
 	if !common.CheckPasswordHash(loginForm.Password, user.PasswordHash) {
-		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
-	slog.Info(fmt.Sprintf("user login: %s", user.Email))
+	common.Logger(ctx).Info(fmt.Sprintf("user login: %s", user.Email))
 
 	token, err := c.authService.InitToken(
 		user.UserId,
@@ -84,8 +82,8 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		nil,
 	)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while generating token for user '%s': '%s'", loginForm.Email, err.Error()))
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while generating token for user '%s': '%s'", loginForm.Email, err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
@@ -93,8 +91,8 @@ func (c *AuthController) Login(ctx *gin.Context) {
 
 	claims, err := c.authService.ParseToken(token)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while parsing token for user '%s': '%s'", loginForm.Email, err.Error()))
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while parsing token for user '%s': '%s'", loginForm.Email, err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
@@ -108,20 +106,20 @@ func (c *AuthController) Login(ctx *gin.Context) {
 // @Consume application/json
 // @Produce json
 // @Success 200 {object} models.JwtClaimsOutput
-// @Failure 400 string BadRequest
-// @Failure 401 string Unauthorized
-// @Failure 502 string BadGateway
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 401 		{object} 	schemas.Problem "Unauthorized"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/auth/validate [GET]
 func (c *AuthController) Validate(ctx *gin.Context) {
 	userClaimsRaw, ok := ctx.Get(common.GIN_CTX_JWT_CLAIM_KEY_NAME)
 	if !ok {
-		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
 	userClaims, ok := userClaimsRaw.(models.JwtClaims)
 	if !ok {
-		ctx.String(http.StatusBadGateway, "BadGateay")
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
@@ -132,9 +130,9 @@ func (c *AuthController) Validate(ctx *gin.Context) {
 // @Tags Auth
 // @Description Removes the cookie
 // @Success 200 string OK
-// @Failure 400 string BadRequest
-// @Failure 401 string Unauthorized
-// @Failure 502 string BadGateway
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 401 		{object} 	schemas.Problem "Unauthorized"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/auth/logout [POST]
 func (c *AuthController) Logout(ctx *gin.Context) {
 	common.ClearAuthCookie(ctx)
@@ -148,22 +146,23 @@ func (c *AuthController) Logout(ctx *gin.Context) {
 // @Produce json
 // @Param orgId path string true "orgId"
 // @Success 200 		{object} 	models.JwtClaimsOutput
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/auth/set-organization/{orgId} [POST]
 func (c *AuthController) SetOrg(ctx *gin.Context) {
 	orgId := ctx.Param("orgId")
 
 	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
 	orgs, err := c.userService.GetUserOrgs(ctx, claims.UserId)
 	if err != nil {
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
@@ -175,20 +174,20 @@ func (c *AuthController) SetOrg(ctx *gin.Context) {
 	}
 
 	if claimsOrg == nil {
-		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
 	token, err := c.authService.InitToken(claims.UserId, claims.Email, &claimsOrg.OrganizationId, &claimsOrg.IsAdmin)
 	if err != nil {
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
 	parsedClaims, err := c.authService.ParseToken(token)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while parsing token for user '%s': '%s'", claims.Email, err.Error()))
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while parsing token for user '%s': '%s'", claims.Email, err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
@@ -201,9 +200,9 @@ func (c *AuthController) SetOrg(ctx *gin.Context) {
 // @Description Gets OauthProviders and their URLs
 // @Produce json
 // @Success 200 		{object} 	map[string]string
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/auth/providers [GET]
 func (c *AuthController) GetOauthProviders(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, c.oauthProvidersUrls)
@@ -216,44 +215,41 @@ func (c *AuthController) GetOauthProviders(ctx *gin.Context) {
 // @Param 	provider 	path 		string true "provider name"
 // @Param   code 		query 		string true "code"
 // @Success 302 		{string} 	OKResponse "StatusFound"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/auth/{provider}/callback [GET]
 func (c *AuthController) OauthCallback(ctx *gin.Context) {
 	code := ctx.Query("code")
 	provider, ok := c.oauthProvidersMap[ctx.Param("provider")]
 	if !ok {
-		ctx.String(http.StatusNotFound, "NotFound")
+		fiddlers.AbortWithProblem(ctx, http.StatusNotFound, common.PROBLEM_CODE_NOT_FOUND, "")
 		return
 	}
 
 	oauthUser, err := provider.Auth(ctx, code)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
-	user, inserted, err := c.authService.LoginOauth(ctx, *oauthUser)
+	user, inserted, err := c.authService.LoginOauth(ctx, *oauthUser, fiddlers.ParseAcceptLanguage(ctx.GetHeader("Accept-Language")))
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 	if inserted {
-		err = c.emailService.SendAccountCreated(user.Email, user.FirstName)
+		err = c.emailService.SendAccountCreated(ctx, user.Email, user.Locale, user.FirstName)
 		if err != nil {
-			slog.Error(err.Error())
-			ctx.String(http.StatusBadGateway, "BadGateway")
+			ctx.Error(err)
 			return
 		}
 	}
 
 	token, err := c.authService.InitToken(user.UserId, user.Email, nil, nil)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
@@ -1,151 +1,359 @@
 package controllers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
 	"github.com/patos-ufscar/quack-week/fiddlers"
 	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/models"
+	"github.com/patos-ufscar/quack-week/payment"
 	"github.com/patos-ufscar/quack-week/schemas"
 	"github.com/patos-ufscar/quack-week/services"
-	"github.com/stripe/stripe-go/v81"
 )
 
 type BillingController struct {
 	billingService services.BillingService
-	emailService   services.EmailService
-	userService    services.UserService
 }
 
 func NewBillingController(
 	billingService services.BillingService,
-	emailService services.EmailService,
-	userService services.UserService,
 ) BillingController {
 	return BillingController{
 		billingService: billingService,
-		emailService:   emailService,
-		userService:    userService,
 	}
 }
 
-// @Summary GetCheckoutSessionUrl
+// @Summary GetCheckoutUrl
 // @Security JWT
 // @Tags Billing
-// @Description Gets the CheckoutSession Url
+// @Description Gets the payment provider checkout Url, applying the promo code if any. Fully discounted
+// @Description payments are completed right away and the Url points to the app success page.
 // @Produce plain
 // @Param 	product_id 	path 		string true "product_id"
-// @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Param 	eventId 	query 		string false "Event being paid for"
+// @Param 	promoCode 	query 		string false "Promo Code of the Event's Organization"
+// @Success 200 		{object} 	schemas.Url
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/billing/checkout-url/{product_id} [POST]
 // @Router /v1/billing/stripe/get-checkout-session-url/{product_id} [POST]
-func (c *BillingController) GetCheckoutSessionUrl(ctx *gin.Context) {
+func (c *BillingController) GetCheckoutUrl(ctx *gin.Context) {
 	prodIdStr := ctx.Param("product_id")
 	var val int64 = 300
 
 	fmt.Printf("prodIdStr: %v\n", prodIdStr)
 
 	if prodIdStr != "0" {
-		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
+	var eventId, promoCode *string
+	if v, ok := ctx.GetQuery("eventId"); ok {
+		eventId = &v
+	}
+	if v, ok := ctx.GetQuery("promoCode"); ok {
+		promoCode = &v
+	}
+
 	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
-	url, err := c.billingService.CreatePayment(ctx, stripe.CurrencyBRL, val*100, "event", claims.UserId)
+	_, url, err := c.billingService.CreatePayment(ctx, payment.CURRENCY_BRL, val*100, "event", claims.UserId, eventId, promoCode)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		if err == common.ErrPromoCodeInvalid {
+			fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_PROMO_CODE, "")
+			return
+		}
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, schemas.Url{Url: url})
 }
 
-// @Summary CheckoutSessionCompletedCallback
-// @Security JWT
+// @Summary WebhookCallback
 // @Tags Billing
-// @Description Completes a SessionCompleted
+// @Description Receives the payment provider notifications, the checkout is fetched
+// @Description from the provider before completing or canceling the payment.
+// @Accept json
 // @Produce plain
-// @Param   payload 	body 		any true "stripe.Event json"
+// @Param   payload 	body 		any true "provider notification json, e.g. stripe.Event"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/billing/webhook [POST]
 // @Router /v1/billing/stripe/checkout-session-completed [POST]
-func (c *BillingController) CheckoutSessionCompletedCallback(ctx *gin.Context) {
-	var stripeEvent stripe.Event
+func (c *BillingController) WebhookCallback(ctx *gin.Context) {
+	payload, err := ctx.GetRawData()
+	if err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
 
-	if err := ctx.ShouldBind(&stripeEvent); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+	checkoutId, err := c.billingService.ParseWebhook(payload, ctx.Request.Header)
+	if err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
-	var inputCheckoutSession stripe.CheckoutSession
+	if checkoutId == "" {
+		ctx.String(http.StatusOK, "OK")
+		return
+	}
+
+	checkout, err := c.billingService.GetCheckout(ctx, checkoutId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	err = c.applyCheckout(ctx, checkout)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+// ReconcilePendingPayments checks the checkout of payments left pending (e.g. when
+// the provider webhook was missed), completing paid ones, canceling expired ones and
+// expiring checkouts abandoned for too long. To be used in a daemon.
+func (c *BillingController) ReconcilePendingPayments() error {
+	ctx := context.Background()
+
+	payments, err := c.billingService.GetStalePendingPayments(
+		ctx,
+		time.Minute*time.Duration(common.PAYMENT_RECONCILE_MINS),
+		common.PAYMENT_RECONCILE_BATCH,
+	)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range payments {
+		err := c.reconcilePayment(ctx, p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reconciling payment %s: %w", p.PaymentId, err))
+		}
+	}
 
-	switch stripeEvent.Type {
-	case stripe.EventTypeCheckoutSessionCompleted:
-		err := json.Unmarshal(stripeEvent.Data.Raw, &inputCheckoutSession)
+	return errors.Join(errs...)
+}
+
+func (c *BillingController) reconcilePayment(ctx context.Context, p models.Payment) error {
+	// creating its checkout failed after the payment was committed
+	if p.CheckoutId == nil {
+		_, err := c.billingService.CancelPendingPayment(ctx, p.PaymentId)
+		if errors.Is(err, common.ErrNotFound) {
+			return nil
+		}
 		if err != nil {
-			slog.Error(err.Error())
-			ctx.String(http.StatusBadRequest, err.Error())
-			return
+			return err
 		}
-	default:
-		slog.Warn(fmt.Sprintf("Unhandled event type: %s", string(stripeEvent.Type)))
-		ctx.String(http.StatusBadGateway, "BadGateway")
-		return
+		common.Logger(ctx).Info(fmt.Sprintf("payment %s without checkout canceled", p.PaymentId))
+		return nil
 	}
 
-	checkoutSession, err := c.billingService.GetCheckoutSession(ctx, inputCheckoutSession.ID)
+	checkout, err := c.billingService.GetCheckout(ctx, *p.CheckoutId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		return err
+	}
+
+	// a pending payment holds a use of its promo code, so it is given up
+	// sooner than the checkout itself would expire
+	abandonAfter := time.Hour * time.Duration(common.PAYMENT_ABANDON_HOURS)
+	if p.PromoCodeId != nil {
+		abandonAfter = time.Minute * time.Duration(common.PROMO_CODE_RESERVATION_MINS)
+	}
+
+	abandoned := time.Since(p.CreatedAt) > abandonAfter
+	if checkout.Status == payment.CHECKOUT_STATUS_OPEN && abandoned {
+		checkout, err = c.billingService.ExpireCheckout(ctx, checkout.Id)
+		if err != nil {
+			return err
+		}
+		common.Logger(ctx).Info(fmt.Sprintf("expired abandoned checkout for payment %s", p.PaymentId))
+	}
+
+	return c.applyCheckout(ctx, checkout)
+}
+
+// Completes or cancels the payment of the checkout, shared by the webhook and the
+// reconciliation daemon. Payments already handled by the other one are skipped.
+func (c *BillingController) applyCheckout(ctx context.Context, checkout *payment.Checkout) error {
+	switch {
+	case checkout.Paid:
+		p, err := c.billingService.SetCheckoutAsComplete(ctx, checkout.Id)
+		if errors.Is(err, common.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		common.Logger(ctx).Info(fmt.Sprintf("payment %s complete", p.PaymentId))
+
+	case checkout.Status == payment.CHECKOUT_STATUS_EXPIRED:
+		p, err := c.billingService.SetCheckoutAsCanceled(ctx, checkout.Id)
+		if errors.Is(err, common.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		common.Logger(ctx).Info(fmt.Sprintf("payment %s canceled", p.PaymentId))
+	}
+
+	return nil
+}
+
+// @Summary GetOrganizationRevenue
+// @Security JWT
+// @Tags Billing
+// @Description Gets the revenue of the Organization's events, by event and period (defaults to month).
+// @Description Ammounts are in cents, `net` is what is left after platform fees.
+// @Produce json
+// @Param	orgId 		path 		string true "Organization Id"
+// @Param	period 		query 		string false "day, week, month or year"
+// @Param	from 		query 		string false "RFC3339 start, inclusive"
+// @Param	to 			query 		string false "RFC3339 end, exclusive, defaults to now"
+// @Success 200 		{object} 	[]models.RevenueEntry
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/billing/organizations/{orgId}/revenue [GET]
+func (c *BillingController) GetOrganizationRevenue(ctx *gin.Context) {
+	entries, ok := c.getOrganizationRevenue(ctx)
+	if !ok {
 		return
 	}
 
-	if !fiddlers.IsStripeChechouseSessionPaid(checkoutSession) {
-		ctx.String(http.StatusUnauthorized, "Unauthorized")
+	ctx.JSON(http.StatusOK, entries)
+}
+
+// @Summary GetOrganizationRevenueCsv
+// @Security JWT
+// @Tags Billing
+// @Description Same as GetOrganizationRevenue, as a CSV file
+// @Produce text/csv
+// @Param	orgId 		path 		string true "Organization Id"
+// @Param	period 		query 		string false "day, week, month or year"
+// @Param	from 		query 		string false "RFC3339 start, inclusive"
+// @Param	to 			query 		string false "RFC3339 end, exclusive, defaults to now"
+// @Success 200 		{string} 	string "csv"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/billing/organizations/{orgId}/revenue/csv [GET]
+func (c *BillingController) GetOrganizationRevenueCsv(ctx *gin.Context) {
+	entries, ok := c.getOrganizationRevenue(ctx)
+	if !ok {
 		return
 	}
 
-	payment, err := c.billingService.SetCheckoutSessionAsComplete(ctx, checkoutSession.ID)
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=revenue-%s.csv", ctx.Param("orgId")))
+	ctx.Status(http.StatusOK)
+
+	err := fiddlers.WriteRevenueCsv(ctx.Writer, entries)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
-		return
+		common.Logger(ctx).Error(err.Error())
 	}
+}
 
-	user, err := c.userService.GetUserFromId(ctx, payment.UserId)
+func (c *BillingController) getOrganizationRevenue(ctx *gin.Context) ([]models.RevenueEntry, bool) {
+	var period schemas.Period
+
+	if err := ctx.ShouldBindQuery(&period); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return nil, false
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+
+	from, to := time.Unix(0, 0), time.Now()
+	if period.From != nil {
+		from = *period.From
+	}
+	if period.To != nil {
+		to = *period.To
+	}
+	if period.Period == "" {
+		period.Period = "month"
+	}
+
+	entries, err := c.billingService.GetOrganizationRevenue(ctx, *claims.OrganizationId, period.Period, from, to)
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+
+	return entries, true
+}
+
+// @Summary GetPayoutOnboardingUrl
+// @Security JWT
+// @Tags Billing
+// @Description Gets the Url where the Organization sets up the account ticket revenue is paid out to.
+// @Description Only available when the payment provider supports it (e.g. Stripe Connect).
+// @Produce json
+// @Param	orgId 		path 		string true "Organization Id"
+// @Success 200 		{object} 	schemas.Url
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 501 		{object} 	schemas.Problem "Not Implemented"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/billing/organizations/{orgId}/payouts/onboarding [POST]
+func (c *BillingController) GetPayoutOnboardingUrl(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
-	err = c.emailService.SendPaymentAccepted(user.Email, user.FirstName, payment)
+	url, err := c.billingService.GetPayoutOnboardingUrl(ctx, *claims.OrganizationId, claims.Email)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		if err == common.ErrPayoutsUnsupported {
+			fiddlers.AbortWithProblem(ctx, http.StatusNotImplemented, common.PROBLEM_CODE_UNIMPLEMENTED, "")
+			return
+		}
+		ctx.Error(err)
 		return
 	}
 
-	ctx.String(http.StatusOK, "OK")
+	ctx.JSON(http.StatusOK, schemas.Url{Url: url})
 }
 
 func (c *BillingController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
 	g := rg.Group("/billing")
 
-	g.POST("/stripe/get-checkout-session-url/:product_id", authMiddleware.AuthorizeUser(), c.GetCheckoutSessionUrl)
-	g.POST("/stripe/checkout-session-completed", c.CheckoutSessionCompletedCallback)
+	g.POST("/checkout-url/:product_id", authMiddleware.AuthorizeUser(), c.GetCheckoutUrl)
+	g.POST("/webhook", c.WebhookCallback)
+	g.GET("/organizations/:orgId/revenue", authMiddleware.AuthorizeOrganization(true), c.GetOrganizationRevenue)
+	g.GET("/organizations/:orgId/revenue/csv", authMiddleware.AuthorizeOrganization(true), c.GetOrganizationRevenueCsv)
+	g.POST("/organizations/:orgId/payouts/onboarding", authMiddleware.AuthorizeOrganization(true), c.GetPayoutOnboardingUrl)
+
+	// kept for clients and webhooks configured before other providers were supported
+	g.POST("/stripe/get-checkout-session-url/:product_id", authMiddleware.AuthorizeUser(), c.GetCheckoutUrl)
+	g.POST("/stripe/checkout-session-completed", c.WebhookCallback)
 }
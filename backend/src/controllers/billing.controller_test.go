@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+	"github.com/patos-ufscar/quack-week/payment"
+	"github.com/patos-ufscar/quack-week/services"
+	"github.com/stripe/stripe-go/v81"
+)
+
+func TestBillingController_ReconcilePendingPayments(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	fakeStripe := helpers.NewFakeStripeServer()
+	t.Cleanup(fakeStripe.Close)
+
+	userService := services.NewUserServicePgImpl(pgContainer.DB)
+	err = userService.CreateUser(ctx, models.User{
+		Email:        "buyer@email.com",
+		PasswordHash: "hashtest",
+		FirstName:    "Buyer",
+		LastName:     "One",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := userService.GetUser(ctx, "buyer@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	billingService := services.NewBillingServicePgImpl(
+		pgContainer.DB,
+		payment.NewStripeProviderWithBackend(fakeStripe.Backend(), "sk_test_fake"),
+		0,
+	)
+	c := NewBillingController(billingService)
+
+	stale := time.Minute * time.Duration(common.PAYMENT_RECONCILE_MINS+1)
+	abandoned := time.Hour * time.Duration(common.PAYMENT_ABANDON_HOURS+1)
+
+	tests := []struct {
+		name          string
+		status        stripe.CheckoutSessionStatus
+		paymentStatus stripe.CheckoutSessionPaymentStatus
+		age           time.Duration
+		want          string
+		wantSession   stripe.CheckoutSessionStatus
+	}{
+		{"paid", stripe.CheckoutSessionStatusComplete, stripe.CheckoutSessionPaymentStatusPaid, stale, "complete", stripe.CheckoutSessionStatusComplete},
+		{"expired", stripe.CheckoutSessionStatusExpired, stripe.CheckoutSessionPaymentStatusUnpaid, stale, "canceled", stripe.CheckoutSessionStatusExpired},
+		{"still open", stripe.CheckoutSessionStatusOpen, stripe.CheckoutSessionPaymentStatusUnpaid, stale, "pending", stripe.CheckoutSessionStatusOpen},
+		{"abandoned", stripe.CheckoutSessionStatusOpen, stripe.CheckoutSessionPaymentStatusUnpaid, abandoned, "canceled", stripe.CheckoutSessionStatusExpired},
+		{"not stale yet", stripe.CheckoutSessionStatusComplete, stripe.CheckoutSessionPaymentStatusPaid, 0, "pending", stripe.CheckoutSessionStatusComplete},
+	}
+
+	payments := make([]models.Payment, len(tests))
+	for i, tt := range tests {
+		payments[i], _, err = billingService.CreatePayment(ctx, payment.CURRENCY_BRL, 300, "event", user.UserId, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fakeStripe.SetSession(*payments[i].CheckoutId, tt.status, tt.paymentStatus)
+
+		_, err = pgContainer.DB.ExecContext(ctx, `
+			UPDATE payments SET created_at = $1 WHERE payment_id = $2;
+		`, time.Now().Add(-tt.age), payments[i].PaymentId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = c.ReconcilePendingPayments()
+	if err != nil {
+		t.Fatalf("ReconcilePendingPayments() error = %v", err)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			err := pgContainer.DB.QueryRowContext(ctx, `
+				SELECT payment_status FROM payments WHERE payment_id = $1;
+			`, payments[i].PaymentId).Scan(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("payment status = %v, want %v", got, tt.want)
+			}
+
+			cs := fakeStripe.GetSession(*payments[i].CheckoutId)
+			if cs.Status != tt.wantSession {
+				t.Errorf("checkout session status = %v, want %v", cs.Status, tt.wantSession)
+			}
+		})
+	}
+
+	// a second run must leave already reconciled payments alone
+	err = c.ReconcilePendingPayments()
+	if err != nil {
+		t.Errorf("ReconcilePendingPayments() twice error = %v", err)
+	}
+}
+
+func TestBillingController_ReconcileAbandonedPromoCode(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	fakeStripe := helpers.NewFakeStripeServer()
+	t.Cleanup(fakeStripe.Close)
+
+	var userId uint32
+	var eventId string
+	err = pgContainer.DB.QueryRowContext(ctx, `
+		INSERT INTO users (email, password_hash, first_name, last_name)
+		VALUES ('buyer@email.com', 'hashtest', 'Buyer', 'One')
+		RETURNING user_id;
+	`).Scan(&userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pgContainer.DB.ExecContext(ctx, `
+		INSERT INTO organizations (organization_id, organization_name, owner_user_id)
+		VALUES ('quack', 'Quack', $1);
+	`, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pgContainer.DB.QueryRowContext(ctx, `
+		INSERT INTO events (event_name, owner_user_id, owner_organization_id, event_description)
+		VALUES ('Quack Week', $1, 'quack', '')
+		RETURNING event_id;
+	`, userId).Scan(&eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maxUses := uint32(1)
+	_, err = services.NewPromoCodeServicePgImpl(pgContainer.DB).CreatePromoCode(ctx, models.PromoCode{
+		OrganizationId: "quack",
+		Code:           "ONCE",
+		DiscountType:   common.DISCOUNT_TYPE_FIXED,
+		DiscountValue:  100,
+		MaxUses:        &maxUses,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	billingService := services.NewBillingServicePgImpl(
+		pgContainer.DB,
+		payment.NewStripeProviderWithBackend(fakeStripe.Backend(), "sk_test_fake"),
+		0,
+	)
+	c := NewBillingController(billingService)
+
+	code := "ONCE"
+	p, _, err := billingService.CreatePayment(ctx, payment.CURRENCY_BRL, 300, "event", userId, &eventId, &code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeStripe.SetSession(*p.CheckoutId, stripe.CheckoutSessionStatusOpen, stripe.CheckoutSessionPaymentStatusUnpaid)
+
+	// the open checkout holds the only use
+	_, _, err = billingService.CreatePayment(ctx, payment.CURRENCY_BRL, 300, "event", userId, &eventId, &code)
+	if err != common.ErrPromoCodeInvalid {
+		t.Fatalf("CreatePayment() while reserved error = %v, want %v", err, common.ErrPromoCodeInvalid)
+	}
+
+	// well before the checkout itself would be abandoned
+	_, err = pgContainer.DB.ExecContext(ctx, `
+		UPDATE payments SET created_at = $1 WHERE payment_id = $2;
+	`, time.Now().Add(-time.Minute*time.Duration(common.PROMO_CODE_RESERVATION_MINS+1)), p.PaymentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.ReconcilePendingPayments()
+	if err != nil {
+		t.Fatalf("ReconcilePendingPayments() error = %v", err)
+	}
+
+	if cs := fakeStripe.GetSession(*p.CheckoutId); cs.Status != stripe.CheckoutSessionStatusExpired {
+		t.Errorf("checkout session status = %v, want %v", cs.Status, stripe.CheckoutSessionStatusExpired)
+	}
+	_, _, err = billingService.CreatePayment(ctx, payment.CURRENCY_BRL, 300, "event", userId, &eventId, &code)
+	if err != nil {
+		t.Errorf("CreatePayment() after abandoned checkout error = %v, want nil", err)
+	}
+}
+
+func TestBillingController_reconcilePayment(t *testing.T) {
+	ctx := context.Background()
+
+	fakeStripe := helpers.NewFakeStripeServer()
+	t.Cleanup(fakeStripe.Close)
+
+	// an open, recent checkout must not reach the database
+	c := NewBillingController(services.NewBillingServicePgImpl(
+		nil,
+		payment.NewStripeProviderWithBackend(fakeStripe.Backend(), "sk_test_fake"),
+		0,
+	))
+
+	checkoutId := "cs_test_open"
+	fakeStripe.SetSession(checkoutId, stripe.CheckoutSessionStatusOpen, stripe.CheckoutSessionPaymentStatusUnpaid)
+
+	err := c.reconcilePayment(ctx, models.Payment{
+		PaymentId:  "payment",
+		CheckoutId: &checkoutId,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		t.Errorf("reconcilePayment() error = %v, want nil", err)
+	}
+	if cs := fakeStripe.GetSession(checkoutId); cs.Status != stripe.CheckoutSessionStatusOpen {
+		t.Errorf("checkout session status = %v, want %v", cs.Status, stripe.CheckoutSessionStatusOpen)
+	}
+
+	missing := "cs_test_missing"
+	err = c.reconcilePayment(ctx, models.Payment{
+		PaymentId:  "payment",
+		CheckoutId: &missing,
+		CreatedAt:  time.Now(),
+	})
+	if err == nil {
+		t.Errorf("reconcilePayment() missing checkout error = nil, want error")
+	}
+}
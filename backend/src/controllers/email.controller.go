@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/schemas"
+	"github.com/patos-ufscar/quack-week/services"
+)
+
+type EmailController struct {
+	emailOutboxService  services.EmailOutboxService
+	emailPreviewService services.EmailPreviewService
+}
+
+func NewEmailController(
+	emailOutboxService services.EmailOutboxService,
+	emailPreviewService services.EmailPreviewService,
+) EmailController {
+	return EmailController{
+		emailOutboxService:  emailOutboxService,
+		emailPreviewService: emailPreviewService,
+	}
+}
+
+// @Summary GetDeadEmails
+// @Security JWT
+// @Tags Email
+// @Description Gets the emails that ran out of delivery attempts, newest first. Platform admins only.
+// @Produce json
+// @Param	limit 		query 		int false "defaults to 50, max 100"
+// @Param	offset 		query 		int false "defaults to 0"
+// @Success 200 		{object} 	[]models.OutboxEmail
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/admin/emails/dead [GET]
+func (c *EmailController) GetDeadEmails(ctx *gin.Context) {
+	var page schemas.Page
+
+	if err := ctx.ShouldBindQuery(&page); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	if page.Limit == 0 {
+		page.Limit = common.EMAIL_OUTBOX_BATCH
+	}
+
+	emails, err := c.emailOutboxService.GetDeadEmails(ctx, page.Limit, page.Offset)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, emails)
+}
+
+// @Summary RetryEmail
+// @Security JWT
+// @Tags Email
+// @Description Queues a dead email again with a fresh set of attempts. Platform admins only.
+// @Produce plain
+// @Param	emailId 	path 		string true "Email Id"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/admin/emails/{emailId}/retry [POST]
+func (c *EmailController) RetryEmail(ctx *gin.Context) {
+	emailId, err := strconv.ParseUint(ctx.Param("emailId"), 10, 64)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	err = c.emailOutboxService.RetryEmail(ctx, emailId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+// @Summary GetEmailTemplates
+// @Security JWT
+// @Tags Email
+// @Description Gets the email templates, with their locales and the sample vars used in previews. Platform admins only.
+// @Produce json
+// @Success 200 		{object} 	[]models.EmailTemplate
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Router /v1/admin/emails/templates [GET]
+func (c *EmailController) GetEmailTemplates(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.emailPreviewService.GetEmailTemplates())
+}
+
+// @Summary PreviewEmail
+// @Security JWT
+// @Tags Email
+// @Description Renders the template with its sample vars. Platform admins only.
+// @Produce html
+// @Param	templateName 	path 		string true "Template name"
+// @Param	locale 			query 		string false "pt-BR or en, defaults to pt-BR"
+// @Param	format 			query 		string false "html or text, defaults to html"
+// @Success 200 		{string} 	string "The rendered email"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/admin/emails/templates/{templateName}/preview [GET]
+func (c *EmailController) PreviewEmail(ctx *gin.Context) {
+	c.previewEmail(ctx, schemas.EmailPreview{})
+}
+
+// @Summary PreviewEmailWithVars
+// @Security JWT
+// @Tags Email
+// @Description Renders the template with the given vars over its sample vars. Platform admins only.
+// @Accept json
+// @Produce html
+// @Param	templateName 	path 		string true "Template name"
+// @Param	format 			query 		string false "html or text, defaults to html"
+// @Param	preview 		body 		schemas.EmailPreview true "Locale and vars"
+// @Success 200 		{string} 	string "The rendered email"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/admin/emails/templates/{templateName}/preview [POST]
+func (c *EmailController) PreviewEmailWithVars(ctx *gin.Context) {
+	var preview schemas.EmailPreview
+
+	if err := ctx.ShouldBindJSON(&preview); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	c.previewEmail(ctx, preview)
+}
+
+func (c *EmailController) previewEmail(ctx *gin.Context, preview schemas.EmailPreview) {
+	var query schemas.EmailPreviewQuery
+
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	locale := preview.Locale
+	if locale == "" {
+		locale = query.Locale
+	}
+
+	rendered, err := c.emailPreviewService.PreviewEmail(ctx.Param("templateName"), locale, preview.Vars)
+	if err != nil {
+		if err == common.ErrEmailTemplateNotFound {
+			fiddlers.AbortWithProblem(ctx, http.StatusNotFound, common.PROBLEM_CODE_NOT_FOUND, "")
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	if query.Format == "text" {
+		ctx.String(http.StatusOK, rendered.Text)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.Html))
+}
+
+// @Summary SendTestEmail
+// @Security JWT
+// @Tags Email
+// @Description Sends the template, with the given vars over its sample vars, to the logged admin right away. The subject is prefixed with [TEST]. Platform admins only.
+// @Accept json
+// @Produce plain
+// @Param	templateName 	path 		string true "Template name"
+// @Param	preview 		body 		schemas.EmailPreview true "Locale and vars"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/admin/emails/templates/{templateName}/test [POST]
+func (c *EmailController) SendTestEmail(ctx *gin.Context) {
+	var preview schemas.EmailPreview
+
+	if err := ctx.ShouldBindJSON(&preview); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	err = c.emailPreviewService.SendTestEmail(ctx, claims.Email, ctx.Param("templateName"), preview.Locale, preview.Vars)
+	if err != nil {
+		if err == common.ErrEmailTemplateNotFound {
+			fiddlers.AbortWithProblem(ctx, http.StatusNotFound, common.PROBLEM_CODE_NOT_FOUND, "")
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+func (c *EmailController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
+	g := rg.Group("/admin/emails")
+
+	g.GET("/dead", authMiddleware.AuthorizeUser(), authMiddleware.AuthorizePlatformAdmin(), c.GetDeadEmails)
+	g.POST("/:emailId/retry", authMiddleware.AuthorizeUser(), authMiddleware.AuthorizePlatformAdmin(), c.RetryEmail)
+	g.GET("/templates", authMiddleware.AuthorizeUser(), authMiddleware.AuthorizePlatformAdmin(), c.GetEmailTemplates)
+	g.GET("/templates/:templateName/preview", authMiddleware.AuthorizeUser(), authMiddleware.AuthorizePlatformAdmin(), c.PreviewEmail)
+	g.POST("/templates/:templateName/preview", authMiddleware.AuthorizeUser(), authMiddleware.AuthorizePlatformAdmin(), c.PreviewEmailWithVars)
+	g.POST("/templates/:templateName/test", authMiddleware.AuthorizeUser(), authMiddleware.AuthorizePlatformAdmin(), c.SendTestEmail)
+}
@@ -1,26 +1,27 @@
 package controllers
 
 import (
-	"bytes"
+	"context"
 	"encoding/base64"
-	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/patos-ufscar/quack-week/common"
 	"github.com/patos-ufscar/quack-week/fiddlers"
-	"github.com/patos-ufscar/quack-week/fiddlers/storage"
 	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/models"
 	"github.com/patos-ufscar/quack-week/schemas"
 	"github.com/patos-ufscar/quack-week/services"
 )
 
 type EventController struct {
-	userService  services.UserService
-	emailService services.EmailService
-	orgService   services.OrganizationService
-	eventService services.EventService
-	objService   services.ObjectService
+	userService   services.UserService
+	emailService  services.EmailService
+	orgService    services.OrganizationService
+	eventService  services.EventService
+	imageService  services.ImageService
+	uploadService services.UploadService
+	uow           services.UnitOfWork
 }
 
 func NewEventController(
@@ -28,14 +29,18 @@ func NewEventController(
 	emailService services.EmailService,
 	orgService services.OrganizationService,
 	eventService services.EventService,
-	objService services.ObjectService,
+	imageService services.ImageService,
+	uploadService services.UploadService,
+	uow services.UnitOfWork,
 ) EventController {
 	return EventController{
-		userService:  userService,
-		emailService: emailService,
-		orgService:   orgService,
-		eventService: eventService,
-		objService:   objService,
+		userService:   userService,
+		emailService:  emailService,
+		orgService:    orgService,
+		eventService:  eventService,
+		imageService:  imageService,
+		uploadService: uploadService,
+		uow:           uow,
 	}
 }
 
@@ -49,30 +54,29 @@ func NewEventController(
 // @Param	orgId 		path string true "Organization Id"
 // @Param   payload 	body 		schemas.Name true "name json"
 // @Success 200 		{object} 	schemas.Id
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/events/organization/{orgId} [PUT]
 func (c *EventController) CreateEvent(ctx *gin.Context) {
 	var name schemas.Name
 
 	if err := ctx.ShouldBind(&name); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	event, err := c.eventService.CreateEvent(ctx, name.Name, claims.UserId, *claims.OrganizationId, "")
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
@@ -87,16 +91,16 @@ func (c *EventController) CreateEvent(ctx *gin.Context) {
 // @Produce plain
 // @Param	eventId 	path string true "Event Id"
 // @Success 200 		{object} 	models.Event
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/events/{eventId} [GET]
 func (c *EventController) GetEvent(ctx *gin.Context) {
 	eventId := ctx.Param("eventId")
 	event, err := c.eventService.GetEvent(ctx, eventId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
@@ -109,14 +113,17 @@ func (c *EventController) GetEvent(ctx *gin.Context) {
 // @Description Gets an Event
 // @Consume application/json
 // @Accept json
-// @Produce plain
+// @Produce json
 // @Param	eventId 	path string true "Event Id"
 // @Param	orgId 		path string true "Organization Id"
 // @Param   payload 	body 		schemas.UploadPicture true "picture json"
-// @Success 200 		{object} 	schemas.Url
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Success 200 		{object} 	models.ImageSrcset
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 413 		{object} 	schemas.Problem "Request Entity Too Large"
+// @Failure 415 		{object} 	schemas.Problem "Unsupported Media Type"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/events/{eventId}/organization/{orgId}/banner [PUT]
 func (c *EventController) SetBanner(ctx *gin.Context) {
 	eventId := ctx.Param("eventId")
@@ -125,69 +132,168 @@ func (c *EventController) SetBanner(ctx *gin.Context) {
 
 	event, err := c.eventService.GetEvent(ctx, eventId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusConflict, "Conflict")
+		ctx.Error(err)
 		return
 	}
 
 	if event.OwnerOrganizationId != orgId {
-		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
 	if err := ctx.ShouldBind(&uploadPicture); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	picBytes, err := base64.StdEncoding.DecodeString(uploadPicture.Content)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	if len(picBytes) > 5*1024*1024 { // if > 5MB
-		ctx.String(http.StatusBadGateway, "ImgTooLarge")
+		fiddlers.AbortWithProblem(ctx, http.StatusRequestEntityTooLarge, common.PROBLEM_CODE_TOO_LARGE, "")
+		return
+	}
+
+	var srcset models.ImageSrcset
+	err = c.uow.Do(ctx, func(txCtx context.Context) error {
+		srcset, err = c.imageService.StoreImage(txCtx, common.UPLOAD_PURPOSE_EVENT_BANNER, event.EventId, picBytes)
+		if err != nil {
+			return err
+		}
+
+		return c.eventService.SetCover(txCtx, event.EventId, srcset[common.IMAGE_VARIANT_FULL].Webp)
+	})
+	if err != nil {
+		abortWithUploadError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, srcset)
+}
+
+// @Summary GetBannerUploadUrl
+// @Security JWT
+// @Tags Event
+// @Description Gets a presigned url to upload the Event banner directly to the storage, then call the confirm endpoint
+// @Consume application/json
+// @Accept json
+// @Produce json
+// @Param	eventId 	path string true "Event Id"
+// @Param	orgId 		path string true "Organization Id"
+// @Param   payload 	body 		schemas.CreateUpload true "upload json"
+// @Success 200 		{object} 	schemas.UploadUrl
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 413 		{object} 	schemas.Problem "Request Entity Too Large"
+// @Failure 415 		{object} 	schemas.Problem "Unsupported Media Type"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/events/{eventId}/organization/{orgId}/banner/upload-url [POST]
+func (c *EventController) GetBannerUploadUrl(ctx *gin.Context) {
+	eventId := ctx.Param("eventId")
+	orgId := ctx.Param("orgId")
+	var createUpload schemas.CreateUpload
+
+	if err := ctx.ShouldBind(&createUpload); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
-	imgFmt, err := common.GetImageFormat(picBytes)
+	event, err := c.eventService.GetEvent(ctx, eventId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
-	if imgFmt != "png" && imgFmt != "jpeg" {
-		ctx.String(http.StatusUnsupportedMediaType, "UnsupportedMediaType")
+	if event.OwnerOrganizationId != orgId {
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
-	objPath := storage.GetPublicPath(storage.EVENT_BANNERS, event.EventId)
-	err = c.objService.Upload(ctx, common.S3_BUCKET, objPath, int64(len(picBytes)), bytes.NewReader(picBytes))
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
-	objUrl, err := storage.GetFullObjUrl(objPath)
+	upload, uploadUrl, err := c.uploadService.CreateUpload(ctx, models.Upload{
+		UserId:      claims.UserId,
+		Purpose:     common.UPLOAD_PURPOSE_EVENT_BANNER,
+		TargetId:    event.EventId,
+		ContentType: createUpload.ContentType,
+		Size:        createUpload.Size,
+	})
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		abortWithUploadError(ctx, err)
 		return
 	}
 
-	err = c.eventService.SetCover(ctx, event.EventId, objUrl)
+	ctx.JSON(http.StatusOK, schemas.UploadUrl{
+		UploadId: upload.UploadId,
+		Url:      uploadUrl,
+		Method:   http.MethodPut,
+		Exp:      upload.Exp,
+	})
+}
+
+// @Summary ConfirmBannerUpload
+// @Security JWT
+// @Tags Event
+// @Description Sets the Event banner to the object uploaded to the url of GetBannerUploadUrl
+// @Produce json
+// @Param	eventId 	path string true "Event Id"
+// @Param	orgId 		path string true "Organization Id"
+// @Param	uploadId 	path string true "Upload Id"
+// @Success 200 		{object} 	models.ImageSrcset
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 413 		{object} 	schemas.Problem "Request Entity Too Large"
+// @Failure 415 		{object} 	schemas.Problem "Unsupported Media Type"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/events/{eventId}/organization/{orgId}/banner/confirm/{uploadId} [POST]
+func (c *EventController) ConfirmBannerUpload(ctx *gin.Context) {
+	eventId := ctx.Param("eventId")
+	orgId := ctx.Param("orgId")
+
+	event, err := c.eventService.GetEvent(ctx, eventId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if event.OwnerOrganizationId != orgId {
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	var srcset models.ImageSrcset
+	err = c.uow.Do(ctx, func(txCtx context.Context) error {
+		srcset, err = c.uploadService.ConfirmUpload(txCtx, claims.UserId, ctx.Param("uploadId"), common.UPLOAD_PURPOSE_EVENT_BANNER, event.EventId)
+		if err != nil {
+			return err
+		}
+
+		return c.eventService.SetCover(txCtx, event.EventId, srcset[common.IMAGE_VARIANT_FULL].Webp)
+	})
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		abortWithUploadError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, schemas.Url{Url: objUrl})
+	ctx.JSON(http.StatusOK, srcset)
 }
 
 func (c *EventController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
@@ -196,4 +302,6 @@ func (c *EventController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware mid
 	g.GET("/:eventId", c.GetEvent)
 	g.PUT("/organization/:orgId", authMiddleware.AuthorizeOrganization(true), c.CreateEvent)
 	g.PUT("/:eventId/organization/:orgId/banner", authMiddleware.AuthorizeOrganization(true), c.SetBanner)
+	g.POST("/:eventId/organization/:orgId/banner/upload-url", authMiddleware.AuthorizeOrganization(true), c.GetBannerUploadUrl)
+	g.POST("/:eventId/organization/:orgId/banner/confirm/:uploadId", authMiddleware.AuthorizeOrganization(true), c.ConfirmBannerUpload)
 }
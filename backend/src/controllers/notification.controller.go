@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/models"
+	"github.com/patos-ufscar/quack-week/schemas"
+	"github.com/patos-ufscar/quack-week/services"
+)
+
+type NotificationController struct {
+	notificationService services.NotificationService
+}
+
+func NewNotificationController(
+	notificationService services.NotificationService,
+) NotificationController {
+	return NotificationController{
+		notificationService: notificationService,
+	}
+}
+
+// @Summary GetNotifications
+// @Security JWT
+// @Tags Notification
+// @Description Gets the notifications of the User, newest first. The `payload` depends on the `notificationType`.
+// @Produce json
+// @Param	unread 		query 		bool false "only the unread ones"
+// @Param	limit 		query 		int false "defaults to 20, max 100"
+// @Param	offset 		query 		int false "defaults to 0"
+// @Success 200 		{object} 	[]models.Notification
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/notifications [GET]
+func (c *NotificationController) GetNotifications(ctx *gin.Context) {
+	var query schemas.NotificationsQuery
+
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = common.NOTIFICATIONS_PAGE_SIZE
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	notifications, err := c.notificationService.GetNotifications(ctx, claims.UserId, query.Unread, query.Limit, query.Offset)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, notifications)
+}
+
+// @Summary GetUnreadCount
+// @Security JWT
+// @Tags Notification
+// @Description Counts the unread notifications of the User
+// @Produce json
+// @Success 200 		{object} 	schemas.Count
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/notifications/unread-count [GET]
+func (c *NotificationController) GetUnreadCount(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	count, err := c.notificationService.GetUnreadCount(ctx, claims.UserId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schemas.Count{Count: count})
+}
+
+// @Summary MarkRead
+// @Security JWT
+// @Tags Notification
+// @Description Marks the notification as read
+// @Produce plain
+// @Param	notificationId 	path 		string true "Notification Id"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/notifications/{notificationId}/read [POST]
+func (c *NotificationController) MarkRead(ctx *gin.Context) {
+	notificationId, err := strconv.ParseUint(ctx.Param("notificationId"), 10, 64)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	err = c.notificationService.MarkRead(ctx, claims.UserId, notificationId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+// @Summary MarkAllRead
+// @Security JWT
+// @Tags Notification
+// @Description Marks every notification of the User as read
+// @Produce plain
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/notifications/read-all [POST]
+func (c *NotificationController) MarkAllRead(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	err = c.notificationService.MarkAllRead(ctx, claims.UserId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+// @Summary GetNotificationPreferences
+// @Security JWT
+// @Tags Notification
+// @Description Gets where the User gets each notification type: in-app, email or both
+// @Produce json
+// @Success 200 		{object} 	[]models.NotificationPreference
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/notifications/preferences [GET]
+func (c *NotificationController) GetPreferences(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	preferences, err := c.notificationService.GetPreferences(ctx, claims.UserId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferences)
+}
+
+// @Summary SetNotificationPreference
+// @Security JWT
+// @Tags Notification
+// @Description Sets where the User gets a notification type: in-app, email or both
+// @Consume application/json
+// @Accept json
+// @Produce plain
+// @Param   payload 	body 		schemas.NotificationPreference true "preference json"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/notifications/preferences [PUT]
+func (c *NotificationController) SetPreference(ctx *gin.Context) {
+	var preference schemas.NotificationPreference
+
+	if err := ctx.ShouldBind(&preference); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	err = c.notificationService.SetPreference(ctx, claims.UserId, models.NotificationPreference{
+		NotificationType: preference.NotificationType,
+		Channel:          preference.Channel,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+func (c *NotificationController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
+	g := rg.Group("/notifications")
+
+	g.GET("", authMiddleware.AuthorizeUser(), c.GetNotifications)
+	g.GET("/unread-count", authMiddleware.AuthorizeUser(), c.GetUnreadCount)
+	g.POST("/:notificationId/read", authMiddleware.AuthorizeUser(), c.MarkRead)
+	g.POST("/read-all", authMiddleware.AuthorizeUser(), c.MarkAllRead)
+	g.GET("/preferences", authMiddleware.AuthorizeUser(), c.GetPreferences)
+	g.PUT("/preferences", authMiddleware.AuthorizeUser(), c.SetPreference)
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/services"
+)
+
+// ObjectController serves the urls of services.ObjectServiceFsImpl, only
+// registered when the objects are kept in the filesystem
+type ObjectController struct {
+	objService services.ObjectService
+	secret     []byte
+}
+
+func NewObjectController(
+	objService services.ObjectService,
+	secret string,
+) ObjectController {
+	return ObjectController{
+		objService: objService,
+		secret:     []byte(secret),
+	}
+}
+
+// verify checks the signature of the url, returning the size it allows to
+// upload, 0 if unlimited
+func (c *ObjectController) verify(ctx *gin.Context, method string, bucket string, objPath string) (int64, bool) {
+	maxSize, err := storage.VerifyObjectSignature(
+		c.secret,
+		method,
+		bucket,
+		objPath,
+		ctx.Query(common.OBJECT_URL_EXP_PARAM),
+		ctx.Query(common.OBJECT_URL_SIZE_PARAM),
+		ctx.Query(common.OBJECT_URL_SIGNATURE_PARAM),
+		time.Now(),
+	)
+
+	return maxSize, err == nil
+}
+
+// @Summary GetObject
+// @Tags Object
+// @Description Downloads an object, the `public/` ones are open, the others need a url from ObjectService.SignedUrl
+// @Produce octet-stream
+// @Param	bucket 		path string true "Bucket"
+// @Param	path 		path string true "Object path"
+// @Param	exp 		query string false "expiration, unix seconds"
+// @Param	sig 		query string false "signature"
+// @Success 200 		{string} 	string "object"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/objects/{bucket}/{path} [GET]
+func (c *ObjectController) GetObject(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	objPath := strings.TrimPrefix(ctx.Param("path"), "/")
+
+	if !storage.IsPublicPath(objPath) {
+		if _, ok := c.verify(ctx, http.MethodGet, bucket, objPath); !ok {
+			fiddlers.AbortWithProblem(ctx, http.StatusForbidden, common.PROBLEM_CODE_FORBIDDEN, "")
+			return
+		}
+	}
+
+	data, err := c.objService.Download(ctx, bucket, objPath)
+	if err != nil {
+		if err == common.ErrObjectNotFound {
+			fiddlers.AbortWithProblem(ctx, http.StatusNotFound, common.PROBLEM_CODE_NOT_FOUND, "")
+			return
+		}
+		if err == common.ErrObjectPathInvalid {
+			fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Data(http.StatusOK, http.DetectContentType(data), data)
+}
+
+// @Summary PutObject
+// @Tags Object
+// @Description Uploads an object to a url from ObjectService.UploadUrl, of at most the `size` it was signed with.
+// @Description Not subject to the request size limit of the other routes.
+// @Accept octet-stream
+// @Produce plain
+// @Param	bucket 		path string true "Bucket"
+// @Param	path 		path string true "Object path"
+// @Param	exp 		query string true "expiration, unix seconds"
+// @Param	size 		query string true "size limit, bytes"
+// @Param	sig 		query string true "signature"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 403 		{object} 	schemas.Problem "Forbidden"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 413 		{object} 	schemas.Problem "Request Entity Too Large"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/objects/{bucket}/{path} [PUT]
+func (c *ObjectController) PutObject(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	objPath := strings.TrimPrefix(ctx.Param("path"), "/")
+
+	// every upload url is signed with its size
+	maxSize, ok := c.verify(ctx, http.MethodPut, bucket, objPath)
+	if !ok || maxSize == 0 {
+		fiddlers.AbortWithProblem(ctx, http.StatusForbidden, common.PROBLEM_CODE_FORBIDDEN, "")
+		return
+	}
+
+	if ctx.Request.ContentLength > maxSize {
+		fiddlers.AbortWithProblem(ctx, http.StatusRequestEntityTooLarge, common.PROBLEM_CODE_TOO_LARGE, "")
+		return
+	}
+
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize)
+	err := c.objService.Upload(ctx, bucket, objPath, ctx.Request.ContentLength, body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			fiddlers.AbortWithProblem(ctx, http.StatusRequestEntityTooLarge, common.PROBLEM_CODE_TOO_LARGE, "")
+			return
+		}
+		if err == common.ErrObjectPathInvalid {
+			fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+// Responds to the errors of UploadService.CreateUpload and ConfirmUpload, of
+// ImageService.StoreImage and of the AttachmentService uploads
+func abortWithUploadError(ctx *gin.Context, err error) {
+	switch err {
+	case common.ErrUploadTooLarge:
+		fiddlers.AbortWithProblem(ctx, http.StatusRequestEntityTooLarge, common.PROBLEM_CODE_TOO_LARGE, "")
+	case common.ErrUploadContentTypeInvalid, common.ErrImageFormatInvalid:
+		fiddlers.AbortWithProblem(ctx, http.StatusUnsupportedMediaType, common.PROBLEM_CODE_MEDIA_TYPE, "")
+	case common.ErrImageTooLarge:
+		fiddlers.AbortWithProblem(ctx, http.StatusRequestEntityTooLarge, common.PROBLEM_CODE_TOO_LARGE, "")
+	case common.ErrStorageQuotaExceeded:
+		fiddlers.AbortWithProblem(ctx, http.StatusRequestEntityTooLarge, common.PROBLEM_CODE_QUOTA, "")
+	case common.ErrUploadMismatch:
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_MISMATCH, "")
+	// not uploaded yet, unknown, expired or already confirmed ones are ErrNotFound
+	case common.ErrObjectNotFound:
+		fiddlers.AbortWithProblem(ctx, http.StatusConflict, common.PROBLEM_CODE_CONFLICT, "")
+	default:
+		ctx.Error(err)
+	}
+}
+
+func (c *ObjectController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
+	g := rg.Group("/objects")
+
+	g.GET("/:bucket/*path", c.GetObject)
+	g.PUT("/:bucket/*path", c.PutObject)
+}
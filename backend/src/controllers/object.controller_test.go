@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/services"
+)
+
+func TestObjectController_PutObject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	objService := services.NewObjectServiceFsImpl(t.TempDir(), "http://api.test", "secret")
+	c := NewObjectController(objService, "secret")
+
+	router := gin.New()
+	router.Use(middlewares.RequestSizeLimiter(common.MAX_REQUEST_SIZE, "/"+storage.OBJECTS_ROUTE+"/:bucket/*path"))
+	c.RegisterRoutes(router.Group("/v1"), nil)
+	// any other route keeps the global limit
+	router.PUT("/v1/other", func(ctx *gin.Context) {
+		_, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, "OK")
+	})
+
+	size := common.MAX_REQUEST_SIZE + 1024*1024
+	data := bytes.Repeat([]byte("x"), int(size))
+
+	uploadUrl := func(t *testing.T, objPath string, size int64) string {
+		signed, err := objService.UploadUrl(ctx, "bucket", objPath, size, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := url.Parse(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.RequestURI()
+	}
+
+	tests := []struct {
+		name          string
+		target        string
+		body          []byte
+		contentLength int64
+		wantStatus    int
+	}{
+		{"above the global limit", uploadUrl(t, "private/big", size), data, size, http.StatusOK},
+		{"above the signed size", uploadUrl(t, "private/bigger", size-1), data, size, http.StatusRequestEntityTooLarge},
+		{"above the signed size, no length", uploadUrl(t, "private/chunked", size-1), data, -1, http.StatusRequestEntityTooLarge},
+		{"unsigned size", "/v1/objects/bucket/private/unsigned", data, size, http.StatusForbidden},
+		{"other route", "/v1/other", data, size, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, tt.target, bytes.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("PUT %s status = %d, want %d", tt.name, w.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	stored, err := objService.Stat(ctx, "bucket", "private/big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Size != size {
+		t.Errorf("stored size = %d, want %d", stored.Size, size)
+	}
+
+	for _, objPath := range []string{"private/bigger", "private/chunked"} {
+		_, err = objService.Stat(ctx, "bucket", objPath)
+		if err != common.ErrObjectNotFound {
+			t.Errorf("Stat(%s) error = %v, want %v", objPath, err, common.ErrObjectNotFound)
+		}
+	}
+}
@@ -1,34 +1,50 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
-	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/patos-ufscar/quack-week/common"
 	"github.com/patos-ufscar/quack-week/fiddlers"
 	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/models"
 	"github.com/patos-ufscar/quack-week/schemas"
 	"github.com/patos-ufscar/quack-week/services"
 )
 
 type OrganizationController struct {
-	userService  services.UserService
-	emailService services.EmailService
-	orgService   services.OrganizationService
+	userService         services.UserService
+	emailService        services.EmailService
+	orgService          services.OrganizationService
+	notificationService services.NotificationService
+	uow                 services.UnitOfWork
+
+	acceptInviteUrl string
 }
 
 func NewOrganizationController(
 	userService services.UserService,
 	emailService services.EmailService,
 	orgService services.OrganizationService,
+	notificationService services.NotificationService,
+	uow services.UnitOfWork,
 ) OrganizationController {
+	acceptInviteUrl, err := url.JoinPath(common.API_HOST_URL, "/v1/organizations/accept-invite")
+	if err != nil {
+		panic(err)
+	}
+
 	return OrganizationController{
-		userService:  userService,
-		emailService: emailService,
-		orgService:   orgService,
+		userService:         userService,
+		emailService:        emailService,
+		orgService:          orgService,
+		notificationService: notificationService,
+		uow:                 uow,
+		acceptInviteUrl:     acceptInviteUrl,
 	}
 }
 
@@ -41,41 +57,35 @@ func NewOrganizationController(
 // @Produce plain
 // @Param   payload 	body 		schemas.CreateOrganization true "org json"
 // @Success 200 		{object} 	schemas.Id
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/organizations [PUT]
 func (c *OrganizationController) CreateOrganization(ctx *gin.Context) {
 	var createOrg schemas.CreateOrganization
 
 	if err := ctx.ShouldBind(&createOrg); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	user, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	org, err := fiddlers.NewOrganization(createOrg.OrganizationName, user.UserId)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while generating organization: %s", err.Error()))
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while generating organization: %s", err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
 	err = c.orgService.CreateOrganization(ctx, *org)
 	if err != nil {
-		if err == common.ErrDbConflict {
-			ctx.String(http.StatusConflict, "Conflict")
-			return
-		}
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
@@ -92,59 +102,67 @@ func (c *OrganizationController) CreateOrganization(ctx *gin.Context) {
 // @Param	orgId 		path string true "Organization Id"
 // @Param   payload 	body 		schemas.CreateOrganizationInvite true "invite json"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/organizations/{orgId}/invite [PUT]
 func (c *OrganizationController) InviteToOrg(ctx *gin.Context) {
 	var createInv schemas.CreateOrganizationInvite
 
 	if err := ctx.ShouldBind(&createInv); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	currUser, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	otp, err := common.GenerateRandomString(common.OTP_LEN)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	user, err := c.userService.GetUser(ctx, createInv.UserEmail)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	org, err := c.orgService.GetOrganization(ctx, *currUser.OrganizationId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	inv := fiddlers.NewOrganizationInvite(*currUser.OrganizationId, user.UserId, createInv.IsAdmin, otp)
-	err = c.orgService.CreateOrganizationInvite(ctx, inv)
-	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
-		return
-	}
+	// the invite is only stored if the user could be notified, the email is
+	// queued in the same transaction
+	err = c.uow.Do(ctx, func(txCtx context.Context) error {
+		err := c.orgService.CreateOrganizationInvite(txCtx, inv)
+		if err != nil {
+			return err
+		}
+
+		sendEmail, err := c.notificationService.Notify(txCtx, user.UserId, common.NOTIFICATION_TYPE_ORGANIZATION_INVITE, models.OrganizationInviteNotification{
+			OrganizationId:   org.OrganizationId,
+			OrganizationName: org.OrganizationName,
+			IsAdmin:          createInv.IsAdmin,
+			AcceptUrl:        c.acceptInviteUrl + "?otp=" + otp,
+		})
+		if err != nil || !sendEmail {
+			return err
+		}
 
-	err = c.emailService.SendOrganizationInvite(user.Email, user.FirstName, otp, org.OrganizationName)
+		return c.emailService.SendOrganizationInvite(txCtx, user.Email, user.Locale, user.FirstName, otp, org.OrganizationName)
+	})
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
@@ -159,9 +177,9 @@ func (c *OrganizationController) InviteToOrg(ctx *gin.Context) {
 // @Produce plain
 // @Param   otp 		query 		string true "OneTimePass sent in email"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/organizations/accept-invite [GET]
 func (c *OrganizationController) AcceptOrgInvite(ctx *gin.Context) {
 
@@ -169,15 +187,14 @@ func (c *OrganizationController) AcceptOrgInvite(ctx *gin.Context) {
 
 	// currUser, err := common.GetClaimsFromGinCtx(ctx)
 	// if err != nil {
-	// 	slog.Error(err.Error())
-	// 	ctx.String(http.StatusBadGateway, "BadGateway")
+	// 	common.Logger(ctx).Error(err.Error())
+	// 	fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 	// 	return
 	// }
 
 	err := c.orgService.ConfirmOrganizationInvite(ctx, otp)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
@@ -192,37 +209,36 @@ func (c *OrganizationController) AcceptOrgInvite(ctx *gin.Context) {
 // @Param	orgId 		path string true "Organization Id"
 // @Param	userId 		path string true "User Id"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/organizations/{orgId}/users/{userId} [DELETE]
 func (c *OrganizationController) RemoveFromOrg(ctx *gin.Context) {
 
 	userId, err := strconv.Atoi(ctx.Param("userId"))
 	if err != nil {
-		ctx.String(http.StatusBadRequest, "BadRequest")
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
 		return
 	}
 
 	var createInv schemas.CreateOrganizationInvite
 
 	if err := ctx.ShouldBind(&createInv); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	currUser, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	err = c.orgService.RemoveUserFromOrg(ctx, *currUser.OrganizationId, uint32(userId))
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
@@ -237,50 +253,47 @@ func (c *OrganizationController) RemoveFromOrg(ctx *gin.Context) {
 // @Param	orgId 		path string true "Organization Id"
 // @Param   payload 	body 		schemas.Email true "email json"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/organizations/{orgId}/owner [POST]
 func (c *OrganizationController) ChangeOwner(ctx *gin.Context) {
 
 	var tgtEmail schemas.Email
 
 	if err := ctx.ShouldBind(&tgtEmail); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	currUser, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	org, err := c.orgService.GetOrganization(ctx, *currUser.OrganizationId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	if currUser.UserId != org.OwnerUserId {
-		ctx.String(http.StatusUnauthorized, "StatusUnauthorized")
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
 	tgtUser, err := c.userService.GetUser(ctx, tgtEmail.Email)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	err = c.orgService.SetOrganizationOwner(ctx, *currUser.OrganizationId, tgtUser.UserId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
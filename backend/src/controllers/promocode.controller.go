@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/schemas"
+	"github.com/patos-ufscar/quack-week/services"
+)
+
+type PromoCodeController struct {
+	promoCodeService services.PromoCodeService
+}
+
+func NewPromoCodeController(
+	promoCodeService services.PromoCodeService,
+) PromoCodeController {
+	return PromoCodeController{
+		promoCodeService: promoCodeService,
+	}
+}
+
+// @Summary CreatePromoCode
+// @Security JWT
+// @Tags PromoCode
+// @Description Creates a Promo Code for the Organization, restricted to `eventIds` if any
+// @Consume application/json
+// @Accept json
+// @Produce json
+// @Param	orgId 		path string true "Organization Id"
+// @Param   payload 	body 		schemas.CreatePromoCode true "promo code json"
+// @Success 200 		{object} 	models.PromoCode
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/promo-codes [PUT]
+func (c *PromoCodeController) CreatePromoCode(ctx *gin.Context) {
+	var createPromo schemas.CreatePromoCode
+
+	if err := ctx.ShouldBind(&createPromo); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	if createPromo.DiscountType == common.DISCOUNT_TYPE_PERCENTAGE && createPromo.DiscountValue > 100 {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	promoCode := fiddlers.NewPromoCode(
+		*claims.OrganizationId,
+		createPromo.Code,
+		createPromo.DiscountType,
+		createPromo.DiscountValue,
+		createPromo.MaxUses,
+		createPromo.Exp,
+		createPromo.EventIds,
+	)
+
+	promoCode, err = c.promoCodeService.CreatePromoCode(ctx, promoCode)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, promoCode)
+}
+
+// @Summary GetPromoCodes
+// @Security JWT
+// @Tags PromoCode
+// @Description Gets the Promo Codes of the Organization
+// @Produce json
+// @Param	orgId 		path string true "Organization Id"
+// @Success 200 		{object} 	[]models.PromoCode
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/promo-codes [GET]
+func (c *PromoCodeController) GetPromoCodes(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	promoCodes, err := c.promoCodeService.GetPromoCodes(ctx, *claims.OrganizationId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, promoCodes)
+}
+
+// @Summary DeletePromoCode
+// @Security JWT
+// @Tags PromoCode
+// @Description Deletes a Promo Code of the Organization
+// @Produce plain
+// @Param	orgId 		path string true "Organization Id"
+// @Param	promoCodeId path string true "Promo Code Id"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/promo-codes/{promoCodeId} [DELETE]
+func (c *PromoCodeController) DeletePromoCode(ctx *gin.Context) {
+	promoCodeId, err := strconv.ParseUint(ctx.Param("promoCodeId"), 10, 32)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	err = c.promoCodeService.DeletePromoCode(ctx, *claims.OrganizationId, uint32(promoCodeId))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+func (c *PromoCodeController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
+	g := rg.Group("/organizations/:orgId/promo-codes")
+
+	g.PUT("", authMiddleware.AuthorizeOrganization(true), c.CreatePromoCode)
+	g.GET("", authMiddleware.AuthorizeOrganization(true), c.GetPromoCodes)
+	g.DELETE("/:promoCodeId", authMiddleware.AuthorizeOrganization(true), c.DeletePromoCode)
+}
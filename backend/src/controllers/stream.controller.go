@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/services"
+)
+
+type StreamController struct {
+	streamService services.StreamService
+}
+
+func NewStreamController(
+	streamService services.StreamService,
+) StreamController {
+	return StreamController{
+		streamService: streamService,
+	}
+}
+
+// @Summary Stream
+// @Security JWT
+// @Tags Stream
+// @Description Server-Sent Events stream of the User. `notification` events carry a models.Notification,
+// @Description members of an Organization also get `registrations` events (models.RegistrationsStreamEvent) of its events.
+// @Description Reconnect with the `Last-Event-ID` header (sent by EventSource) or the `lastEventId` query to get what was missed.
+// @Produce text/event-stream
+// @Param	Last-Event-ID	header	string false "id of the last event received"
+// @Param	lastEventId		query	string false "same as Last-Event-ID, for clients that cannot set headers"
+// @Success 200 		{string} 	string "text/event-stream"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/stream [GET]
+func (c *StreamController) Stream(ctx *gin.Context) {
+	lastEventIdStr := ctx.GetHeader("Last-Event-ID")
+	if lastEventIdStr == "" {
+		lastEventIdStr = ctx.Query("lastEventId")
+	}
+
+	lastEventId, err := fiddlers.ParseLastEventId(lastEventIdStr)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	topics := []string{fiddlers.GetUserStreamTopic(claims.UserId)}
+	if claims.OrganizationId != nil {
+		topics = append(topics, fiddlers.GetOrganizationStreamTopic(*claims.OrganizationId))
+	}
+
+	events, err := c.streamService.Subscribe(ctx.Request.Context(), topics, lastEventId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// nginx buffers responses by default
+	ctx.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(time.Duration(common.STREAM_HEARTBEAT_SECS) * time.Second)
+	defer heartbeat.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-events:
+			// closed when the client is gone or fell behind, in which case it
+			// reconnects with Last-Event-ID
+			if !ok {
+				return false
+			}
+			return fiddlers.WriteSseEvent(w, e) == nil
+		case <-heartbeat.C:
+			return fiddlers.WriteSseComment(w, "ping") == nil
+		}
+	})
+}
+
+func (c *StreamController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
+	rg.GET("/stream", authMiddleware.AuthorizeUser(), c.Stream)
+}
@@ -1,86 +1,91 @@
 package controllers
 
 import (
-	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
-	"log/slog"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/patos-ufscar/quack-week/common"
 	"github.com/patos-ufscar/quack-week/fiddlers"
-	"github.com/patos-ufscar/quack-week/fiddlers/storage"
 	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/models"
 	"github.com/patos-ufscar/quack-week/schemas"
 	"github.com/patos-ufscar/quack-week/services"
 )
 
 type UserController struct {
-	authService  services.AuthService
-	userService  services.UserService
-	emailService services.EmailService
-	objService   services.ObjectService
+	authService   services.AuthService
+	userService   services.UserService
+	emailService  services.EmailService
+	imageService  services.ImageService
+	uploadService services.UploadService
+	uow           services.UnitOfWork
 }
 
 func NewUserController(
 	authService services.AuthService,
 	userService services.UserService,
 	emailService services.EmailService,
-	objService services.ObjectService,
+	imageService services.ImageService,
+	uploadService services.UploadService,
+	uow services.UnitOfWork,
 ) UserController {
 	return UserController{
-		authService:  authService,
-		userService:  userService,
-		emailService: emailService,
-		objService:   objService,
+		authService:   authService,
+		userService:   userService,
+		emailService:  emailService,
+		imageService:  imageService,
+		uploadService: uploadService,
+		uow:           uow,
 	}
 }
 
 // @Summary CreateUser
 // @Tags User
-// @Description Creates an User
+// @Description Creates an User, the emails language is `locale` or else detected from `Accept-Language`
 // @Consume application/json
 // @Accept json
 // @Produce plain
 // @Param   payload 	body 		schemas.CreateUser true "user json"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/users [PUT]
 func (c *UserController) CreateUser(ctx *gin.Context) {
 	var createUser schemas.CreateUser
 
 	if err := ctx.ShouldBind(&createUser); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
-	unconfirmedUser, err := fiddlers.NewUnconfirmedUser(createUser.Email, createUser.Password, createUser.FirstName, createUser.LastName, createUser.DateOfBirth)
+	locale := createUser.Locale
+	if locale == "" {
+		locale = fiddlers.ParseAcceptLanguage(ctx.GetHeader("Accept-Language"))
+	}
+
+	unconfirmedUser, err := fiddlers.NewUnconfirmedUser(createUser.Email, createUser.Password, createUser.FirstName, createUser.LastName, createUser.DateOfBirth, locale)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while generating unconfirmed user '%s': '%s'", createUser.Email, err.Error()))
-		ctx.String(http.StatusBadRequest, "BadRequest")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while generating unconfirmed user '%s': '%s'", createUser.Email, err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
 		return
 	}
 
 	err = c.userService.CreateUnconfirmedUser(ctx, *unconfirmedUser)
-	if err == common.ErrDbConflict {
-		ctx.String(http.StatusConflict, "Conflict")
-		return
-	}
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while creating unconfirmedUser '%s': '%s'", unconfirmedUser.Email, err.Error()))
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(fmt.Errorf("creating unconfirmedUser '%s': %w", unconfirmedUser.Email, err))
 		return
 	}
 
-	err = c.emailService.SendEmailConfirmation(unconfirmedUser.Email, unconfirmedUser.FirstName, unconfirmedUser.Otp)
+	err = c.emailService.SendEmailConfirmation(ctx, unconfirmedUser.Email, unconfirmedUser.Locale, unconfirmedUser.FirstName, unconfirmedUser.Otp)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while sending email '%s': '%s'", unconfirmedUser.Email, err.Error()))
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while sending email '%s': '%s'", unconfirmedUser.Email, err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
@@ -93,17 +98,17 @@ func (c *UserController) CreateUser(ctx *gin.Context) {
 // @Produce plain
 // @Param   otp 		query 		string true "OneTimePass sent in email"
 // @Success 302 		{string} 	OKResponse "StatusFound"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/users/confirm [GET]
 func (c *UserController) ConfirmUser(ctx *gin.Context) {
 	otp := ctx.Query("otp")
 
 	err := c.userService.ConfirmUser(ctx, otp)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while confirming user otp='%s': '%s'", otp, err.Error()))
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while confirming user otp='%s': '%s'", otp, err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
@@ -118,21 +123,20 @@ func (c *UserController) ConfirmUser(ctx *gin.Context) {
 // @Description Gets Orgs Users Belongs to
 // @Produce json
 // @Success 200 		{object} 	[]schemas.OrganizationOutput
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/users/organizations [GET]
 func (c *UserController) GetUserOrgs(ctx *gin.Context) {
 	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
 	orgs, err := c.userService.GetUserOrgs(ctx, claims.UserId)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
@@ -147,44 +151,44 @@ func (c *UserController) GetUserOrgs(ctx *gin.Context) {
 // @Produce plain
 // @Param   payload 	body 		schemas.Email true "email json"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/users/init-reset-password [POST]
 func (c *UserController) InitResetPassword(ctx *gin.Context) {
 	var email schemas.Email
 
 	if err := ctx.ShouldBind(&email); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	otp, err := common.GenerateRandomString(common.OTP_LEN)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	user, err := c.userService.GetUser(ctx, email.Email)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	err = c.userService.InitPasswordReset(ctx, user.UserId, otp)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
-	err = c.emailService.SendPasswordReset(email.Email, user.FirstName, otp)
+	err = c.emailService.SendPasswordReset(ctx, email.Email, user.Locale, user.FirstName, otp)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
@@ -197,24 +201,24 @@ func (c *UserController) InitResetPassword(ctx *gin.Context) {
 // @Produce plain
 // @Param   otp 		query 		string true "OneTimePass sent in email"
 // @Success 302 		{string} 	OKResponse "StatusFound"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/users/set-password-reset-cookie [GET]
 func (c *UserController) SetPasswordResetCookie(ctx *gin.Context) {
 	otp := ctx.Query("otp")
 
 	reset, err := c.userService.GetPasswordReset(ctx, otp)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while setting cookie user otp='%s': '%s'", otp, err.Error()))
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while setting cookie user otp='%s': '%s'", otp, err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
 	tokenStr, err := c.authService.InitPasswordResetToken(reset.UserId)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while confirming user otp='%s': '%s'", otp, err.Error()))
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		common.Logger(ctx).Error(fmt.Sprintf("Error while confirming user otp='%s': '%s'", otp, err.Error()))
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
@@ -232,36 +236,34 @@ func (c *UserController) SetPasswordResetCookie(ctx *gin.Context) {
 // @Produce plain
 // @Param   payload 	body 		schemas.Password true "pw json"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/users/reset-password [POST]
 func (c *UserController) ResetPassword(ctx *gin.Context) {
 	var pw schemas.Password
 
 	if err := ctx.ShouldBind(&pw); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	cookieVal, err := ctx.Cookie(common.PASSWORD_RESET_TIMEOUT_JWT_COOKIE_NAME)
 	if err != nil {
-		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 		return
 	}
 
 	claims, err := c.authService.ParsePasswordResetToken(cookieVal)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	err = c.userService.UpdateUserPassword(ctx, claims.UserId, pw.Password)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
@@ -277,109 +279,281 @@ func (c *UserController) ResetPassword(ctx *gin.Context) {
 // @Produce plain
 // @Param   payload 	body 		schemas.EditUser true "editUser json"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/users/edit [POST]
 func (c *UserController) EditUser(ctx *gin.Context) {
 	var editUser schemas.EditUser
 
 	if err := ctx.ShouldBind(&editUser); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
 	err = c.userService.EditUser(ctx, claims.UserId, editUser)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		ctx.Error(err)
 		return
 	}
 
 	ctx.String(http.StatusOK, "OK")
 }
 
-// @Summary SetPicture
+// @Summary SetLocale
 // @Tags User
-// @Description Sets User Picture
+// @Security JWT
+// @Description Sets the language of the emails sent to the User
 // @Consume application/json
 // @Accept json
 // @Produce plain
-// @Param   payload 	body 		schemas.UploadPicture true "picture json"
+// @Param   payload 	body 		schemas.Locale true "locale json"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/users/locale [PUT]
+func (c *UserController) SetLocale(ctx *gin.Context) {
+	var locale schemas.Locale
+
+	if err := ctx.ShouldBind(&locale); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	err = c.userService.SetLocale(ctx, claims.UserId, locale.Locale)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+// @Summary SetAnnouncementsSubscription
+// @Tags User
+// @Security JWT
+// @Description Subscribes or unsubscribes the User from event announcements, transactional emails are always sent
+// @Consume application/json
+// @Accept json
+// @Produce plain
+// @Param   payload 	body 		schemas.AnnouncementsSubscription true "subscription json"
 // @Success 200 		{string} 	OKResponse "OK"
-// @Failure 400 		{string} 	ErrorResponse "Bad Request"
-// @Failure 409 		{string} 	ErrorResponse "Conflict"
-// @Failure 502 		{string} 	ErrorResponse "Bad Gateway"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/users/announcements [PUT]
+func (c *UserController) SetAnnouncementsSubscription(ctx *gin.Context) {
+	var subscription schemas.AnnouncementsSubscription
+
+	if err := ctx.ShouldBind(&subscription); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
+		return
+	}
+
+	err = c.userService.SetAnnouncementsOptOut(ctx, claims.UserId, !*subscription.Subscribed)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+// @Summary Unsubscribe
+// @Tags User
+// @Description Unsubscribes the User from event announcements, linked in every announcement email. Also accepts POST for one-click unsubscribe.
+// @Produce plain
+// @Param   token 		query 		string true "Unsubscribe token sent in email"
+// @Success 302 		{string} 	OKResponse "StatusFound"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/users/unsubscribe [GET]
+func (c *UserController) Unsubscribe(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	err := c.userService.Unsubscribe(ctx, token)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if ctx.Request.Method == http.MethodPost {
+		ctx.String(http.StatusOK, "OK")
+		return
+	}
+
+	ctx.Header("location", common.APP_HOST_URL)
+	ctx.String(http.StatusFound, "Found")
+}
+
+// @Summary SetPicture
+// @Tags User
+// @Description Sets User Picture, stored as resized WebP and JPEG variants
+// @Consume application/json
+// @Accept json
+// @Produce json
+// @Param   payload 	body 		schemas.UploadPicture true "picture json"
+// @Success 200 		{object} 	models.ImageSrcset
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 413 		{object} 	schemas.Problem "Request Entity Too Large"
+// @Failure 415 		{object} 	schemas.Problem "Unsupported Media Type"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
 // @Router /v1/users/profile-picture [PUT]
 func (c *UserController) SetPicture(ctx *gin.Context) {
 	var pic schemas.UploadPicture
 
 	if err := ctx.ShouldBind(&pic); err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
 	picBytes, err := base64.StdEncoding.DecodeString(pic.Content)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
 	if len(picBytes) > 100*1024 { // if > 100k
-		ctx.String(http.StatusBadGateway, "ImgTooLarge")
+		fiddlers.AbortWithProblem(ctx, http.StatusRequestEntityTooLarge, common.PROBLEM_CODE_TOO_LARGE, "")
 		return
 	}
 
-	imgFmt, err := common.GetImageFormat(picBytes)
+	var srcset models.ImageSrcset
+	err = c.uow.Do(ctx, func(txCtx context.Context) error {
+		srcset, err = c.imageService.StoreImage(txCtx, common.UPLOAD_PURPOSE_AVATAR, strconv.Itoa(int(claims.UserId)), picBytes)
+		if err != nil {
+			return err
+		}
+
+		return c.userService.SetAvatarUrl(txCtx, claims.UserId, srcset[common.IMAGE_VARIANT_FULL].Webp)
+	})
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadGateway, "BadGateway")
+		abortWithUploadError(ctx, err)
 		return
 	}
 
-	if imgFmt != "png" && imgFmt != "jpeg" {
-		ctx.String(http.StatusUnsupportedMediaType, "UnsupportedMediaType")
+	ctx.JSON(http.StatusOK, srcset)
+}
+
+// @Summary GetPictureUploadUrl
+// @Security JWT
+// @Tags User
+// @Description Gets a presigned url to upload the User Picture directly to the storage, then call the confirm endpoint
+// @Consume application/json
+// @Accept json
+// @Produce json
+// @Param   payload 	body 		schemas.CreateUpload true "upload json"
+// @Success 200 		{object} 	schemas.UploadUrl
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 413 		{object} 	schemas.Problem "Request Entity Too Large"
+// @Failure 415 		{object} 	schemas.Problem "Unsupported Media Type"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/users/profile-picture/upload-url [POST]
+func (c *UserController) GetPictureUploadUrl(ctx *gin.Context) {
+	var createUpload schemas.CreateUpload
+
+	if err := ctx.ShouldBind(&createUpload); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
 		return
 	}
 
-	objPath := storage.GetPublicPath(storage.USER_AVATARS, strconv.Itoa(int(claims.UserId)))
-	err = c.objService.Upload(ctx, common.S3_BUCKET, objPath, int64(len(picBytes)), bytes.NewReader(picBytes))
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
-	objUrl, err := storage.GetFullObjUrl(objPath)
+	upload, uploadUrl, err := c.uploadService.CreateUpload(ctx, models.Upload{
+		UserId:      claims.UserId,
+		Purpose:     common.UPLOAD_PURPOSE_AVATAR,
+		TargetId:    strconv.Itoa(int(claims.UserId)),
+		ContentType: createUpload.ContentType,
+		Size:        createUpload.Size,
+	})
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		abortWithUploadError(ctx, err)
 		return
 	}
 
-	err = c.userService.SetAvatarUrl(ctx, claims.UserId, objUrl)
+	ctx.JSON(http.StatusOK, schemas.UploadUrl{
+		UploadId: upload.UploadId,
+		Url:      uploadUrl,
+		Method:   http.MethodPut,
+		Exp:      upload.Exp,
+	})
+}
+
+// @Summary ConfirmPictureUpload
+// @Security JWT
+// @Tags User
+// @Description Sets the User Picture to the object uploaded to the url of GetPictureUploadUrl
+// @Produce json
+// @Param	uploadId 	path string true "Upload Id"
+// @Success 200 		{object} 	models.ImageSrcset
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 413 		{object} 	schemas.Problem "Request Entity Too Large"
+// @Failure 415 		{object} 	schemas.Problem "Unsupported Media Type"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/users/profile-picture/confirm/{uploadId} [POST]
+func (c *UserController) ConfirmPictureUpload(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
 	if err != nil {
-		slog.Error(err.Error())
-		ctx.String(http.StatusBadRequest, err.Error())
+		fiddlers.AbortWithProblem(ctx, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 		return
 	}
 
-	ctx.String(http.StatusOK, "OK")
+	var srcset models.ImageSrcset
+	err = c.uow.Do(ctx, func(txCtx context.Context) error {
+		srcset, err = c.uploadService.ConfirmUpload(txCtx, claims.UserId, ctx.Param("uploadId"), common.UPLOAD_PURPOSE_AVATAR, strconv.Itoa(int(claims.UserId)))
+		if err != nil {
+			return err
+		}
+
+		return c.userService.SetAvatarUrl(txCtx, claims.UserId, srcset[common.IMAGE_VARIANT_FULL].Webp)
+	})
+	if err != nil {
+		abortWithUploadError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, srcset)
 }
 
 func (c *UserController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
@@ -393,4 +567,10 @@ func (c *UserController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware midd
 	g.GET("/organizations", authMiddleware.AuthorizeUser(), c.GetUserOrgs)
 	g.POST("/edit", authMiddleware.AuthorizeUser(), c.EditUser)
 	g.PUT("/profile-picture", authMiddleware.AuthorizeUser(), c.SetPicture)
+	g.POST("/profile-picture/upload-url", authMiddleware.AuthorizeUser(), c.GetPictureUploadUrl)
+	g.POST("/profile-picture/confirm/:uploadId", authMiddleware.AuthorizeUser(), c.ConfirmPictureUpload)
+	g.PUT("/locale", authMiddleware.AuthorizeUser(), c.SetLocale)
+	g.PUT("/announcements", authMiddleware.AuthorizeUser(), c.SetAnnouncementsSubscription)
+	g.GET("/unsubscribe", c.Unsubscribe)
+	g.POST("/unsubscribe", c.Unsubscribe)
 }
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/middlewares"
+	"github.com/patos-ufscar/quack-week/schemas"
+	"github.com/patos-ufscar/quack-week/services"
+)
+
+type WebhookController struct {
+	webhookService services.WebhookService
+}
+
+func NewWebhookController(
+	webhookService services.WebhookService,
+) WebhookController {
+	return WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// @Summary CreateWebhook
+// @Security JWT
+// @Tags Webhook
+// @Description Registers a Webhook of the Organization, POSTed a models.WebhookEnvelope for each of `eventTypes`.
+// @Description The `secret` is only returned here, deliveries carry the `X-Webhook-Signature: t=<unix>,v1=<hex>` header,
+// @Description where v1 is the HMAC-SHA256 of "<t>.<body>" with the secret.
+// @Description The `url` must resolve to public addresses, loopback and private networks are rejected.
+// @Consume application/json
+// @Accept json
+// @Produce json
+// @Param	orgId 		path string true "Organization Id"
+// @Param   payload 	body 		schemas.CreateWebhook true "webhook json"
+// @Success 200 		{object} 	models.Webhook
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/webhooks [PUT]
+func (c *WebhookController) CreateWebhook(ctx *gin.Context) {
+	var createWebhook schemas.CreateWebhook
+
+	if err := ctx.ShouldBind(&createWebhook); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	webhook, err := c.webhookService.CreateWebhook(ctx, *claims.OrganizationId, createWebhook.Url, createWebhook.EventTypes)
+	if errors.Is(err, common.ErrWebhookUrlForbidden) {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_WEBHOOK_URL, "url must resolve to a public address")
+		return
+	}
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+// @Summary GetWebhooks
+// @Security JWT
+// @Tags Webhook
+// @Description Gets the Webhooks of the Organization
+// @Produce json
+// @Param	orgId 		path string true "Organization Id"
+// @Success 200 		{object} 	[]models.Webhook
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/webhooks [GET]
+func (c *WebhookController) GetWebhooks(ctx *gin.Context) {
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	webhooks, err := c.webhookService.GetWebhooks(ctx, *claims.OrganizationId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary DeleteWebhook
+// @Security JWT
+// @Tags Webhook
+// @Description Deletes a Webhook of the Organization, with its delivery log
+// @Produce plain
+// @Param	orgId 		path string true "Organization Id"
+// @Param	webhookId 	path string true "Webhook Id"
+// @Success 200 		{string} 	OKResponse "OK"
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/webhooks/{webhookId} [DELETE]
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	webhookId, err := strconv.ParseUint(ctx.Param("webhookId"), 10, 32)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	err = c.webhookService.DeleteWebhook(ctx, *claims.OrganizationId, uint32(webhookId))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "OK")
+}
+
+// @Summary GetWebhookDeliveries
+// @Security JWT
+// @Tags Webhook
+// @Description Gets the delivery log of a Webhook of the Organization, newest first
+// @Produce json
+// @Param	orgId 		path string true "Organization Id"
+// @Param	webhookId 	path string true "Webhook Id"
+// @Param	limit 		query 		int false "defaults to 50, max 100"
+// @Param	offset 		query 		int false "defaults to 0"
+// @Success 200 		{object} 	[]models.WebhookDelivery
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/webhooks/{webhookId}/deliveries [GET]
+func (c *WebhookController) GetDeliveries(ctx *gin.Context) {
+	var query schemas.Page
+
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		common.Logger(ctx).Error(err.Error())
+		fiddlers.AbortWithBadRequest(ctx, err)
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = common.WEBHOOK_DELIVERIES_PAGE_SIZE
+	}
+
+	webhookId, err := strconv.ParseUint(ctx.Param("webhookId"), 10, 32)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	deliveries, err := c.webhookService.GetDeliveries(ctx, *claims.OrganizationId, uint32(webhookId), query.Limit, query.Offset)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary RedeliverWebhook
+// @Security JWT
+// @Tags Webhook
+// @Description Sends the payload of a delivery again, as a new delivery
+// @Produce json
+// @Param	orgId 		path string true "Organization Id"
+// @Param	deliveryId 	path string true "Delivery Id"
+// @Success 200 		{object} 	models.WebhookDelivery
+// @Failure 400 		{object} 	schemas.Problem "Bad Request"
+// @Failure 404 		{object} 	schemas.Problem "Not Found"
+// @Failure 409 		{object} 	schemas.Problem "Conflict"
+// @Failure 502 		{object} 	schemas.Problem "Bad Gateway"
+// @Router /v1/organizations/{orgId}/webhooks/deliveries/{deliveryId}/redeliver [POST]
+func (c *WebhookController) Redeliver(ctx *gin.Context) {
+	deliveryId, err := strconv.ParseUint(ctx.Param("deliveryId"), 10, 64)
+	if err != nil {
+		fiddlers.AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, "")
+		return
+	}
+
+	claims, err := fiddlers.GetClaimsFromGinCtx(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	delivery, err := c.webhookService.Redeliver(ctx, *claims.OrganizationId, deliveryId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delivery)
+}
+
+func (c *WebhookController) RegisterRoutes(rg *gin.RouterGroup, authMiddleware middlewares.AuthMiddleware) {
+	g := rg.Group("/organizations/:orgId/webhooks")
+
+	g.PUT("", authMiddleware.AuthorizeOrganization(true), c.CreateWebhook)
+	g.GET("", authMiddleware.AuthorizeOrganization(true), c.GetWebhooks)
+	g.DELETE("/:webhookId", authMiddleware.AuthorizeOrganization(true), c.DeleteWebhook)
+	g.GET("/:webhookId/deliveries", authMiddleware.AuthorizeOrganization(true), c.GetDeliveries)
+	g.POST("/deliveries/:deliveryId/redeliver", authMiddleware.AuthorizeOrganization(true), c.Redeliver)
+}
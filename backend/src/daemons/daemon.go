@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"log/slog"
 	"time"
+
+	"github.com/patos-ufscar/quack-week/metrics"
 )
 
 type Task struct {
+	// labels the task metrics
+	Name     string
 	Interval time.Duration
 	Callable func() error
 	Workers  uint32
@@ -20,8 +24,9 @@ type TaskRunner struct {
 }
 
 // RegisterTask Creates and inserts a task in the TaskRunner, runs only after Dispatch() call
-func (f *TaskRunner) RegisterTask(interval time.Duration, callable func() error, workers uint32) {
+func (f *TaskRunner) RegisterTask(name string, interval time.Duration, callable func() error, workers uint32) {
 	f.tasks = append(f.tasks, Task{
+		Name:     name,
 		Interval: interval,
 		Callable: callable,
 		Workers:  workers,
@@ -38,16 +43,26 @@ func (f *TaskRunner) Dispatch() {
 }
 
 func taskWrapper(t Task) {
+	start := time.Now()
 	defer func() {
 		if r := recover(); r != nil {
-			slog.Error(fmt.Sprintf("Task crashed: %v, waiting 5s to restart...", r))
+			observeTask(t, start, metrics.TASK_OUTCOME_PANIC)
+			slog.Error(fmt.Sprintf("Task %s crashed: %v, waiting 5s to restart...", t.Name, r))
 			time.Sleep(5 * time.Second)
 		}
 	}()
 	err := t.Callable()
 	if err != nil {
-		slog.Error(err.Error())
+		observeTask(t, start, metrics.TASK_OUTCOME_ERROR)
+		slog.Error(err.Error(), slog.String("task", t.Name))
+		return
 	}
+	observeTask(t, start, metrics.TASK_OUTCOME_SUCCESS)
+}
+
+func observeTask(t Task, start time.Time, outcome string) {
+	metrics.TaskRuns.WithLabelValues(t.Name, outcome).Inc()
+	metrics.TaskDuration.WithLabelValues(t.Name).Observe(time.Since(start).Seconds())
 }
 
 func taskRunner(t Task) {
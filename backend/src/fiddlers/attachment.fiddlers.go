@@ -0,0 +1,54 @@
+package fiddlers
+
+import (
+	"net/http"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+// The public attachments can be read by anyone with the url, the private ones
+// only with a signed url
+func GetAttachmentPath(visibility string, eventId string, attachmentId string) string {
+	filename := eventId + "/" + attachmentId
+
+	if visibility == common.ATTACHMENT_VISIBILITY_PUBLIC {
+		return storage.GetPublicPath(storage.EVENT_ATTACHMENTS, filename)
+	}
+
+	return storage.GetPrivatePath(storage.EVENT_ATTACHMENTS, filename)
+}
+
+// ValidateAttachmentRequest checks what the client declares before it gets an
+// upload url, `used` and `quota` are the storage of the organization
+func ValidateAttachmentRequest(contentType string, size int64, used int64, quota int64) error {
+	if _, ok := common.ATTACHMENT_CONTENT_TYPES[contentType]; !ok {
+		return common.ErrUploadContentTypeInvalid
+	}
+
+	if size > common.ATTACHMENT_MAX_BYTES {
+		return common.ErrUploadTooLarge
+	}
+
+	if used+size > quota {
+		return common.ErrStorageQuotaExceeded
+	}
+
+	return nil
+}
+
+// ValidateUploadedAttachment checks the uploaded object against what was
+// declared, the type is sniffed from the `data` as the storage metadata is
+// set by the client
+func ValidateUploadedAttachment(attachment models.EventAttachment, info models.ObjectInfo, data []byte) error {
+	if info.Size != attachment.Size || int64(len(data)) != attachment.Size {
+		return common.ErrUploadMismatch
+	}
+
+	if http.DetectContentType(data) != common.ATTACHMENT_CONTENT_TYPES[attachment.ContentType] {
+		return common.ErrUploadContentTypeInvalid
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package fiddlers
+
+import (
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestGetAttachmentPath(t *testing.T) {
+	if got := GetAttachmentPath(common.ATTACHMENT_VISIBILITY_PUBLIC, "ev", "at"); got != "public/event-attachments/ev/at" {
+		t.Errorf("GetAttachmentPath() public = %s", got)
+	}
+	if got := GetAttachmentPath(common.ATTACHMENT_VISIBILITY_PRIVATE, "ev", "at"); got != "private/event-attachments/ev/at" {
+		t.Errorf("GetAttachmentPath() private = %s", got)
+	}
+}
+
+func TestValidateAttachmentRequest(t *testing.T) {
+	pptx := "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+
+	tests := []struct {
+		name        string
+		contentType string
+		size        int64
+		used        int64
+		quota       int64
+		wantErr     error
+	}{
+		{"pdf", "application/pdf", 1024, 0, 2048, nil},
+		{"slides filling the quota", pptx, 1024, 1024, 2048, nil},
+		{"over quota", "application/pdf", 1024, 1025, 2048, common.ErrStorageQuotaExceeded},
+		{"too large", "application/pdf", common.ATTACHMENT_MAX_BYTES + 1, 0, common.ATTACHMENT_DEFAULT_QUOTA_BYTES, common.ErrUploadTooLarge},
+		{"html", "text/html", 1024, 0, 2048, common.ErrUploadContentTypeInvalid},
+		{"executable", "application/octet-stream", 1024, 0, 2048, common.ErrUploadContentTypeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateAttachmentRequest(tt.contentType, tt.size, tt.used, tt.quota); err != tt.wantErr {
+				t.Errorf("ValidateAttachmentRequest() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateUploadedAttachment(t *testing.T) {
+	pdf := []byte("%PDF-1.7\n%quack")
+	zip := []byte("PK\x03\x04quack-slides")
+	pptx := "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+
+	tests := []struct {
+		name        string
+		contentType string
+		size        int64
+		data        []byte
+		wantErr     error
+	}{
+		{"pdf", "application/pdf", int64(len(pdf)), pdf, nil},
+		{"slides", pptx, int64(len(zip)), zip, nil},
+		{"other size", "application/pdf", int64(len(pdf)) + 1, pdf, common.ErrUploadMismatch},
+		{"html as pdf", "application/pdf", 13, []byte("<html></html>"), common.ErrUploadContentTypeInvalid},
+		{"pdf as slides", pptx, int64(len(pdf)), pdf, common.ErrUploadContentTypeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachment := models.EventAttachment{ContentType: tt.contentType, Size: tt.size}
+			info := models.ObjectInfo{Size: int64(len(tt.data))}
+			if err := ValidateUploadedAttachment(attachment, info, tt.data); err != tt.wantErr {
+				t.Errorf("ValidateUploadedAttachment() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,7 +1,71 @@
 package fiddlers
 
-import "github.com/stripe/stripe-go/v81"
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
 
-func IsStripeChechouseSessionPaid(cs *stripe.CheckoutSession) bool {
-	return cs.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+// Payments with nothing to charge never go through Stripe, so they start complete
+func GetInitialPaymentStatus(total int64) string {
+	if total == 0 {
+		return "complete"
+	}
+	return "pending"
+}
+
+func GetInitialPaymentCompletedAt(total int64) *time.Time {
+	if total == 0 {
+		now := time.Now()
+		return &now
+	}
+	return nil
+}
+
+// Gets the platform's share of `total`, rounded down
+func GetPlatformFeeAmmount(total int64, feePercent int64) int64 {
+	return total * feePercent / 100
+}
+
+func WriteRevenueCsv(w io.Writer, entries []models.RevenueEntry) error {
+	csvWriter := csv.NewWriter(w)
+
+	err := csvWriter.Write([]string{"event_id", "event_name", "period_start", "currency", "payments", "gross", "fees", "net"})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		err := csvWriter.Write([]string{
+			e.EventId,
+			e.EventName,
+			e.PeriodStart.Format(common.TIMESTAMP_STR_FORMAT),
+			e.Currency,
+			strconv.FormatUint(uint64(e.Payments), 10),
+			formatCents(e.Gross),
+			formatCents(e.Fees),
+			formatCents(e.Net),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// 12345 -> "123.45"
+func formatCents(v int64) string {
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
 }
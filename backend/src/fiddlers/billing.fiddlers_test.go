@@ -0,0 +1,51 @@
+package fiddlers
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestWriteRevenueCsv(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []models.RevenueEntry
+		want    string
+	}{
+		{
+			"no revenue",
+			[]models.RevenueEntry{},
+			"event_id,event_name,period_start,currency,payments,gross,fees,net\n",
+		},
+		{
+			"one event",
+			[]models.RevenueEntry{
+				{
+					EventId:     "e1",
+					EventName:   "Quack Week, 2026",
+					PeriodStart: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+					Currency:    "brl",
+					Payments:    3,
+					Gross:       90000,
+					Fees:        4505,
+					Net:         85495,
+				},
+			},
+			"event_id,event_name,period_start,currency,payments,gross,fees,net\n" +
+				"e1,\"Quack Week, 2026\",2026-10-01T00:00:00Z,brl,3,900.00,45.05,854.95\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			if err := WriteRevenueCsv(w, tt.entries); err != nil {
+				t.Fatalf("WriteRevenueCsv() error = %v", err)
+			}
+			if got := w.String(); got != tt.want {
+				t.Errorf("WriteRevenueCsv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
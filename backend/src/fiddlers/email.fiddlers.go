@@ -0,0 +1,98 @@
+package fiddlers
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+// BuildMimeMessage renders a multipart/alternative email with a plain-text and
+// an HTML part as an RFC 5322 message
+func BuildMimeMessage(from string, to string, subject string, html string, text string, date time.Time, messageId string) ([]byte, error) {
+	msg := new(bytes.Buffer)
+	mw := multipart.NewWriter(msg)
+
+	fmt.Fprintf(msg, "From: %s\r\n", from)
+	fmt.Fprintf(msg, "To: %s\r\n", to)
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "Message-ID: <%s>\r\n", messageId)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: multipart/alternative; boundary=%q\r\n", mw.Boundary())
+	msg.WriteString("\r\n")
+
+	// clients show the last part they support, so html goes last
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		w := quotedprintable.NewWriter(pw)
+		_, err = w.Write([]byte(p.content))
+		if err != nil {
+			return nil, err
+		}
+		err = w.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := mw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return msg.Bytes(), nil
+}
+
+// GetEmailRetryDelay is the exponential backoff before retrying an email that failed `attempts` times
+func GetEmailRetryDelay(attempts int) time.Duration {
+	base := time.Duration(common.EMAIL_OUTBOX_BACKOFF_SECS) * time.Second
+	maxDelay := time.Duration(common.EMAIL_OUTBOX_MAX_BACKOFF_HOURS) * time.Hour
+
+	if attempts < 1 {
+		return base
+	}
+
+	// past 2^20 the delay is way above the cap anyway
+	delay := base << min(attempts-1, 20)
+	if delay > maxDelay {
+		return maxDelay
+	}
+
+	return delay
+}
+
+// IsPlatformAdmin checks `email` against the comma separated `adminEmails`
+func IsPlatformAdmin(email string, adminEmails string) bool {
+	if email == "" {
+		return false
+	}
+
+	for _, admin := range strings.Split(adminEmails, ",") {
+		if strings.EqualFold(strings.TrimSpace(admin), email) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package fiddlers
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMimeMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		html    string
+		text    string
+	}{
+		{"ascii", "Password Reset", "<p>Hello</p>", "Hello\n"},
+		{"accents", "Confirme sua conta!", "<p>Olá, João</p>", "Olá, João\n"},
+		{"long line", "Payment Accepted", "<p>" + string(bytes.Repeat([]byte("quack "), 50)) + "</p>", string(bytes.Repeat([]byte("quack "), 50))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+			raw, err := BuildMimeMessage("no-reply@example.com", "user@example.com", tt.subject, tt.html, tt.text, date, "1@example.com")
+			if err != nil {
+				t.Fatalf("BuildMimeMessage() error = %v", err)
+			}
+
+			msg, err := mail.ReadMessage(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("mail.ReadMessage() error = %v", err)
+			}
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if err != nil {
+				t.Fatalf("DecodeHeader() error = %v", err)
+			}
+			if subject != tt.subject {
+				t.Errorf("Subject = %q, want %q", subject, tt.subject)
+			}
+			if got := msg.Header.Get("To"); got != "user@example.com" {
+				t.Errorf("To = %q, want %q", got, "user@example.com")
+			}
+
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			if err != nil {
+				t.Fatalf("ParseMediaType() error = %v", err)
+			}
+			if mediaType != "multipart/alternative" {
+				t.Fatalf("Content-Type = %q, want multipart/alternative", mediaType)
+			}
+
+			wantParts := []struct{ contentType, body string }{
+				{"text/plain", tt.text},
+				{"text/html", tt.html},
+			}
+			mr := multipart.NewReader(msg.Body, params["boundary"])
+			for _, want := range wantParts {
+				part, err := mr.NextRawPart()
+				if err != nil {
+					t.Fatalf("NextRawPart() error = %v", err)
+				}
+				if got, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); got != want.contentType {
+					t.Errorf("part Content-Type = %q, want %q", got, want.contentType)
+				}
+				body, err := io.ReadAll(quotedprintable.NewReader(part))
+				if err != nil {
+					t.Fatalf("quotedprintable read error = %v", err)
+				}
+				// quoted-printable text is sent with CRLF line breaks
+				if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != want.body {
+					t.Errorf("part body = %q, want %q", got, want.body)
+				}
+			}
+			if _, err := mr.NextRawPart(); err != io.EOF {
+				t.Errorf("NextRawPart() error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestGetEmailRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{"no attempts", 0, 30 * time.Second},
+		{"first failure", 1, 30 * time.Second},
+		{"second failure", 2, time.Minute},
+		{"fifth failure", 5, 8 * time.Minute},
+		{"capped", 12, 6 * time.Hour},
+		{"huge", 1000, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetEmailRetryDelay(tt.attempts); got != tt.want {
+				t.Errorf("GetEmailRetryDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPlatformAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		email  string
+		admins string
+		want   bool
+	}{
+		{"no admins", "a@example.com", "", false},
+		{"empty email", "", "", false},
+		{"listed", "b@example.com", "a@example.com,b@example.com", true},
+		{"spaces and case", "B@example.com", "a@example.com, b@example.com ", true},
+		{"not listed", "c@example.com", "a@example.com,b@example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPlatformAdmin(tt.email, tt.admins); got != tt.want {
+				t.Errorf("IsPlatformAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package fiddlers
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	cssCommentRegex    = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssSimpleSelector  = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9]*)?(\.[a-zA-Z_-][a-zA-Z0-9_-]*)?$`)
+	textWhitespaceRuns = regexp.MustCompile(`[ \t\r\n\f]+`)
+	textBlankLineRuns  = regexp.MustCompile(`\n{3,}`)
+)
+
+type cssRule struct {
+	tag         string
+	class       string
+	specificity int
+	decls       string
+}
+
+// InlineCss moves the rules of the `<style>` elements to the `style` attribute of
+// the elements they match, as most email clients drop `<style>`. Only `tag`,
+// `.class` and `tag.class` selectors are inlined, other rules stay in a `<style>`
+// element. Declarations already in a `style` attribute take precedence.
+func InlineCss(document string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", err
+	}
+
+	var styleNodes []*html.Node
+	walkHtml(doc, func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Style {
+			styleNodes = append(styleNodes, n)
+		}
+	})
+
+	var rules []cssRule
+	var kept []string
+	for _, n := range styleNodes {
+		css := ""
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			css += c.Data
+		}
+		r, k := parseCss(css)
+		rules = append(rules, r...)
+		kept = append(kept, k...)
+	}
+
+	// stable, rules of the same specificity keep the stylesheet order
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].specificity < rules[j].specificity
+	})
+
+	walkHtml(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+
+		var decls []string
+		for _, r := range rules {
+			if r.matches(n) {
+				decls = append(decls, r.decls)
+			}
+		}
+		if len(decls) == 0 {
+			return
+		}
+
+		if inline, ok := getHtmlAttr(n, "style"); ok && strings.TrimSpace(inline) != "" {
+			decls = append(decls, strings.TrimSuffix(strings.TrimSpace(inline), ";"))
+		}
+		setHtmlAttr(n, "style", strings.Join(decls, "; ")+";")
+	})
+
+	for i, n := range styleNodes {
+		if i == 0 && len(kept) > 0 {
+			n.FirstChild.Data = "\n" + strings.Join(kept, "\n") + "\n"
+			for c := n.FirstChild.NextSibling; c != nil; c = n.FirstChild.NextSibling {
+				n.RemoveChild(c)
+			}
+			continue
+		}
+		n.Parent.RemoveChild(n)
+	}
+
+	buf := new(bytes.Buffer)
+	err = html.Render(buf, doc)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Splits the css in inlinable rules and the rules to be kept as is
+func parseCss(css string) ([]cssRule, []string) {
+	css = cssCommentRegex.ReplaceAllString(css, "")
+
+	var rules []cssRule
+	var kept []string
+	for _, block := range strings.Split(css, "}") {
+		selectors, decls, ok := strings.Cut(block, "{")
+		if !ok {
+			continue
+		}
+		selectors = strings.TrimSpace(selectors)
+		decls = strings.TrimSuffix(strings.TrimSpace(textWhitespaceRuns.ReplaceAllString(decls, " ")), ";")
+
+		var inlinable []cssRule
+		for _, selector := range strings.Split(selectors, ",") {
+			m := cssSimpleSelector.FindStringSubmatch(strings.TrimSpace(selector))
+			if m == nil || (m[1] == "" && m[2] == "") {
+				inlinable = nil
+				break
+			}
+			r := cssRule{tag: strings.ToLower(m[1]), class: strings.TrimPrefix(m[2], "."), decls: decls}
+			if r.tag != "" {
+				r.specificity += 1
+			}
+			if r.class != "" {
+				r.specificity += 10
+			}
+			inlinable = append(inlinable, r)
+		}
+
+		if inlinable == nil {
+			kept = append(kept, selectors+" { "+decls+"; }")
+			continue
+		}
+		rules = append(rules, inlinable...)
+	}
+
+	return rules, kept
+}
+
+func (r cssRule) matches(n *html.Node) bool {
+	if r.tag != "" && r.tag != n.Data {
+		return false
+	}
+	if r.class == "" {
+		return true
+	}
+
+	classes, _ := getHtmlAttr(n, "class")
+	for _, c := range strings.Fields(classes) {
+		if c == r.class {
+			return true
+		}
+	}
+
+	return false
+}
+
+var textBlockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Tr: true, atom.Li: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Table: true, atom.Ul: true, atom.Ol: true, atom.Hr: true,
+}
+
+// HtmlToText renders the readable text of an html document, to be used as the
+// plain-text alternative of emails. Links become "label (url)".
+func HtmlToText(document string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(strings.Builder)
+	var render func(n *html.Node)
+	render = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			buf.WriteString(textWhitespaceRuns.ReplaceAllString(n.Data, " "))
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Head, atom.Style, atom.Script, atom.Title:
+				return
+			case atom.Br:
+				buf.WriteString("\n")
+				return
+			}
+		}
+
+		block := n.Type == html.ElementNode && textBlockElements[n.DataAtom]
+		if block {
+			buf.WriteString("\n")
+		}
+
+		start := buf.Len()
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			render(c)
+		}
+
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			label := strings.TrimSpace(buf.String()[start:])
+			if href, ok := getHtmlAttr(n, "href"); ok && href != "" && href != label {
+				buf.WriteString(" (" + href + ")")
+			}
+		}
+
+		if block {
+			buf.WriteString("\n")
+		}
+	}
+	render(doc)
+
+	lines := strings.Split(buf.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	text := textBlankLineRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+
+	return strings.TrimSpace(text) + "\n", nil
+}
+
+func walkHtml(n *html.Node, f func(n *html.Node)) {
+	f(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkHtml(c, f)
+	}
+}
+
+func getHtmlAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+
+	return "", false
+}
+
+func setHtmlAttr(n *html.Node, key string, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
@@ -0,0 +1,92 @@
+package fiddlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInlineCss(t *testing.T) {
+	tests := []struct {
+		name         string
+		document     string
+		wantContains []string
+		wantAbsent   []string
+	}{
+		{
+			"tag and class rules",
+			`<html><head><style>
+				/* base */
+				p { margin: 0 }
+				.button { color: #000; padding: 4px; }
+			</style></head><body><p>Hi</p><a class="big button" href="x">go</a></body></html>`,
+			[]string{`<p style="margin: 0;">`, `<a class="big button" href="x" style="color: #000; padding: 4px;">`},
+			[]string{"<style>"},
+		},
+		{
+			"specificity and inline precedence",
+			`<html><head><style>.note { color: red } div { color: blue } div.note { color: green }</style></head>` +
+				`<body><div class="note" style="font-size: 2px">x</div></body></html>`,
+			[]string{`style="color: blue; color: red; color: green; font-size: 2px;"`},
+			nil,
+		},
+		{
+			"unsupported selectors are kept",
+			`<html><head><style>a:hover { color: red } .x { color: blue }</style></head><body><span class="x">x</span></body></html>`,
+			[]string{"<style>\na:hover { color: red; }\n</style>", `<span class="x" style="color: blue;">`},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InlineCss(tt.document)
+			if err != nil {
+				t.Fatalf("InlineCss() error = %v", err)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(got, want) {
+					t.Errorf("InlineCss() = %s, want it to contain %s", got, want)
+				}
+			}
+			for _, absent := range tt.wantAbsent {
+				if strings.Contains(got, absent) {
+					t.Errorf("InlineCss() = %s, want it without %s", got, absent)
+				}
+			}
+		})
+	}
+}
+
+func TestHtmlToText(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{
+			"paragraphs and breaks",
+			`<html><head><title>T</title><style>p{}</style></head><body><p>Hi   Ana,</p><p>Cheers,<br/>The team</p></body></html>`,
+			"Hi Ana,\n\nCheers,\nThe team\n",
+		},
+		{
+			"links",
+			`<p><a href="https://x.dev/confirm?otp=1">CONFIRM</a></p><p><a href="https://x.dev">https://x.dev</a></p>`,
+			"CONFIRM (https://x.dev/confirm?otp=1)\n\nhttps://x.dev\n",
+		},
+		{
+			"entities are decoded",
+			`<div>&copy; 2024 &lt;PATOS&gt;</div>`,
+			"© 2024 <PATOS>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HtmlToText(tt.document)
+			if err != nil {
+				t.Fatalf("HtmlToText() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HtmlToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package fiddlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+
+	"github.com/chai2010/webp"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/models"
+	"golang.org/x/image/draw"
+)
+
+func GetImageVariantMaxSide(variant string) int {
+	switch variant {
+	case common.IMAGE_VARIANT_THUMBNAIL:
+		return common.IMAGE_THUMBNAIL_SIZE
+	case common.IMAGE_VARIANT_MEDIUM:
+		return common.IMAGE_MEDIUM_SIZE
+	}
+
+	return common.IMAGE_FULL_SIZE
+}
+
+// GetImageId identifies the image by its content, so a new image never reuses
+// the urls of the previous one and caches never serve it stale
+func GetImageId(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Prefix of the paths of every image stored for the upload purpose, `targetId`
+// is the user or event id
+func GetImagePathPrefix(purpose string, targetId string) string {
+	filename := targetId + "-"
+
+	if purpose == common.UPLOAD_PURPOSE_EVENT_BANNER {
+		return storage.GetPublicPath(storage.EVENT_BANNERS, filename)
+	}
+
+	return storage.GetPublicPath(storage.USER_AVATARS, filename)
+}
+
+// Where a variant of the image `imageId` (see GetImageId) of an upload purpose
+// is stored
+func GetImageVariantPath(purpose string, targetId string, imageId string, variant string, ext string) string {
+	return GetImagePathPrefix(purpose, targetId) + imageId + "-" + variant + "." + ext
+}
+
+// GetExifOrientation reads the orientation tag of the Exif of a JPEG, 1 (as
+// stored) if there is none
+func GetExifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// walks the segments up to the image data
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		segLen := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if segLen < 2 || i+2+segLen > len(data) {
+			return 1
+		}
+
+		seg := data[i+4 : i+2+segLen]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return getTiffOrientation(seg[6:])
+		}
+
+		i += 2 + segLen
+	}
+
+	return 1
+}
+
+func getTiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for e := 0; e < entries; e++ {
+		entry := ifd + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		// 0x0112 is Orientation, a SHORT stored in the value field
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// OrientImage applies an Exif orientation, so the pixels are stored as they
+// are meant to be displayed
+func OrientImage(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // upside down, mirrored
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated 90 counterclockwise
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+
+	return dst
+}
+
+// FitImage scales the image down so its largest side is at most `maxSide`,
+// smaller images are kept as they are
+func FitImage(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+
+	if w >= h {
+		h = max(1, h*maxSide/w)
+		w = maxSide
+	} else {
+		w = max(1, w*maxSide/h)
+		h = maxSide
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+
+	return dst
+}
+
+// JPEG has no transparency, transparent pixels become white
+func flattenImage(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+
+	return dst
+}
+
+// ProcessImage decodes a PNG or JPEG and encodes each of common.IMAGE_VARIANTS
+// as WebP and JPEG. Re-encoding drops all the metadata, Exif included, after
+// its orientation is applied.
+func ProcessImage(data []byte) ([]models.ImageVariant, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "png" && format != "jpeg") {
+		return nil, common.ErrImageFormatInvalid
+	}
+
+	// checked before decoding, a small file can declare huge dimensions
+	if cfg.Width*cfg.Height > common.IMAGE_MAX_PIXELS {
+		return nil, common.ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, common.ErrImageFormatInvalid
+	}
+
+	if format == "jpeg" {
+		img = OrientImage(img, GetExifOrientation(data))
+	}
+
+	variants := []models.ImageVariant{}
+	for _, name := range common.IMAGE_VARIANTS {
+		resized := FitImage(img, GetImageVariantMaxSide(name))
+		b := resized.Bounds()
+
+		webpBuf := bytes.Buffer{}
+		err = webp.Encode(&webpBuf, resized, &webp.Options{Quality: float32(common.IMAGE_WEBP_QUALITY)})
+		if err != nil {
+			return nil, err
+		}
+
+		jpegBuf := bytes.Buffer{}
+		err = jpeg.Encode(&jpegBuf, flattenImage(resized), &jpeg.Options{Quality: common.IMAGE_JPEG_QUALITY})
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants,
+			models.ImageVariant{Name: name, Width: b.Dx(), Height: b.Dy(), Ext: "webp", ContentType: "image/webp", Data: webpBuf.Bytes()},
+			models.ImageVariant{Name: name, Width: b.Dx(), Height: b.Dy(), Ext: "jpg", ContentType: "image/jpeg", Data: jpegBuf.Bytes()},
+		)
+	}
+
+	return variants, nil
+}
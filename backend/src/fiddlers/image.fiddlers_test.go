@@ -0,0 +1,217 @@
+package fiddlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+// Inserts an Exif APP1 segment with the `orientation` after the SOI of a JPEG
+func withExifOrientation(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+
+	tiff := []byte("MM\x00\x2A\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(seg)+2))
+	app1 = append(app1, seg...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, app1...)
+	return append(out, jpg[2:]...)
+}
+
+func encodeJpeg(t *testing.T, w int, h int) []byte {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGetExifOrientation(t *testing.T) {
+	jpg := encodeJpeg(t, 4, 2)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", jpg, 1},
+		{"rotated", withExifOrientation(t, jpg, 6), 6},
+		{"mirrored", withExifOrientation(t, jpg, 2), 2},
+		{"out of range", withExifOrientation(t, jpg, 9), 1},
+		{"not a jpeg", []byte("quack"), 1},
+		{"truncated", withExifOrientation(t, jpg, 6)[:20], 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetExifOrientation(tt.data); got != tt.want {
+				t.Errorf("GetExifOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	// 2x1, red on the left and blue on the right
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		w, h        int
+		first       color.NRGBA
+	}{
+		{1, 2, 1, red},
+		{2, 2, 1, blue},
+		{3, 2, 1, blue},
+		{4, 2, 1, red},
+		{5, 1, 2, red},
+		{6, 1, 2, red},
+		{7, 1, 2, blue},
+		{8, 1, 2, blue},
+	}
+	for _, tt := range tests {
+		got := OrientImage(img, tt.orientation)
+		b := got.Bounds()
+		if b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("OrientImage(%d) size = %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if c := color.NRGBAModel.Convert(got.At(b.Min.X, b.Min.Y)); c != tt.first {
+			t.Errorf("OrientImage(%d) first pixel = %v, want %v", tt.orientation, c, tt.first)
+		}
+	}
+}
+
+func TestFitImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		w, h    int
+		maxSide int
+		wantW   int
+		wantH   int
+	}{
+		{"landscape", 2000, 1000, 640, 640, 320},
+		{"portrait", 1000, 2000, 160, 80, 160},
+		{"smaller is kept", 100, 50, 640, 100, 50},
+		{"thin", 4000, 2, 160, 160, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := FitImage(image.NewNRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.maxSide).Bounds()
+			if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("FitImage() = %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestProcessImage(t *testing.T) {
+	jpg := withExifOrientation(t, encodeJpeg(t, 2000, 1000), 6)
+
+	variants, err := ProcessImage(jpg)
+	if err != nil {
+		t.Fatalf("ProcessImage() error = %v", err)
+	}
+	if len(variants) != 2*len(common.IMAGE_VARIANTS) {
+		t.Fatalf("ProcessImage() len = %d", len(variants))
+	}
+
+	for _, v := range variants {
+		// rotated to portrait
+		maxSide := GetImageVariantMaxSide(v.Name)
+		if v.Height != maxSide || v.Width != maxSide/2 {
+			t.Errorf("%s.%s = %dx%d", v.Name, v.Ext, v.Width, v.Height)
+		}
+
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(v.Data))
+		if v.Ext == "jpg" {
+			if err != nil || format != "jpeg" || cfg.Width != v.Width {
+				t.Errorf("%s.jpg decodes as %s %v, %v", v.Name, format, cfg, err)
+			}
+			// the exif is not carried over
+			if GetExifOrientation(v.Data) != 1 || bytes.Contains(v.Data, []byte("Exif")) {
+				t.Errorf("%s.jpg kept the exif", v.Name)
+			}
+		} else if !bytes.HasPrefix(v.Data[8:], []byte("WEBP")) {
+			t.Errorf("%s.webp is not a webp", v.Name)
+		}
+	}
+
+	_, err = ProcessImage([]byte("<svg></svg>"))
+	if err != common.ErrImageFormatInvalid {
+		t.Errorf("ProcessImage() svg error = %v, want %v", err, common.ErrImageFormatInvalid)
+	}
+
+	// a tiny png declaring a huge size is refused before decoding
+	buf := bytes.Buffer{}
+	err = png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bomb := buf.Bytes()
+	binary.BigEndian.PutUint32(bomb[16:20], 100_000)
+	binary.BigEndian.PutUint32(bomb[20:24], 100_000)
+	binary.BigEndian.PutUint32(bomb[29:33], crc32.ChecksumIEEE(bomb[12:29]))
+
+	_, err = ProcessImage(bomb)
+	if err != common.ErrImageTooLarge {
+		t.Errorf("ProcessImage() bomb error = %v, want %v", err, common.ErrImageTooLarge)
+	}
+}
+
+func TestGetImageVariantPath(t *testing.T) {
+	imageId := GetImageId([]byte("image"))
+	if imageId == GetImageId([]byte("other image")) {
+		t.Errorf("GetImageId() = %s for different images", imageId)
+	}
+
+	tests := []struct {
+		name     string
+		purpose  string
+		targetId string
+		expects  string
+	}{
+		{"avatar", common.UPLOAD_PURPOSE_AVATAR, "1", "public/user-avatars/1-" + imageId + "-full.webp"},
+		{"banner", common.UPLOAD_PURPOSE_EVENT_BANNER, "abc", "public/event-banners/abc-" + imageId + "-full.webp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetImageVariantPath(tt.purpose, tt.targetId, imageId, common.IMAGE_VARIANT_FULL, "webp")
+			if got != tt.expects {
+				t.Errorf("GetImageVariantPath() = %s, want %s", got, tt.expects)
+			}
+			if !strings.HasPrefix(got, GetImagePathPrefix(tt.purpose, tt.targetId)) {
+				t.Errorf("GetImageVariantPath() = %s, not under GetImagePathPrefix()", got)
+			}
+		})
+	}
+
+	// the prefix of a user is not the one of another user whose id starts alike
+	if strings.HasPrefix(GetImageVariantPath(common.UPLOAD_PURPOSE_AVATAR, "12", imageId, common.IMAGE_VARIANT_FULL, "webp"), GetImagePathPrefix(common.UPLOAD_PURPOSE_AVATAR, "1")) {
+		t.Error("GetImagePathPrefix() of user 1 matches the images of user 12")
+	}
+}
@@ -0,0 +1,82 @@
+package fiddlers
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+// ResolveLocale maps `locale` to a supported one: exact match first, then the
+// same base language (e.g. "pt-PT" -> "pt-BR"), else `common.DEFAULT_LOCALE`
+func ResolveLocale(locale string) string {
+	resolved, ok := matchLocale(locale)
+	if !ok {
+		return common.DEFAULT_LOCALE
+	}
+
+	return resolved
+}
+
+// ParseAcceptLanguage picks the supported locale preferred by an `Accept-Language`
+// header, e.g. "en-US,en;q=0.9,pt;q=0.8"
+func ParseAcceptLanguage(header string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, t := range tags {
+		if locale, ok := matchLocale(t.tag); ok {
+			return locale
+		}
+	}
+
+	return common.DEFAULT_LOCALE
+}
+
+func matchLocale(locale string) (string, bool) {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	for _, supported := range common.SUPPORTED_LOCALES {
+		if strings.EqualFold(locale, supported) {
+			return supported, true
+		}
+	}
+
+	base, _, _ := strings.Cut(locale, "-")
+	for _, supported := range common.SUPPORTED_LOCALES {
+		supportedBase, _, _ := strings.Cut(supported, "-")
+		if strings.EqualFold(base, supportedBase) {
+			return supported, true
+		}
+	}
+
+	return "", false
+}
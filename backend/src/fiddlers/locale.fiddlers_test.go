@@ -0,0 +1,50 @@
+package fiddlers
+
+import "testing"
+
+func TestResolveLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		want   string
+	}{
+		{"exact", "en", "en"},
+		{"case", "PT-br", "pt-BR"},
+		{"underscore", "pt_BR", "pt-BR"},
+		{"same base", "pt-PT", "pt-BR"},
+		{"region of en", "en-GB", "en"},
+		{"unsupported", "fr", "pt-BR"},
+		{"empty", "", "pt-BR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveLocale(tt.locale); got != tt.want {
+				t.Errorf("ResolveLocale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"empty", "", "pt-BR"},
+		{"brazilian browser", "pt-BR,pt;q=0.9,en-US;q=0.8,en;q=0.7", "pt-BR"},
+		{"english browser", "en-US,en;q=0.9", "en"},
+		{"weights out of order", "en;q=0.5, pt;q=0.8", "pt-BR"},
+		{"skips unsupported", "fr-FR,fr;q=0.9,en;q=0.8", "en"},
+		{"q zero is refused", "en;q=0, fr", "pt-BR"},
+		{"wildcard only", "*", "pt-BR"},
+		{"malformed q", "en;q=abc, pt-BR;q=0.1", "pt-BR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAcceptLanguage(tt.header); got != tt.want {
+				t.Errorf("ParseAcceptLanguage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package fiddlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/schemas"
+)
+
+// UseRequestFieldNames makes the binding validator report fields by their
+// json or form name instead of the Go one
+func UseRequestFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+func NewProblem(ctx *gin.Context, status int, code string, detail string) schemas.Problem {
+	return schemas.Problem{
+		Type:      common.PROBLEM_TYPE_DEFAULT,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  ctx.Request.URL.Path,
+		Code:      code,
+		RequestId: ctx.GetString(common.GIN_CTX_REQUEST_ID_KEY),
+	}
+}
+
+// AbortWithProblem replies with a problem+json and stops the handler chain
+func AbortWithProblem(ctx *gin.Context, status int, code string, detail string) {
+	WriteProblem(ctx, NewProblem(ctx, status, code, detail))
+}
+
+// AbortWithBadRequest replies 400, listing the invalid fields when err comes
+// from binding the request
+func AbortWithBadRequest(ctx *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		problem := NewProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_VALIDATION, "the request has invalid fields")
+		for _, fe := range validationErrs {
+			problem.Errors = append(problem.Errors, NewFieldError(fe))
+		}
+		WriteProblem(ctx, problem)
+	case errors.As(err, &typeErr):
+		problem := NewProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_VALIDATION, "the request has invalid fields")
+		problem.Errors = append(problem.Errors, schemas.FieldError{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type.String()),
+		})
+		WriteProblem(ctx, problem)
+	default:
+		AbortWithProblem(ctx, http.StatusBadRequest, common.PROBLEM_CODE_BAD_REQUEST, err.Error())
+	}
+}
+
+func NewFieldError(fe validator.FieldError) schemas.FieldError {
+	// the namespace starts with the struct name, CreateUser.address.street
+	field := fe.Namespace()
+	if _, after, ok := strings.Cut(field, "."); ok {
+		field = after
+	}
+
+	var msg string
+	switch fe.Tag() {
+	case "required":
+		msg = fmt.Sprintf("%s is required", field)
+	case "email":
+		msg = fmt.Sprintf("%s must be a valid email", field)
+	case "oneof":
+		msg = fmt.Sprintf("%s must be one of: %s", field, fe.Param())
+	case "min", "gte":
+		msg = fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "max", "lte":
+		msg = fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "gt":
+		msg = fmt.Sprintf("%s must be greater than %s", field, fe.Param())
+	case "lt":
+		msg = fmt.Sprintf("%s must be less than %s", field, fe.Param())
+	default:
+		msg = fmt.Sprintf("%s failed the %s rule", field, fe.Tag())
+	}
+
+	return schemas.FieldError{
+		Field:   field,
+		Rule:    fe.Tag(),
+		Param:   fe.Param(),
+		Message: msg,
+	}
+}
+
+func WriteProblem(ctx *gin.Context, problem schemas.Problem) {
+	body, err := json.Marshal(problem)
+	if err != nil {
+		ctx.AbortWithStatus(problem.Status)
+		return
+	}
+
+	ctx.Abort()
+	ctx.Data(problem.Status, common.PROBLEM_CONTENT_TYPE, body)
+}
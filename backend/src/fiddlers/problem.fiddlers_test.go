@@ -0,0 +1,72 @@
+package fiddlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/schemas"
+)
+
+func TestAbortWithBadRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	UseRequestFieldNames()
+
+	type payload struct {
+		Email      string `json:"email" binding:"required,email"`
+		Visibility string `json:"visibility" binding:"required,oneof=public private"`
+		Size       int64  `json:"size" binding:"gt=0"`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   string
+		wantFields []string
+	}{
+		{"validation", `{"email": "nope", "size": 0}`, common.PROBLEM_CODE_VALIDATION, []string{"email", "visibility", "size"}},
+		{"type", `{"email": "a@b.c", "visibility": "public", "size": "big"}`, common.PROBLEM_CODE_VALIDATION, []string{"size"}},
+		{"syntax", `{"email"`, common.PROBLEM_CODE_BAD_REQUEST, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/things", func(ctx *gin.Context) {
+				ctx.Set(common.GIN_CTX_REQUEST_ID_KEY, "req-1")
+				var p payload
+				if err := ctx.ShouldBindJSON(&p); err != nil {
+					AbortWithBadRequest(ctx, err)
+					return
+				}
+				ctx.String(http.StatusOK, "OK")
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(tt.body)))
+
+			if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != common.PROBLEM_CONTENT_TYPE {
+				t.Fatalf("AbortWithBadRequest() = %d %s", w.Code, w.Header().Get("Content-Type"))
+			}
+
+			var problem schemas.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Code != tt.wantCode || problem.RequestId != "req-1" || problem.Instance != "/things" || problem.Title != "Bad Request" {
+				t.Errorf("AbortWithBadRequest() problem = %+v", problem)
+			}
+
+			fields := []string{}
+			for _, fe := range problem.Errors {
+				fields = append(fields, fe.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("AbortWithBadRequest() fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
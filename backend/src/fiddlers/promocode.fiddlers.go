@@ -0,0 +1,40 @@
+package fiddlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func NewPromoCode(orgId string, code string, discountType string, discountValue int64, maxUses *uint32, exp *time.Time, eventIds []string) models.PromoCode {
+	return models.PromoCode{
+		OrganizationId: orgId,
+		Code:           NormalizePromoCode(code),
+		DiscountType:   discountType,
+		DiscountValue:  discountValue,
+		MaxUses:        maxUses,
+		Exp:            exp,
+		EventIds:       eventIds,
+	}
+}
+
+// Promo codes are case-insensitive, "quack10" and "QUACK10" are the same code
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Gets how much is taken off `unitAmmount`, never more than `unitAmmount` itself
+func GetDiscountAmmount(unitAmmount int64, discountType string, discountValue int64) int64 {
+	var discount int64
+
+	switch discountType {
+	case common.DISCOUNT_TYPE_PERCENTAGE:
+		discount = unitAmmount * discountValue / 100
+	case common.DISCOUNT_TYPE_FIXED:
+		discount = discountValue
+	}
+
+	return min(max(discount, 0), unitAmmount)
+}
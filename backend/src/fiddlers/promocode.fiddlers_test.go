@@ -0,0 +1,58 @@
+package fiddlers
+
+import (
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+func TestGetDiscountAmmount(t *testing.T) {
+	type args struct {
+		unitAmmount   int64
+		discountType  string
+		discountValue int64
+	}
+	tests := []struct {
+		name string
+		args args
+		want int64
+	}{
+		{
+			"ten percent",
+			args{30000, common.DISCOUNT_TYPE_PERCENTAGE, 10},
+			3000,
+		},
+		{
+			"free",
+			args{30000, common.DISCOUNT_TYPE_PERCENTAGE, 100},
+			30000,
+		},
+		{
+			"percentage rounds down",
+			args{999, common.DISCOUNT_TYPE_PERCENTAGE, 15},
+			149,
+		},
+		{
+			"fixed",
+			args{30000, common.DISCOUNT_TYPE_FIXED, 5000},
+			5000,
+		},
+		{
+			"fixed above price",
+			args{30000, common.DISCOUNT_TYPE_FIXED, 50000},
+			30000,
+		},
+		{
+			"unknown type",
+			args{30000, "bogus", 10},
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetDiscountAmmount(tt.args.unitAmmount, tt.args.discountType, tt.args.discountValue); got != tt.want {
+				t.Errorf("GetDiscountAmmount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,14 @@
 package storage
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/url"
 	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/patos-ufscar/quack-week/common"
 )
@@ -10,14 +16,40 @@ import (
 type storageDir string
 
 const (
-	EVENT_BANNERS storageDir = "event-banners"
-	USER_AVATARS  storageDir = "user-avatars"
+	EVENT_BANNERS     storageDir = "event-banners"
+	USER_AVATARS      storageDir = "user-avatars"
+	EVENT_ATTACHMENTS storageDir = "event-attachments"
+	// objects uploaded directly by the browser, waiting for confirmation
+	UPLOADS storageDir = "uploads"
 )
 
+// the filesystem ObjectService is served by the API itself, see ObjectController
+const OBJECTS_ROUTE = "v1/objects"
+
 func GetFullObjUrl(objPath string) (string, error) {
+	if common.OBJECT_STORAGE_PROVIDER == common.OBJECT_STORAGE_FILESYSTEM {
+		return url.JoinPath(common.API_HOST_URL, OBJECTS_ROUTE, common.S3_BUCKET, objPath)
+	}
+
 	return url.JoinPath(common.S3_ENDPOINT, common.S3_BUCKET, objPath)
 }
 
+// GetObjPathFromUrl is the reverse of GetFullObjUrl, false for urls of
+// other hosts or buckets
+func GetObjPathFromUrl(objUrl string) (string, bool) {
+	base, err := GetFullObjUrl("/")
+	if err != nil || !strings.HasPrefix(objUrl, base) {
+		return "", false
+	}
+
+	objPath, err := url.PathUnescape(strings.TrimPrefix(objUrl, base))
+	if err != nil || objPath == "" {
+		return "", false
+	}
+
+	return objPath, true
+}
+
 func GetPublicPath(p storageDir, filename string) string {
 	return path.Join("public", string(p), filename)
 }
@@ -25,3 +57,66 @@ func GetPublicPath(p storageDir, filename string) string {
 func GetPrivatePath(p storageDir, filename string) string {
 	return path.Join("private", string(p), filename)
 }
+
+// Public objects can be read without a signed url
+func IsPublicPath(objPath string) bool {
+	return strings.HasPrefix(path.Clean("/"+objPath), "/public/")
+}
+
+func signObject(secret []byte, method string, bucket string, objPath string, exp string, size string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(method + "\n" + bucket + "\n" + objPath + "\n" + exp))
+	if size != "" {
+		mac.Write([]byte("\n" + size))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignObjectUrl builds the url allowing `method` on the object until `exp`,
+// uploads are limited to `size` bytes when it is positive
+func SignObjectUrl(baseUrl string, secret []byte, method string, bucket string, objPath string, size int64, exp time.Time) (string, error) {
+	objUrl, err := url.JoinPath(baseUrl, OBJECTS_ROUTE, bucket, objPath)
+	if err != nil {
+		return "", err
+	}
+
+	expStr := strconv.FormatInt(exp.Unix(), 10)
+	sizeStr := ""
+	q := url.Values{}
+	q.Set(common.OBJECT_URL_EXP_PARAM, expStr)
+	if size > 0 {
+		sizeStr = strconv.FormatInt(size, 10)
+		q.Set(common.OBJECT_URL_SIZE_PARAM, sizeStr)
+	}
+	q.Set(common.OBJECT_URL_SIGNATURE_PARAM, signObject(secret, method, bucket, objPath, expStr, sizeStr))
+
+	return objUrl + "?" + q.Encode(), nil
+}
+
+// VerifyObjectSignature checks the `exp`, `size` and `sig` query params of a
+// url built by SignObjectUrl, returning the size limit, 0 if there is none
+func VerifyObjectSignature(secret []byte, method string, bucket string, objPath string, exp string, size string, sig string, now time.Time) (int64, error) {
+	expUnix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return 0, common.ErrObjectSignatureInvalid
+	}
+
+	if now.Unix() > expUnix {
+		return 0, common.ErrObjectSignatureInvalid
+	}
+
+	var maxSize int64
+	if size != "" {
+		maxSize, err = strconv.ParseInt(size, 10, 64)
+		if err != nil || maxSize <= 0 {
+			return 0, common.ErrObjectSignatureInvalid
+		}
+	}
+
+	expected := signObject(secret, method, bucket, objPath, exp, size)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return 0, common.ErrObjectSignatureInvalid
+	}
+
+	return maxSize, nil
+}
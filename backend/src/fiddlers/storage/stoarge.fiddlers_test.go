@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+func TestIsPublicPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		objPath string
+		expects bool
+	}{
+		{"public", GetPublicPath(USER_AVATARS, "1"), true},
+		{"private", GetPrivatePath(USER_AVATARS, "1"), false},
+		{"public dir itself", "public", false},
+		{"traversal", "public/../private/user-avatars/1", false},
+		{"prefix only", "publicity/1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPublicPath(tt.objPath); got != tt.expects {
+				t.Errorf("IsPublicPath(%s) = %v, want %v", tt.objPath, got, tt.expects)
+			}
+		})
+	}
+}
+
+func TestGetObjPathFromUrl(t *testing.T) {
+	objPath := GetPublicPath(USER_AVATARS, "1-full.webp")
+	objUrl, err := GetFullObjUrl(objPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		objUrl string
+		want   string
+		wantOk bool
+	}{
+		{"own url", objUrl, objPath, true},
+		{"other host", "https://example.com/" + objPath, "", false},
+		{"bucket root", objUrl[:len(objUrl)-len(objPath)], "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := GetObjPathFromUrl(tt.objUrl)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("GetObjPathFromUrl(%s) = %s, %v, want %s, %v", tt.objUrl, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestVerifyObjectSignature(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	objPath := GetPrivatePath(EVENT_BANNERS, "abc")
+
+	signed, err := SignObjectUrl("http://127.0.0.1:8080/", secret, "GET", "bucket", objPath, 0, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/"+OBJECTS_ROUTE+"/bucket/"+objPath {
+		t.Errorf("SignObjectUrl() path = %s", u.Path)
+	}
+	exp := u.Query().Get(common.OBJECT_URL_EXP_PARAM)
+	sig := u.Query().Get(common.OBJECT_URL_SIGNATURE_PARAM)
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		method  string
+		bucket  string
+		objPath string
+		exp     string
+		sig     string
+		now     time.Time
+		wantErr bool
+	}{
+		{"valid", secret, "GET", "bucket", objPath, exp, sig, now, false},
+		{"expired", secret, "GET", "bucket", objPath, exp, sig, now.Add(2 * time.Minute), true},
+		{"other method", secret, "PUT", "bucket", objPath, exp, sig, now, true},
+		{"other bucket", secret, "GET", "other", objPath, exp, sig, now, true},
+		{"other path", secret, "GET", "bucket", objPath + "x", exp, sig, now, true},
+		{"other secret", []byte("other"), "GET", "bucket", objPath, exp, sig, now, true},
+		{"extended exp", secret, "GET", "bucket", objPath, "9999999999", sig, now, true},
+		{"bad exp", secret, "GET", "bucket", objPath, "soon", sig, now, true},
+		{"no signature", secret, "GET", "bucket", objPath, exp, "", now, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyObjectSignature(tt.secret, tt.method, tt.bucket, tt.objPath, tt.exp, "", tt.sig, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyObjectSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	signed, err = SignObjectUrl("http://127.0.0.1:8080/", secret, "PUT", "bucket", objPath, 1024, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err = url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = u.Query().Get(common.OBJECT_URL_EXP_PARAM)
+	sig = u.Query().Get(common.OBJECT_URL_SIGNATURE_PARAM)
+
+	sized := []struct {
+		name    string
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{"signed size", u.Query().Get(common.OBJECT_URL_SIZE_PARAM), 1024, false},
+		{"raised size", "4096", 0, true},
+		{"dropped size", "", 0, true},
+		{"bad size", "-1", 0, true},
+	}
+	for _, tt := range sized {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyObjectSignature(secret, "PUT", "bucket", objPath, exp, tt.size, sig, now)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("VerifyObjectSignature() = %d, %v, want %d, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
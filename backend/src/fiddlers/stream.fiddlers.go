@@ -0,0 +1,52 @@
+package fiddlers
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func GetUserStreamTopic(userId uint32) string {
+	return fmt.Sprintf("%s%d", common.STREAM_TOPIC_USER, userId)
+}
+
+func GetOrganizationStreamTopic(orgId string) string {
+	return common.STREAM_TOPIC_ORGANIZATION + orgId
+}
+
+// ParseLastEventId parses the Last-Event-ID sent by EventSource on reconnection,
+// empty means the client is not resuming
+func ParseLastEventId(lastEventId string) (uint64, error) {
+	if lastEventId == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(lastEventId, 10, 64)
+}
+
+// WriteSseEvent writes the event in the text/event-stream format, each line of
+// the payload goes in its own data field
+func WriteSseEvent(w io.Writer, e models.StreamEvent) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "id: %d\n", e.StreamEventId)
+	fmt.Fprintf(&b, "event: %s\n", e.EventType)
+	for _, line := range strings.Split(string(e.Payload), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\r"))
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteSseComment writes a comment line, which clients ignore, to keep proxies
+// from closing idle streams
+func WriteSseComment(w io.Writer, comment string) error {
+	_, err := fmt.Fprintf(w, ": %s\n\n", comment)
+	return err
+}
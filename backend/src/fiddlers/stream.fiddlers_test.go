@@ -0,0 +1,64 @@
+package fiddlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestWriteSseEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   models.StreamEvent
+		expects string
+	}{
+		{
+			"json",
+			models.StreamEvent{StreamEventId: 42, EventType: "notification", Payload: json.RawMessage(`{"notificationId":1}`)},
+			"id: 42\nevent: notification\ndata: {\"notificationId\":1}\n\n",
+		},
+		{
+			"multiline",
+			models.StreamEvent{StreamEventId: 7, EventType: "registrations", Payload: json.RawMessage("{\r\n\"registrations\": 3\n}")},
+			"id: 7\nevent: registrations\ndata: {\ndata: \"registrations\": 3\ndata: }\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteSseEvent(&b, tt.event); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.expects {
+				t.Errorf("WriteSseEvent() = %q, want %q", b.String(), tt.expects)
+			}
+		})
+	}
+}
+
+func TestParseLastEventId(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastEventId string
+		expects     uint64
+		wantErr     bool
+	}{
+		{"empty", "", 0, false},
+		{"id", "123", 123, false},
+		{"negative", "-1", 0, true},
+		{"garbage", "abc", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := ParseLastEventId(tt.lastEventId)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLastEventId() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if id != tt.expects {
+				t.Errorf("ParseLastEventId() = %d, want %d", id, tt.expects)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package fiddlers
+
+import (
+	"mime"
+	"slices"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func GetUploadMaxSize(purpose string) int64 {
+	switch purpose {
+	case common.UPLOAD_PURPOSE_AVATAR:
+		return common.UPLOAD_AVATAR_MAX_BYTES
+	case common.UPLOAD_PURPOSE_EVENT_BANNER:
+		return common.UPLOAD_BANNER_MAX_BYTES
+	}
+
+	return 0
+}
+
+// Where the browser uploads to, before the confirmation
+func GetUploadPendingPath(uploadId string) string {
+	return storage.GetPrivatePath(storage.UPLOADS, uploadId)
+}
+
+// ValidateUploadRequest checks what the client declares before it gets an upload url
+func ValidateUploadRequest(purpose string, contentType string, size int64) error {
+	if size > GetUploadMaxSize(purpose) {
+		return common.ErrUploadTooLarge
+	}
+
+	if !slices.Contains(common.UPLOAD_IMAGE_CONTENT_TYPES, contentType) {
+		return common.ErrUploadContentTypeInvalid
+	}
+
+	return nil
+}
+
+// ValidateUploadedObject checks the storage metadata of the uploaded object
+// against what was declared, so an upload url cannot be used for anything else
+func ValidateUploadedObject(upload models.Upload, info models.ObjectInfo) error {
+	if info.Size > GetUploadMaxSize(upload.Purpose) {
+		return common.ErrUploadTooLarge
+	}
+
+	contentType, _, err := mime.ParseMediaType(info.ContentType)
+	if err != nil || !slices.Contains(common.UPLOAD_IMAGE_CONTENT_TYPES, contentType) {
+		return common.ErrUploadContentTypeInvalid
+	}
+
+	if info.Size != upload.Size || contentType != upload.ContentType {
+		return common.ErrUploadMismatch
+	}
+
+	return nil
+}
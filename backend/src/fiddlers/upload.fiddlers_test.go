@@ -0,0 +1,57 @@
+package fiddlers
+
+import (
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestValidateUploadRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		purpose     string
+		contentType string
+		size        int64
+		wantErr     error
+	}{
+		{"avatar png", common.UPLOAD_PURPOSE_AVATAR, "image/png", 1024, nil},
+		{"banner jpeg", common.UPLOAD_PURPOSE_EVENT_BANNER, "image/jpeg", common.UPLOAD_BANNER_MAX_BYTES, nil},
+		{"avatar too large", common.UPLOAD_PURPOSE_AVATAR, "image/png", common.UPLOAD_AVATAR_MAX_BYTES + 1, common.ErrUploadTooLarge},
+		{"unknown purpose", "resume", "image/png", 1, common.ErrUploadTooLarge},
+		{"gif", common.UPLOAD_PURPOSE_AVATAR, "image/gif", 1024, common.ErrUploadContentTypeInvalid},
+		{"svg", common.UPLOAD_PURPOSE_EVENT_BANNER, "image/svg+xml", 1024, common.ErrUploadContentTypeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateUploadRequest(tt.purpose, tt.contentType, tt.size); err != tt.wantErr {
+				t.Errorf("ValidateUploadRequest() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateUploadedObject(t *testing.T) {
+	upload := models.Upload{Purpose: common.UPLOAD_PURPOSE_AVATAR, ContentType: "image/png", Size: 1024}
+
+	tests := []struct {
+		name    string
+		info    models.ObjectInfo
+		wantErr error
+	}{
+		{"as declared", models.ObjectInfo{Size: 1024, ContentType: "image/png"}, nil},
+		{"with params", models.ObjectInfo{Size: 1024, ContentType: "image/png; charset=binary"}, nil},
+		{"other size", models.ObjectInfo{Size: 1000, ContentType: "image/png"}, common.ErrUploadMismatch},
+		{"other image type", models.ObjectInfo{Size: 1024, ContentType: "image/jpeg"}, common.ErrUploadMismatch},
+		{"too large", models.ObjectInfo{Size: common.UPLOAD_AVATAR_MAX_BYTES + 1, ContentType: "image/png"}, common.ErrUploadTooLarge},
+		{"html", models.ObjectInfo{Size: 1024, ContentType: "text/html"}, common.ErrUploadContentTypeInvalid},
+		{"empty", models.ObjectInfo{Size: 1024, ContentType: ""}, common.ErrUploadContentTypeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateUploadedObject(upload, tt.info); err != tt.wantErr {
+				t.Errorf("ValidateUploadedObject() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
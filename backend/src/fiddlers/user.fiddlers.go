@@ -7,7 +7,7 @@ import (
 	"github.com/patos-ufscar/quack-week/models"
 )
 
-func NewUnconfirmedUser(email string, password string, firstName string, lastName string, dateOfBirth *time.Time) (*models.UnconfirmedUser, error) {
+func NewUnconfirmedUser(email string, password string, firstName string, lastName string, dateOfBirth *time.Time, locale string) (*models.UnconfirmedUser, error) {
 	hash, err := common.HashPassword(password)
 	if err != nil {
 		return nil, err
@@ -25,5 +25,6 @@ func NewUnconfirmedUser(email string, password string, firstName string, lastNam
 		FirstName:    firstName,
 		LastName:     lastName,
 		DateOfBirth:  dateOfBirth,
+		Locale:       ResolveLocale(locale),
 	}, nil
 }
@@ -0,0 +1,96 @@
+package fiddlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+func NewWebhookSecret() (string, error) {
+	secret, err := common.GenerateRandomString(common.WEBHOOK_SECRET_LEN)
+	if err != nil {
+		return "", err
+	}
+
+	return common.WEBHOOK_SECRET_PREFIX + secret, nil
+}
+
+// SignWebhookPayload builds the signature header value, receivers compute the
+// HMAC-SHA256 of "<t>.<body>" with the secret and compare it to v1. The
+// timestamp lets them reject replayed deliveries.
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%s,v1=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Exponential backoff starting at `common.WEBHOOK_BACKOFF_SECS`, capped at
+// `common.WEBHOOK_MAX_BACKOFF_HOURS`
+func GetWebhookRetryDelay(attempts int) time.Duration {
+	base := time.Duration(common.WEBHOOK_BACKOFF_SECS) * time.Second
+	maxDelay := time.Duration(common.WEBHOOK_MAX_BACKOFF_HOURS) * time.Hour
+
+	if attempts < 1 {
+		return base
+	}
+
+	delay := base << min(attempts-1, 20)
+	if delay > maxDelay {
+		return maxDelay
+	}
+
+	return delay
+}
+
+// reserved ranges not covered by the net.IP helpers
+var webhookBlockedNets = []*net.IPNet{
+	mustParseCidr("0.0.0.0/8"),
+	mustParseCidr("100.64.0.0/10"),
+	mustParseCidr("198.18.0.0/15"),
+}
+
+func mustParseCidr(cidr string) *net.IPNet {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// IsWebhookIpAllowed reports if webhooks may be sent to ip, so organizations
+// cannot reach the loopback, private, link-local or unspecified addresses of
+// the servers
+func IsWebhookIpAllowed(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	if ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() {
+		return false
+	}
+
+	for _, n := range webhookBlockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
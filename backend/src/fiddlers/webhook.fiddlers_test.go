@@ -0,0 +1,103 @@
+package fiddlers
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	timestamp := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"payment.completed"}`)
+
+	tests := []struct {
+		name    string
+		secret  string
+		body    []byte
+		expects string
+	}{
+		{"known value", "whsec_test", body, "t=1792411200,v1=b3d8797c8d734a75f4fe0710442a22195da0bb140932b44f0672f575af5dbc8c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SignWebhookPayload(tt.secret, timestamp, tt.body); got != tt.expects {
+				t.Errorf("SignWebhookPayload() = %s, want %s", got, tt.expects)
+			}
+		})
+	}
+
+	if SignWebhookPayload("whsec_other", timestamp, body) == SignWebhookPayload("whsec_test", timestamp, body) {
+		t.Error("SignWebhookPayload() same signature for different secrets")
+	}
+	if SignWebhookPayload("whsec_test", timestamp.Add(time.Second), body) == SignWebhookPayload("whsec_test", timestamp, body) {
+		t.Error("SignWebhookPayload() same signature for different timestamps")
+	}
+}
+
+func TestNewWebhookSecret(t *testing.T) {
+	secret, err := NewWebhookSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, common.WEBHOOK_SECRET_PREFIX) {
+		t.Errorf("NewWebhookSecret() = %s, want prefix %s", secret, common.WEBHOOK_SECRET_PREFIX)
+	}
+	if len(secret) != len(common.WEBHOOK_SECRET_PREFIX)+common.WEBHOOK_SECRET_LEN {
+		t.Errorf("NewWebhookSecret() len = %d", len(secret))
+	}
+}
+
+func TestGetWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{"no attempts", 0, time.Minute},
+		{"first failure", 1, time.Minute},
+		{"second failure", 2, 2 * time.Minute},
+		{"fifth failure", 5, 16 * time.Minute},
+		{"capped", 12, 12 * time.Hour},
+		{"huge", 1000, 12 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetWebhookRetryDelay(tt.attempts); got != tt.want {
+				t.Errorf("GetWebhookRetryDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsWebhookIpAllowed(t *testing.T) {
+	tests := []struct {
+		ip      string
+		expects bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := IsWebhookIpAllowed(net.ParseIP(tt.ip)); got != tt.expects {
+				t.Errorf("IsWebhookIpAllowed(%s) = %v, want %v", tt.ip, got, tt.expects)
+			}
+		})
+	}
+}
@@ -3,9 +3,9 @@ package helpers
 import (
 	"context"
 	"database/sql"
-	"path/filepath"
 
 	_ "github.com/lib/pq"
+	"github.com/patos-ufscar/quack-week/migrations"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -20,7 +20,6 @@ type PostgresContainer struct {
 func NewPostgresContainer(ctx context.Context) (*PostgresContainer, error) {
 	pgContainer, err := postgres.Run(ctx,
 		"postgres:16.6-bullseye",
-		postgres.WithInitScripts(filepath.Join("..", "scripts", "init-db.sql")),
 		postgres.WithDatabase("test"),
 		postgres.WithUsername("user"),
 		postgres.WithPassword("password"),
@@ -48,5 +47,14 @@ func NewPostgresContainer(ctx context.Context) (*PostgresContainer, error) {
 		return nil, err
 	}
 
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	_, err = migrator.Up(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return &PostgresContainer{DB: db, Container: pgContainer, ConnString: connStr}, nil
 }
@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// FakeSmtpMessage is an email accepted by FakeSmtpServer
+type FakeSmtpMessage struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// FakeSmtpServer is a minimal plaintext SMTP server that accepts every message.
+// It advertises AUTH PLAIN but not STARTTLS.
+type FakeSmtpServer struct {
+	Host string
+	Port string
+
+	listener net.Listener
+	mu       sync.Mutex
+	messages []FakeSmtpMessage
+}
+
+func NewFakeSmtpServer() *FakeSmtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		panic(err)
+	}
+
+	f := &FakeSmtpServer{
+		Host:     host,
+		Port:     port,
+		listener: listener,
+	}
+
+	go f.serve()
+
+	return f
+}
+
+// Messages returns a copy of every message received so far
+func (f *FakeSmtpServer) Messages() []FakeSmtpMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeSmtpMessage{}, f.messages...)
+}
+
+func (f *FakeSmtpServer) Close() {
+	f.listener.Close()
+}
+
+func (f *FakeSmtpServer) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go f.handle(conn)
+	}
+}
+
+func (f *FakeSmtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake-smtp ready")
+
+	var msg FakeSmtpMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tp.PrintfLine("250-fake-smtp")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "HELO", "NOOP":
+			tp.PrintfLine("250 OK")
+		case "AUTH":
+			tp.PrintfLine("235 Authentication successful")
+		case "MAIL":
+			msg = FakeSmtpMessage{From: trimPath(arg)}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, trimPath(arg))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = data
+
+			f.mu.Lock()
+			f.messages = append(f.messages, msg)
+			f.mu.Unlock()
+
+			tp.PrintfLine("250 OK")
+		case "RSET":
+			msg = FakeSmtpMessage{}
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// trimPath extracts the address from MAIL/RCPT arguments like "FROM:<a@b> BODY=8BITMIME"
+func trimPath(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	path, _, _ = strings.Cut(path, " ")
+
+	return strings.Trim(path, "<>")
+}
@@ -0,0 +1,142 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/stripe/stripe-go/v81"
+)
+
+// FakeStripeServer is an in-memory stand-in for the Stripe Checkout Sessions API
+type FakeStripeServer struct {
+	Server *httptest.Server
+
+	mu       sync.Mutex
+	sessions map[string]*stripe.CheckoutSession
+}
+
+func NewFakeStripeServer() *FakeStripeServer {
+	f := &FakeStripeServer{
+		sessions: make(map[string]*stripe.CheckoutSession),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/checkout/sessions", f.createSession)
+	mux.HandleFunc("GET /v1/checkout/sessions/{id}", f.getSession)
+	mux.HandleFunc("POST /v1/checkout/sessions/{id}/expire", f.expireSession)
+
+	f.Server = httptest.NewServer(mux)
+
+	return f
+}
+
+// Backend returns a Stripe API backend pointing to the fake server
+func (f *FakeStripeServer) Backend() stripe.Backend {
+	return stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+		URL:               stripe.String(f.Server.URL),
+		MaxNetworkRetries: stripe.Int64(0),
+		LeveledLogger:     &stripe.LeveledLogger{Level: stripe.LevelNull},
+	})
+}
+
+// SetSession creates or overwrites a session with the given state
+func (f *FakeStripeServer) SetSession(id string, status stripe.CheckoutSessionStatus, paymentStatus stripe.CheckoutSessionPaymentStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sessions[id] = newStripeSession(id, status, paymentStatus)
+}
+
+// GetSession returns a copy of the current state of a session, nil if non-existant
+func (f *FakeStripeServer) GetSession(id string) *stripe.CheckoutSession {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cs, ok := f.sessions[id]
+	if !ok {
+		return nil
+	}
+	c := *cs
+
+	return &c
+}
+
+func (f *FakeStripeServer) Close() {
+	f.Server.Close()
+}
+
+func (f *FakeStripeServer) createSession(w http.ResponseWriter, r *http.Request) {
+	referenceId := r.FormValue("client_reference_id")
+
+	f.mu.Lock()
+	id := fmt.Sprintf("cs_test_%d", len(f.sessions)+1)
+	cs := newStripeSession(id, stripe.CheckoutSessionStatusOpen, stripe.CheckoutSessionPaymentStatusUnpaid)
+	cs.ClientReferenceID = referenceId
+	f.sessions[id] = cs
+	c := *cs
+	f.mu.Unlock()
+
+	writeStripeJson(w, http.StatusOK, &c)
+}
+
+func (f *FakeStripeServer) getSession(w http.ResponseWriter, r *http.Request) {
+	cs := f.GetSession(r.PathValue("id"))
+	if cs == nil {
+		writeStripeError(w, http.StatusNotFound, "resource_missing")
+		return
+	}
+
+	writeStripeJson(w, http.StatusOK, cs)
+}
+
+func (f *FakeStripeServer) expireSession(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	cs, ok := f.sessions[r.PathValue("id")]
+	expired := ok && cs.Status == stripe.CheckoutSessionStatusOpen
+	var c stripe.CheckoutSession
+	if expired {
+		cs.Status = stripe.CheckoutSessionStatusExpired
+		c = *cs
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		writeStripeError(w, http.StatusNotFound, "resource_missing")
+		return
+	}
+
+	if !expired {
+		writeStripeError(w, http.StatusBadRequest, "checkout_session_not_open")
+		return
+	}
+
+	writeStripeJson(w, http.StatusOK, &c)
+}
+
+func newStripeSession(id string, status stripe.CheckoutSessionStatus, paymentStatus stripe.CheckoutSessionPaymentStatus) *stripe.CheckoutSession {
+	return &stripe.CheckoutSession{
+		ID:            id,
+		Object:        "checkout.session",
+		Status:        status,
+		PaymentStatus: paymentStatus,
+		URL:           "https://checkout.stripe.test/" + id,
+	}
+}
+
+func writeStripeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeStripeError(w http.ResponseWriter, status int, code string) {
+	writeStripeJson(w, status, map[string]any{
+		"error": map[string]string{
+			"type": "invalid_request_error",
+			"code": code,
+		},
+	})
+}
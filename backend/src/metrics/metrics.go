@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "quack_week"
+
+const (
+	TASK_OUTCOME_SUCCESS string = "success"
+	TASK_OUTCOME_ERROR   string = "error"
+	TASK_OUTCOME_PANIC   string = "panic"
+
+	EMAIL_OUTCOME_SENT   string = "sent"
+	EMAIL_OUTCOME_FAILED string = "failed"
+	EMAIL_OUTCOME_DEAD   string = "dead"
+
+	REGISTRATION_KIND_FREE string = "free"
+	REGISTRATION_KIND_PAID string = "paid"
+
+	// routes no handler matched share a label, so scanners do not blow up
+	// the cardinality
+	ROUTE_UNMATCHED string = "unmatched"
+)
+
+// Registry holds the metrics of the app along with the go runtime and
+// process ones, it is what `Handler` exposes
+var Registry = prometheus.NewRegistry()
+
+var (
+	HttpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	TaskRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "task_runs_total",
+		Help:      "Runs of the background tasks by outcome.",
+	}, []string{"task", "outcome"})
+
+	TaskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_duration_seconds",
+		Help:      "Duration of the background task runs.",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"task"})
+
+	EmailsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_total",
+		Help:      "Email send attempts by outcome, dead emails ran out of attempts.",
+	}, []string{"outcome"})
+
+	Registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Event registrations, free ones are fully discounted.",
+	}, []string{"kind"})
+
+	PaymentsCompleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_completed_total",
+		Help:      "Payments completed by the checkout provider.",
+	}, []string{"provider"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequestDuration,
+		TaskRuns,
+		TaskDuration,
+		EmailsSent,
+		Registrations,
+		PaymentsCompleted,
+	)
+}
+
+// RegisterDb exposes the connection pool stats of db, from `sql.DB.Stats()`
+func RegisterDb(db *sql.DB, dbName string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler serves the metrics of `Registry` in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+)
+
+// AccessLog logs every request once it is done, with its status and latency,
+// through the request logger so it carries the request, user and org ids.
+// Server errors are logged as errors and client errors as warnings.
+func AccessLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		path := ctx.Request.URL.Path
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		reqCtx := ctx.Request.Context()
+		common.Logger(reqCtx).LogAttrs(reqCtx, level, "request",
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", path),
+			slog.String("route", ctx.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", max(ctx.Writer.Size(), 0)),
+			slog.String("clientIp", ctx.ClientIP()),
+			slog.String("userAgent", ctx.Request.UserAgent()),
+		)
+	}
+}
+
+// Recovery replaces gin's recovery, panics are logged with their stack
+// through the request logger and answered with a 500 problem
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, err any) {
+		common.Logger(ctx.Request.Context()).Error(fmt.Sprintf("panic: %v", err), slog.String("stack", string(debug.Stack())))
+		fiddlers.AbortWithProblem(ctx, http.StatusInternalServerError, common.PROBLEM_CODE_INTERNAL, "")
+	})
+}
@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	buf := &bytes.Buffer{}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(RequestId(), AccessLog(), Recovery())
+	router.GET("/things/:thingId", func(ctx *gin.Context) {
+		withLogAttrs(ctx, slog.Uint64("userId", 7))
+		common.Logger(ctx).Info("handled")
+		ctx.String(http.StatusOK, "OK")
+	})
+	router.GET("/panic", func(ctx *gin.Context) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantLevel  string
+		wantRoute  string
+	}{
+		{"/things/1", http.StatusOK, "INFO", "/things/:thingId"},
+		{"/panic", http.StatusInternalServerError, "ERROR", "/panic"},
+		{"/missing", http.StatusNotFound, "WARN", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(common.REQUEST_ID_HEADER, "req-1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			records := []map[string]any{}
+			dec := json.NewDecoder(buf)
+			for dec.More() {
+				record := map[string]any{}
+				if err := dec.Decode(&record); err != nil {
+					t.Fatal(err)
+				}
+				records = append(records, record)
+			}
+			if len(records) == 0 {
+				t.Fatal("no records logged")
+			}
+
+			for _, record := range records {
+				if record["requestId"] != "req-1" {
+					t.Errorf("record %v has no requestId", record)
+				}
+			}
+
+			access := records[len(records)-1]
+			if access["msg"] != "request" || access["level"] != tt.wantLevel ||
+				access["status"] != float64(tt.wantStatus) || access["route"] != tt.wantRoute || access["path"] != tt.path {
+				t.Errorf("AccessLog() record = %v", access)
+			}
+			if _, ok := access["latency"]; !ok {
+				t.Errorf("AccessLog() record has no latency: %v", access)
+			}
+			if tt.wantStatus == http.StatusOK && access["userId"] != float64(7) {
+				t.Errorf("AccessLog() record lost the handler attrs: %v", access)
+			}
+		})
+	}
+}
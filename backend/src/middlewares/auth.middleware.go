@@ -8,4 +8,6 @@ type AuthMiddleware interface {
 	AuthorizeUser() gin.HandlerFunc
 	AuthorizeOrganization(needAdmin bool) gin.HandlerFunc
 	Reauthorize() gin.HandlerFunc
+	// Must come after AuthorizeUser
+	AuthorizePlatformAdmin() gin.HandlerFunc
 }
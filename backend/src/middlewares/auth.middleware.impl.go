@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/patos-ufscar/quack-week/common"
 	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/models"
 	"github.com/patos-ufscar/quack-week/services"
 )
 
@@ -28,18 +29,16 @@ func (m *AuthMiddlewareJwt) AuthorizeUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenStr, err := c.Cookie(common.JWT_COOKIE_NAME)
 		if err != nil && err != http.ErrNoCookie {
-			c.String(http.StatusUnauthorized, "Unauthorized")
 			common.ClearAuthCookie(c)
-			c.Abort()
+			fiddlers.AbortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 			return
 		}
 
 		jwtClaims, err := m.authService.ParseToken(tokenStr)
 		if err != nil {
-			slog.Info(err.Error())
-			c.String(http.StatusUnauthorized, "Unauthorized")
+			common.Logger(c).Info(err.Error())
 			common.ClearAuthCookie(c)
-			c.Abort()
+			fiddlers.AbortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 			return
 		}
 
@@ -49,13 +48,12 @@ func (m *AuthMiddlewareJwt) AuthorizeUser() gin.HandlerFunc {
 		expTTL := time.Until(expTime)
 
 		if expTTL > time.Minute*time.Duration(common.JWT_TIMEOUT_SECS/2) {
-			slog.Info(fmt.Sprintf("renewing jwt: %s", jwtClaims.Email))
+			common.Logger(c).Info(fmt.Sprintf("renewing jwt: %s", jwtClaims.Email))
 			token, err := m.authService.InitToken(jwtClaims.UserId, jwtClaims.Email, jwtClaims.OrganizationId, jwtClaims.IsAdmin)
 			if err != nil {
-				slog.Error(err.Error())
-				c.String(http.StatusBadGateway, "BadGateway")
+				common.Logger(c).Error(err.Error())
 				common.ClearAuthCookie(c)
-				c.Abort()
+				fiddlers.AbortWithProblem(c, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 				return
 			}
 
@@ -63,6 +61,7 @@ func (m *AuthMiddlewareJwt) AuthorizeUser() gin.HandlerFunc {
 		}
 
 		c.Set(common.GIN_CTX_JWT_CLAIM_KEY_NAME, jwtClaims)
+		withClaimsLogAttrs(c, jwtClaims)
 		c.Next()
 	}
 }
@@ -71,40 +70,35 @@ func (m *AuthMiddlewareJwt) AuthorizeOrganization(needAdmin bool) gin.HandlerFun
 	return func(c *gin.Context) {
 		tokenStr, err := c.Cookie(common.JWT_COOKIE_NAME)
 		if err != nil && err != http.ErrNoCookie {
-			c.String(http.StatusUnauthorized, "Unauthorized")
 			common.ClearAuthCookie(c)
-			c.Abort()
+			fiddlers.AbortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 			return
 		}
 
 		jwtClaims, err := m.authService.ParseToken(tokenStr)
 		if err != nil {
-			slog.Info(err.Error())
-			c.String(http.StatusUnauthorized, "Unauthorized")
+			common.Logger(c).Info(err.Error())
 			common.ClearAuthCookie(c)
-			c.Abort()
+			fiddlers.AbortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 			return
 		}
 
 		orgId := c.Param("orgId")
 		if jwtClaims.OrganizationId == nil || jwtClaims.IsAdmin == nil {
-			c.String(http.StatusUnauthorized, "Unauthorized")
 			common.ClearAuthCookie(c)
-			c.Abort()
+			fiddlers.AbortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 			return
 		}
 
 		if orgId != *jwtClaims.OrganizationId {
-			c.String(http.StatusUnauthorized, "Unauthorized")
 			common.ClearAuthCookie(c)
-			c.Abort()
+			fiddlers.AbortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 			return
 		}
 
 		if needAdmin && !*jwtClaims.IsAdmin {
-			c.String(http.StatusUnauthorized, "Unauthorized")
 			common.ClearAuthCookie(c)
-			c.Abort()
+			fiddlers.AbortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
 			return
 		}
 
@@ -114,13 +108,12 @@ func (m *AuthMiddlewareJwt) AuthorizeOrganization(needAdmin bool) gin.HandlerFun
 		expTTL := time.Until(expTime)
 
 		if expTTL > time.Minute*time.Duration(common.JWT_TIMEOUT_SECS/2) {
-			slog.Info(fmt.Sprintf("renewing jwt: %s", jwtClaims.Email))
+			common.Logger(c).Info(fmt.Sprintf("renewing jwt: %s", jwtClaims.Email))
 			token, err := m.authService.InitToken(jwtClaims.UserId, jwtClaims.Email, jwtClaims.OrganizationId, jwtClaims.IsAdmin)
 			if err != nil {
-				slog.Error(err.Error())
-				c.String(http.StatusBadGateway, "BadGateway")
+				common.Logger(c).Error(err.Error())
 				common.ClearAuthCookie(c)
-				c.Abort()
+				fiddlers.AbortWithProblem(c, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 				return
 			}
 
@@ -128,6 +121,7 @@ func (m *AuthMiddlewareJwt) AuthorizeOrganization(needAdmin bool) gin.HandlerFun
 		}
 
 		c.Set(common.GIN_CTX_JWT_CLAIM_KEY_NAME, jwtClaims)
+		withClaimsLogAttrs(c, jwtClaims)
 		c.Next()
 	}
 }
@@ -136,20 +130,18 @@ func (m *AuthMiddlewareJwt) Reauthorize() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		jwtClaims, err := fiddlers.GetClaimsFromGinCtx(c)
 		if err != nil {
-			slog.Error(err.Error())
-			c.String(http.StatusBadGateway, "BadGateway")
+			common.Logger(c).Error(err.Error())
 			common.ClearAuthCookie(c)
-			c.Abort()
+			fiddlers.AbortWithProblem(c, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 			return
 		}
 
-		slog.Info(fmt.Sprintf("renewing jwt: %s", jwtClaims.Email))
+		common.Logger(c).Info(fmt.Sprintf("renewing jwt: %s", jwtClaims.Email))
 		token, err := m.authService.InitToken(jwtClaims.UserId, jwtClaims.Email, jwtClaims.OrganizationId, jwtClaims.IsAdmin)
 		if err != nil {
-			slog.Error(err.Error())
-			c.String(http.StatusBadGateway, "BadGateway")
+			common.Logger(c).Error(err.Error())
 			common.ClearAuthCookie(c)
-			c.Abort()
+			fiddlers.AbortWithProblem(c, http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY, "")
 			return
 		}
 
@@ -159,3 +151,32 @@ func (m *AuthMiddlewareJwt) Reauthorize() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// Allows only the users in `common.PLATFORM_ADMIN_EMAILS`
+func (m *AuthMiddlewareJwt) AuthorizePlatformAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwtClaims, err := fiddlers.GetClaimsFromGinCtx(c)
+		if err != nil {
+			fiddlers.AbortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
+			return
+		}
+
+		if !fiddlers.IsPlatformAdmin(jwtClaims.Email, common.PLATFORM_ADMIN_EMAILS) {
+			fiddlers.AbortWithProblem(c, http.StatusForbidden, common.PROBLEM_CODE_FORBIDDEN, "")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// withClaimsLogAttrs scopes the request logger with the authenticated user
+// and, when the token has one, its organization
+func withClaimsLogAttrs(c *gin.Context, jwtClaims models.JwtClaims) {
+	attrs := []any{slog.Uint64("userId", uint64(jwtClaims.UserId))}
+	if jwtClaims.OrganizationId != nil {
+		attrs = append(attrs, slog.String("orgId", *jwtClaims.OrganizationId))
+	}
+
+	withLogAttrs(c, attrs...)
+}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+)
+
+// ErrorHandler replies with a problem+json to the last error a handler
+// attached with ctx.Error, unless the handler already wrote a response.
+// Errors with no known status are logged and answered with 502.
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		err := ctx.Errors.Last().Err
+		status, code := ErrorStatus(err)
+		if status >= http.StatusInternalServerError {
+			common.Logger(ctx).Error(err.Error())
+		}
+
+		// the violated constraint tells the client which field clashed
+		detail := ""
+		var dbErr *common.DbError
+		if errors.As(common.FilterSqlPgError(err), &dbErr) && status < http.StatusInternalServerError {
+			detail = dbErr.Constraint
+		}
+
+		fiddlers.AbortWithProblem(ctx, status, code, detail)
+	}
+}
+
+// ErrorStatus maps err to its HTTP status and problem code
+func ErrorStatus(err error) (int, string) {
+	err = common.FilterSqlPgError(err)
+
+	switch {
+	case errors.Is(err, common.ErrNotFound):
+		return http.StatusNotFound, common.PROBLEM_CODE_NOT_FOUND
+	case errors.Is(err, common.ErrConflict):
+		return http.StatusConflict, common.PROBLEM_CODE_CONFLICT
+	case errors.Is(err, common.ErrForeignKey):
+		return http.StatusConflict, common.PROBLEM_CODE_FOREIGN_KEY
+	case errors.Is(err, common.ErrCheckViolation):
+		return http.StatusUnprocessableEntity, common.PROBLEM_CODE_CHECK
+	case errors.Is(err, common.ErrSerialization):
+		// the transaction lost a race, retrying is safe
+		return http.StatusServiceUnavailable, common.PROBLEM_CODE_SERIALIZATION
+	case errors.Is(err, common.ErrAuth):
+		return http.StatusForbidden, common.PROBLEM_CODE_FORBIDDEN
+	default:
+		return http.StatusBadGateway, common.PROBLEM_CODE_BAD_GATEWAY
+	}
+}
@@ -0,0 +1,113 @@
+package middlewares
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/schemas"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		handler    gin.HandlerFunc
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			"no rows",
+			func(ctx *gin.Context) { ctx.Error(sql.ErrNoRows) },
+			http.StatusNotFound,
+			common.PROBLEM_CODE_NOT_FOUND,
+		},
+		{
+			"not found",
+			func(ctx *gin.Context) { ctx.Error(common.ErrNotFound) },
+			http.StatusNotFound,
+			common.PROBLEM_CODE_NOT_FOUND,
+		},
+		{
+			"unique violation",
+			func(ctx *gin.Context) { ctx.Error(&pq.Error{Code: "23505", Constraint: "users_email_key"}) },
+			http.StatusConflict,
+			common.PROBLEM_CODE_CONFLICT,
+		},
+		{
+			"foreign key",
+			func(ctx *gin.Context) { ctx.Error(common.FilterSqlPgError(&pq.Error{Code: "23503"})) },
+			http.StatusConflict,
+			common.PROBLEM_CODE_FOREIGN_KEY,
+		},
+		{
+			"check violation",
+			func(ctx *gin.Context) { ctx.Error(&pq.Error{Code: "23514"}) },
+			http.StatusUnprocessableEntity,
+			common.PROBLEM_CODE_CHECK,
+		},
+		{
+			"serialization",
+			func(ctx *gin.Context) { ctx.Error(&pq.Error{Code: "40001"}) },
+			http.StatusServiceUnavailable,
+			common.PROBLEM_CODE_SERIALIZATION,
+		},
+		{
+			"unknown",
+			func(ctx *gin.Context) { ctx.Error(errors.New("boom")) },
+			http.StatusBadGateway,
+			common.PROBLEM_CODE_BAD_GATEWAY,
+		},
+		{
+			"already written",
+			func(ctx *gin.Context) {
+				ctx.String(http.StatusTeapot, "Teapot")
+				ctx.Error(common.ErrNotFound)
+			},
+			http.StatusTeapot,
+			"",
+		},
+		{
+			"no error",
+			func(ctx *gin.Context) { ctx.String(http.StatusOK, "OK") },
+			http.StatusOK,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", tt.handler)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ErrorHandler() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				if w.Header().Get("Content-Type") == common.PROBLEM_CONTENT_TYPE {
+					t.Errorf("ErrorHandler() replaced the response: %s", w.Body.String())
+				}
+				return
+			}
+
+			var problem schemas.Problem
+			err := json.Unmarshal(w.Body.Bytes(), &problem)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Header().Get("Content-Type") != common.PROBLEM_CONTENT_TYPE || problem.Code != tt.wantCode || problem.Status != tt.wantStatus {
+				t.Errorf("ErrorHandler() = %s %s, want code %s", w.Header().Get("Content-Type"), w.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
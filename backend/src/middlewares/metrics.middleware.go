@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/metrics"
+)
+
+// Metrics observes the latency of every request, labeled by its route
+// template instead of its path so ids do not become series
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = metrics.ROUTE_UNMATCHED
+		}
+
+		metrics.HttpRequestDuration.WithLabelValues(
+			ctx.Request.Method,
+			route,
+			strconv.Itoa(ctx.Writer.Status()),
+		).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsToken allows only the requests bearing token, for /metrics when it
+// is served on the API port
+func MetricsToken(token string) gin.HandlerFunc {
+	expected := []byte("Bearer " + token)
+
+	return func(ctx *gin.Context) {
+		got := []byte(ctx.GetHeader("Authorization"))
+		if token == "" || subtle.ConstantTimeCompare(got, expected) != 1 {
+			fiddlers.AbortWithProblem(ctx, http.StatusUnauthorized, common.PROBLEM_CODE_UNAUTHORIZED, "")
+			return
+		}
+
+		ctx.Next()
+	}
+}
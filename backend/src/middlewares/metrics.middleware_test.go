@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Metrics())
+	router.GET("/things/:thingId", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "OK")
+	})
+	router.GET("/metrics", MetricsToken("secret"), gin.WrapH(metrics.Handler()))
+
+	for _, path := range []string{"/things/1", "/things/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	tests := []struct {
+		name       string
+		auth       string
+		wantStatus int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"token", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("MetricsToken() = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			body := w.Body.String()
+			for _, want := range []string{
+				`quack_week_http_request_duration_seconds_count{method="GET",route="/things/:thingId",status="200"} 2`,
+				`quack_week_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+			} {
+				if !strings.Contains(body, want) {
+					t.Errorf("metrics missing %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsTokenEmpty(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/metrics", MetricsToken(""), gin.WrapH(metrics.Handler()))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("MetricsToken(\"\") = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"log/slog"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/patos-ufscar/quack-week/common"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ids from proxies are kept as long as they are safe to log and echo back
+var requestIdRegex = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestId keeps the X-Request-Id of the request, or generates one, sets it
+// as `common.GIN_CTX_REQUEST_ID_KEY`, echoes it in the response and scopes
+// the request logger with it and the trace id, if the request is traced
+func RequestId() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestId := ctx.GetHeader(common.REQUEST_ID_HEADER)
+		if !requestIdRegex.MatchString(requestId) {
+			requestId = uuid.NewString()
+		}
+
+		ctx.Set(common.GIN_CTX_REQUEST_ID_KEY, requestId)
+		ctx.Header(common.REQUEST_ID_HEADER, requestId)
+		attrs := []any{slog.String("requestId", requestId)}
+		if span := trace.SpanContextFromContext(ctx.Request.Context()); span.IsValid() {
+			attrs = append(attrs, slog.String("traceId", span.TraceID().String()))
+		}
+		withLogAttrs(ctx, attrs...)
+		ctx.Next()
+	}
+}
+
+// withLogAttrs adds attrs to the logger of the request, the router needs
+// ContextWithFallback so `common.Logger(ctx)` finds it through the gin ctx
+func withLogAttrs(ctx *gin.Context, attrs ...any) {
+	logger := common.Logger(ctx.Request.Context()).With(attrs...)
+	ctx.Request = ctx.Request.WithContext(common.WithLogger(ctx.Request.Context(), logger))
+}
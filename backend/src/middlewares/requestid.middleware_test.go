@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+func TestRequestId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		incoming string
+		wantKept bool
+	}{
+		{"generated", "", false},
+		{"kept", "abc-123.def:456", true},
+		{"unsafe", "abc\ndef", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			router := gin.New()
+			router.Use(RequestId())
+			router.GET("/", func(ctx *gin.Context) {
+				seen = ctx.GetString(common.GIN_CTX_REQUEST_ID_KEY)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header[common.REQUEST_ID_HEADER] = []string{tt.incoming}
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(common.REQUEST_ID_HEADER)
+			if got == "" || got != seen {
+				t.Fatalf("RequestId() header = %q, ctx = %q", got, seen)
+			}
+			if (got == tt.incoming) != tt.wantKept {
+				t.Errorf("RequestId() = %q, incoming %q, wantKept %v", got, tt.incoming, tt.wantKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"slices"
+
+	limits "github.com/gin-contrib/size"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestSizeLimiter caps the request bodies at limit, except the ones of the
+// `exempt` route templates, which enforce their own
+func RequestSizeLimiter(limit int64, exempt ...string) gin.HandlerFunc {
+	limiter := limits.RequestSizeLimiter(limit)
+
+	return func(ctx *gin.Context) {
+		if slices.Contains(exempt, ctx.FullPath()) {
+			ctx.Next()
+			return
+		}
+
+		limiter(ctx)
+	}
+}
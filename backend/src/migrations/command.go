@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+const (
+	COMMAND_MIGRATE  string = "migrate"
+	COMMAND_ROLLBACK string = "rollback"
+	COMMAND_STATUS   string = "status"
+)
+
+// RunCommand runs the migration subcommand of the binary:
+//
+//	main migrate          applies every pending migration
+//	main rollback [steps] rolls back the last steps migrations, 1 by default
+//	main status           lists the migrations and when they were applied
+func RunCommand(ctx context.Context, m *Migrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: expected one of %s, %s, %s", common.ErrMigrationCommandUnknown, COMMAND_MIGRATE, COMMAND_ROLLBACK, COMMAND_STATUS)
+	}
+
+	switch args[0] {
+	case COMMAND_MIGRATE:
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range applied {
+			fmt.Fprintf(w, "applied %d_%s\n", migration.Version, migration.Name)
+		}
+		fmt.Fprintf(w, "%d migrations applied\n", len(applied))
+	case COMMAND_ROLLBACK:
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("%w: steps must be a positive integer", common.ErrMigrationCommandUnknown)
+			}
+		}
+		rolledBack, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range rolledBack {
+			fmt.Fprintf(w, "rolled back %d_%s\n", migration.Version, migration.Name)
+		}
+		fmt.Fprintf(w, "%d migrations rolled back\n", len(rolledBack))
+	case COMMAND_STATUS:
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			name := status.Name
+			if name == "" {
+				name = "(unknown)"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, name, appliedAt)
+		}
+	default:
+		return fmt.Errorf("%w: %s", common.ErrMigrationCommandUnknown, args[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,354 @@
+package migrations
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// both replicas starting at the same time wait on this lock, so only one of
+// them applies the pending migrations
+const advisoryLockKey int64 = 0x71756163_6b6d6967 // "quackmig"
+
+var fileNameRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies the migrations of sql/, files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql and every up needs a down.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	sub, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMigratorFs(db, sub)
+}
+
+func NewMigratorFs(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// LoadMigrations reads the migrations at the root of fsys, sorted by version
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := fileNameRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: bad file name %s", common.ErrMigrationInvalid, entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad version %s", common.ErrMigrationInvalid, entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d has two names", common.ErrMigrationInvalid, version)
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("%w: version %d needs an up and a down file", common.ErrMigrationInvalid, m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := []Migration{}
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			slog.Info(fmt.Sprintf("applying migration %d_%s", migration.Version, migration.Name))
+			err = m.apply(ctx, conn, migration.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2);",
+				migration.Version, migration.Name,
+			)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back the last steps applied migrations and returns them, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	rolledBack := []Migration{}
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		sorted := []int64{}
+		for version := range versions {
+			sorted = append(sorted, version)
+		}
+		slices.Sort(sorted)
+		slices.Reverse(sorted)
+
+		for _, version := range sorted[:min(steps, len(sorted))] {
+			idx := slices.IndexFunc(m.migrations, func(migration Migration) bool {
+				return migration.Version == version
+			})
+			if idx < 0 {
+				return fmt.Errorf("%w: version %d is applied but unknown", common.ErrMigrationInvalid, version)
+			}
+			migration := m.migrations[idx]
+
+			slog.Info(fmt.Sprintf("rolling back migration %d_%s", migration.Version, migration.Name))
+			err = m.apply(ctx, conn, migration.Down,
+				"DELETE FROM schema_migrations WHERE version = $1;",
+				migration.Version,
+			)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// Status lists the known migrations, AppliedAt is nil for the pending ones.
+// Applied versions this binary does not know are listed with an empty name.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	statuses := []MigrationStatus{}
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+				delete(versions, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+
+		for version, appliedAt := range versions {
+			statuses = append(statuses, MigrationStatus{Version: version, AppliedAt: &appliedAt})
+		}
+		slices.SortFunc(statuses, func(a, b MigrationStatus) int {
+			return cmp.Compare(a.Version, b.Version)
+		})
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// withLock runs fn on a single connection holding the migrations advisory
+// lock, creating the schema_migrations table if needed
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1);", advisoryLockKey)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// the context may be done, the lock still has to be released
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1);", advisoryLockKey)
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
+	err = m.ensureTable(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// ensureTable creates schema_migrations, databases created by the old
+// init-db.sql already have the first migration and are marked as such
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL;").Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return tx.Commit()
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE TABLE schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+		);
+	`)
+	if err != nil {
+		return err
+	}
+
+	var legacy bool
+	err = tx.QueryRowContext(ctx, "SELECT to_regclass('users') IS NOT NULL;").Scan(&legacy)
+	if err != nil {
+		return err
+	}
+	if legacy && len(m.migrations) > 0 {
+		slog.Info(fmt.Sprintf("existing schema found, marking migration %d_%s as applied", m.migrations[0].Version, m.migrations[0].Name))
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version, name) VALUES ($1, $2);",
+			m.migrations[0].Version, m.migrations[0].Name,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	versions := make(map[int64]time.Time)
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+// apply runs the migration script and the schema_migrations bookkeeping in a
+// single transaction
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, bookkeeping, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,281 @@
+package migrations_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_tags.up.sql":   {Data: []byte("CREATE TABLE tags (tag TEXT);")},
+		"0002_add_tags.down.sql": {Data: []byte("DROP TABLE tags;")},
+		"0001_init.up.sql":       {Data: []byte("CREATE TABLE users (user_id INT);")},
+		"0001_init.down.sql":     {Data: []byte("DROP TABLE users;")},
+		"README.md":              {Data: []byte("ignored")},
+	}
+
+	migs, err := migrations.LoadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migs) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migs))
+	}
+	if migs[0].Version != 1 || migs[0].Name != "init" || migs[1].Version != 2 || migs[1].Name != "add_tags" {
+		t.Fatalf("unexpected order: %+v", migs)
+	}
+	if migs[1].Up != "CREATE TABLE tags (tag TEXT);" || migs[1].Down != "DROP TABLE tags;" {
+		t.Fatalf("unexpected scripts: %+v", migs[1])
+	}
+}
+
+func TestLoadMigrationsInvalid(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"bad name": {
+			"init.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"missing down": {
+			"0001_init.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"two names": {
+			"0001_init.up.sql":    {Data: []byte("SELECT 1;")},
+			"0001_other.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+
+	for name, fsys := range cases {
+		_, err := migrations.LoadMigrations(fsys)
+		if !errors.Is(err, common.ErrMigrationInvalid) {
+			t.Fatalf("%s: expected ErrMigrationInvalid, got %v", name, err)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	_, err := migrations.NewMigrator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunCommandUnknown(t *testing.T) {
+	m, err := migrations.NewMigrator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{}, {"drop"}, {migrations.COMMAND_ROLLBACK, "-1"}} {
+		err = migrations.RunCommand(context.Background(), m, args, &bytes.Buffer{})
+		if !errors.Is(err, common.ErrMigrationCommandUnknown) {
+			t.Fatalf("%v: expected ErrMigrationCommandUnknown, got %v", args, err)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	// the container is already migrated
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	m, err := migrations.NewMigrator(pgContainer.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("expected no pending migrations, got %d", len(applied))
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Fatalf("expected %d_%s to be applied", status.Version, status.Name)
+		}
+	}
+
+	rolledBack, err := m.Down(ctx, len(statuses)+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rolledBack) != len(statuses) {
+		t.Fatalf("expected %d rolled back, got %d", len(statuses), len(rolledBack))
+	}
+
+	var usersExists bool
+	err = pgContainer.DB.QueryRowContext(ctx, "SELECT to_regclass('users') IS NOT NULL;").Scan(&usersExists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usersExists {
+		t.Fatal("expected the users table to be dropped")
+	}
+
+	out := &bytes.Buffer{}
+	err = migrations.RunCommand(ctx, m, []string{migrations.COMMAND_MIGRATE}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "applied 1_init") {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+
+	out.Reset()
+	err = migrations.RunCommand(ctx, m, []string{migrations.COMMAND_STATUS}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "pending") {
+		t.Fatalf("expected no pending migrations: %s", out.String())
+	}
+}
+
+func TestMigratorBaseline(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	// a database created by the old init-db.sql has the schema but no
+	// schema_migrations table
+	_, err = pgContainer.DB.ExecContext(ctx, "DROP TABLE schema_migrations;")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// running the script would fail, the first migration has to be skipped
+	m, err := migrations.NewMigratorFs(pgContainer.DB, fstest.MapFS{
+		"0001_init.up.sql":   {Data: []byte("SELECT 1/0;")},
+		"0001_init.down.sql": {Data: []byte("SELECT 1/0;")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("expected no migrations applied, got %d", len(applied))
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].AppliedAt == nil {
+		t.Fatalf("expected the first migration to be marked as applied: %+v", statuses)
+	}
+}
+
+func TestMigratorLegacySchema(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	// start over from a database created by the init-db.sql the migrations
+	// replaced
+	_, err = pgContainer.DB.ExecContext(ctx, "DROP SCHEMA public CASCADE; CREATE SCHEMA public;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := os.ReadFile(filepath.Join("testdata", "init-db.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pgContainer.DB.ExecContext(ctx, string(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pgContainer.DB.ExecContext(ctx, `
+		WITH u AS (
+			INSERT INTO users (email, password_hash, first_name, last_name)
+			VALUES ('buyer@email.com', 'hashtest', 'Buyer', 'One')
+			RETURNING user_id
+		)
+		INSERT INTO payments (user_id, unit_ammount, unit_currency, stripe_checkout_session_id)
+		SELECT user_id, 300, 'brl', 'cs_test_legacy' FROM u;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := migrations.NewMigrator(pgContainer.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(statuses)-1 || applied[0].Version != 2 {
+		t.Fatalf("expected every migration but the first to be applied, got %d of %d", len(applied), len(statuses))
+	}
+
+	for _, table := range []string{"promo_codes", "email_outbox", "announcements", "notifications", "stream_events", "webhooks", "uploads", "event_attachments", "object_references"} {
+		var exists bool
+		err = pgContainer.DB.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL;", table).Scan(&exists)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("expected the %s table to exist", table)
+		}
+	}
+
+	// the stripe session ids are kept as checkouts of the stripe provider
+	var provider, checkoutId string
+	err = pgContainer.DB.QueryRowContext(ctx, "SELECT payment_provider, checkout_id FROM payments;").Scan(&provider, &checkoutId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider != "stripe" || checkoutId != "cs_test_legacy" {
+		t.Errorf("expected the stripe session to be kept, got %s %s", provider, checkoutId)
+	}
+}
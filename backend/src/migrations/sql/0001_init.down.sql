@@ -0,0 +1,12 @@
+DROP TABLE events;
+DROP TABLE payments;
+DROP TABLE oauth_users;
+DROP TABLE password_resets;
+DROP FUNCTION delete_expired_resets();
+DROP TABLE organization_invites;
+DROP FUNCTION delete_expired_invites();
+DROP TABLE organizations_users;
+DROP TABLE organizations;
+DROP TABLE unconfirmed_users;
+DROP TABLE users;
+DROP FUNCTION update_updated_at();
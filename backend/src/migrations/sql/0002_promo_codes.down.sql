@@ -0,0 +1,7 @@
+ALTER TABLE payments
+    DROP COLUMN promo_code_id,
+    DROP COLUMN event_id,
+    DROP COLUMN discount_ammount;
+
+DROP TABLE promo_code_events;
+DROP TABLE promo_codes;
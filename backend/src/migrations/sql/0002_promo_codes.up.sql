@@ -0,0 +1,35 @@
+ALTER TABLE payments
+    ADD COLUMN event_id UUID DEFAULT NULL,
+    ADD COLUMN promo_code_id INT DEFAULT NULL,
+    ADD COLUMN discount_ammount BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE payments
+    ADD CONSTRAINT fk_payments_event FOREIGN KEY (event_id) REFERENCES events (event_id);
+
+-- promo codes
+CREATE TABLE promo_codes (
+    promo_code_id SERIAL PRIMARY KEY,
+    organization_id CHAR(5) REFERENCES organizations (organization_id) NOT NULL,
+    code VARCHAR(50) NOT NULL,
+    discount_type TEXT CHECK (discount_type IN ('percentage', 'fixed')) NOT NULL,
+    discount_value BIGINT NOT NULL CHECK (discount_value > 0),
+    max_uses INT DEFAULT NULL,
+    uses INT NOT NULL DEFAULT 0,
+    exp TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+
+    UNIQUE (organization_id, code),
+    CHECK (discount_type <> 'percentage' OR discount_value <= 100),
+    CHECK (max_uses IS NULL OR uses <= max_uses)
+);
+
+-- empty means the code is valid for every event of the organization
+CREATE TABLE promo_code_events (
+    promo_code_id INT REFERENCES promo_codes (promo_code_id) ON DELETE CASCADE NOT NULL,
+    event_id UUID REFERENCES events (event_id) NOT NULL,
+
+    PRIMARY KEY (promo_code_id, event_id)
+);
+
+ALTER TABLE payments
+    ADD CONSTRAINT fk_payments_promo_code FOREIGN KEY (promo_code_id) REFERENCES promo_codes (promo_code_id) ON DELETE SET NULL;
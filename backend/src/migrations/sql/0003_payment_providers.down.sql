@@ -0,0 +1,9 @@
+-- checkouts of other providers lose their id
+UPDATE payments SET checkout_id = NULL WHERE payment_provider IS DISTINCT FROM 'stripe';
+
+ALTER TABLE payments
+    DROP CONSTRAINT payments_payment_provider_checkout_id_key,
+    DROP COLUMN payment_provider,
+    ALTER COLUMN checkout_id DROP DEFAULT;
+
+ALTER TABLE payments RENAME COLUMN checkout_id TO stripe_checkout_session_id;
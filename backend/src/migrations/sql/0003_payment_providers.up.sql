@@ -0,0 +1,11 @@
+-- checkouts were always stripe sessions until now
+ALTER TABLE payments RENAME COLUMN stripe_checkout_session_id TO checkout_id;
+
+ALTER TABLE payments
+    ALTER COLUMN checkout_id SET DEFAULT NULL,
+    ADD COLUMN payment_provider VARCHAR(20) DEFAULT NULL;
+
+UPDATE payments SET payment_provider = 'stripe' WHERE checkout_id IS NOT NULL;
+
+ALTER TABLE payments
+    ADD CONSTRAINT payments_payment_provider_checkout_id_key UNIQUE (payment_provider, checkout_id);
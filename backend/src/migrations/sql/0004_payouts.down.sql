@@ -0,0 +1,5 @@
+ALTER TABLE payments
+    DROP COLUMN fee_ammount;
+
+ALTER TABLE organizations
+    DROP COLUMN payout_account_id;
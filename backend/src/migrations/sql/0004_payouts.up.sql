@@ -0,0 +1,5 @@
+ALTER TABLE organizations
+    ADD COLUMN payout_account_id VARCHAR(255) DEFAULT NULL;
+
+ALTER TABLE payments
+    ADD COLUMN fee_ammount BIGINT NOT NULL DEFAULT 0;
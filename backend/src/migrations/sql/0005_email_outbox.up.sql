@@ -0,0 +1,16 @@
+-- email outbox, rows are queued in the same transaction as the change that
+-- triggers the email and sent by a daemon
+CREATE TABLE email_outbox (
+    email_id BIGSERIAL PRIMARY KEY,
+    recipient VARCHAR(100) NOT NULL,
+    template_name VARCHAR(50) NOT NULL,
+    template_vars JSONB NOT NULL DEFAULT '{}',
+    email_status TEXT CHECK (email_status IN ('pending', 'sent', 'dead')) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT DEFAULT NULL,
+    next_attempt_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    sent_at TIMESTAMPTZ DEFAULT NULL
+);
+
+CREATE INDEX email_outbox_due_idx ON email_outbox (next_attempt_at) WHERE email_status = 'pending';
@@ -0,0 +1,3 @@
+ALTER TABLE email_outbox DROP COLUMN locale;
+ALTER TABLE unconfirmed_users DROP COLUMN locale;
+ALTER TABLE users DROP COLUMN locale;
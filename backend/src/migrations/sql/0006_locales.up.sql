@@ -0,0 +1,8 @@
+ALTER TABLE users
+    ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT 'pt-BR';
+
+ALTER TABLE unconfirmed_users
+    ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT 'pt-BR';
+
+ALTER TABLE email_outbox
+    ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT 'pt-BR';
@@ -0,0 +1,11 @@
+DROP TABLE announcement_recipients;
+DROP TABLE announcements;
+
+DROP INDEX email_outbox_due_idx;
+CREATE INDEX email_outbox_due_idx ON email_outbox (next_attempt_at) WHERE email_status = 'pending';
+
+ALTER TABLE email_outbox DROP COLUMN priority;
+
+ALTER TABLE users
+    DROP COLUMN unsubscribe_token,
+    DROP COLUMN announcements_opt_out;
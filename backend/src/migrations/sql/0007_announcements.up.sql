@@ -0,0 +1,31 @@
+ALTER TABLE users
+    -- opt out of non-transactional emails, like announcements
+    ADD COLUMN announcements_opt_out BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN unsubscribe_token UUID UNIQUE NOT NULL DEFAULT gen_random_uuid();
+
+-- lower goes first, so bulk emails do not hold transactional ones back
+ALTER TABLE email_outbox
+    ADD COLUMN priority SMALLINT NOT NULL DEFAULT 0;
+
+DROP INDEX email_outbox_due_idx;
+CREATE INDEX email_outbox_due_idx ON email_outbox (priority, next_attempt_at) WHERE email_status = 'pending';
+
+-- announcements, emailed to the attendees of an event through the outbox
+CREATE TABLE announcements (
+    announcement_id BIGSERIAL PRIMARY KEY,
+    event_id UUID REFERENCES events (event_id) NOT NULL,
+    author_user_id INT REFERENCES users (user_id) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    audience TEXT CHECK (audience IN ('registrants')) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+-- email_id is NULL when the user opted out of announcements
+CREATE TABLE announcement_recipients (
+    announcement_id BIGINT REFERENCES announcements (announcement_id) ON DELETE CASCADE NOT NULL,
+    user_id INT REFERENCES users (user_id) NOT NULL,
+    email_id BIGINT REFERENCES email_outbox (email_id) DEFAULT NULL,
+
+    PRIMARY KEY (announcement_id, user_id)
+);
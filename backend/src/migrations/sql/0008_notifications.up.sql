@@ -0,0 +1,20 @@
+-- in-app notifications, payload holds the type specific data
+CREATE TABLE notifications (
+    notification_id BIGSERIAL PRIMARY KEY,
+    user_id INT REFERENCES users (user_id) NOT NULL,
+    notification_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX notifications_user_idx ON notifications (user_id, created_at DESC);
+
+-- no row means 'both'
+CREATE TABLE notification_preferences (
+    user_id INT REFERENCES users (user_id) NOT NULL,
+    notification_type VARCHAR(50) NOT NULL,
+    channel TEXT CHECK (channel IN ('in-app', 'email', 'both')) NOT NULL,
+
+    PRIMARY KEY (user_id, notification_type)
+);
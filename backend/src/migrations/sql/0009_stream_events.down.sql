@@ -0,0 +1 @@
+DROP TABLE stream_events;
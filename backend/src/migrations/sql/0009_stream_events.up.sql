@@ -0,0 +1,11 @@
+-- real-time events pushed by GET /v1/stream, the id is the SSE event id so
+-- clients can resume with Last-Event-ID, rows are cleaned by a daemon
+CREATE TABLE stream_events (
+    stream_event_id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(50) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX stream_events_created_at_idx ON stream_events (created_at);
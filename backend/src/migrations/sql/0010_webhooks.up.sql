@@ -0,0 +1,29 @@
+-- outgoing webhooks of the organizations, deliveries are queued in the same
+-- transaction as the change and sent by a daemon, the table is the delivery log
+CREATE TABLE webhooks (
+    webhook_id SERIAL PRIMARY KEY,
+    organization_id CHAR(5) REFERENCES organizations (organization_id) NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    event_types TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX webhooks_organization_idx ON webhooks (organization_id);
+
+CREATE TABLE webhook_deliveries (
+    delivery_id BIGSERIAL PRIMARY KEY,
+    webhook_id INT REFERENCES webhooks (webhook_id) ON DELETE CASCADE NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    delivery_status TEXT CHECK (delivery_status IN ('pending', 'delivered', 'dead')) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT DEFAULT NULL,
+    last_error TEXT DEFAULT NULL,
+    next_attempt_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    delivered_at TIMESTAMPTZ DEFAULT NULL
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE delivery_status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, created_at DESC);
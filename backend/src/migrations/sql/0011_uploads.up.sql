@@ -0,0 +1,13 @@
+-- direct to storage uploads, the browser PUTs to a presigned url and then
+-- confirms, target_id is the user or event the upload is for
+CREATE TABLE uploads (
+    upload_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id INT REFERENCES users (user_id) NOT NULL,
+    purpose TEXT CHECK (purpose IN ('avatar', 'event-banner')) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    exp TIMESTAMPTZ NOT NULL,
+    confirmed_at TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
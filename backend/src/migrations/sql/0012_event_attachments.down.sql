@@ -0,0 +1,4 @@
+DROP TABLE event_attachments;
+
+ALTER TABLE organizations
+    DROP COLUMN storage_quota_bytes;
@@ -0,0 +1,22 @@
+-- of the event attachments, NULL is the default quota
+ALTER TABLE organizations
+    ADD COLUMN storage_quota_bytes BIGINT DEFAULT NULL;
+
+-- files of events, the quota of the organization counts the confirmed ones
+-- and the ones still being uploaded
+CREATE TABLE event_attachments (
+    attachment_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID REFERENCES events (event_id) ON DELETE CASCADE NOT NULL,
+    organization_id CHAR(5) REFERENCES organizations (organization_id) NOT NULL,
+    uploaded_by INT REFERENCES users (user_id) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    visibility TEXT CHECK (visibility IN ('public', 'private')) NOT NULL,
+    exp TIMESTAMPTZ NOT NULL,
+    confirmed_at TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX event_attachments_organization_idx ON event_attachments (organization_id);
+CREATE INDEX event_attachments_event_idx ON event_attachments (event_id);
@@ -0,0 +1,12 @@
+-- the rows owning each object of the bucket, deleting the owner drops the
+-- reference and the garbage collector deletes the object later
+CREATE TABLE object_references (
+    object_path TEXT PRIMARY KEY,
+    user_id INT REFERENCES users (user_id) ON DELETE CASCADE DEFAULT NULL,
+    event_id UUID REFERENCES events (event_id) ON DELETE CASCADE DEFAULT NULL,
+    attachment_id UUID REFERENCES event_attachments (attachment_id) ON DELETE CASCADE DEFAULT NULL,
+    upload_id UUID REFERENCES uploads (upload_id) ON DELETE CASCADE DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+
+    CHECK (num_nonnulls(user_id, event_id, attachment_id, upload_id) = 1)
+);
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+type Announcement struct {
+	AnnouncementId uint64            `json:"announcementId"`
+	EventId        string            `json:"eventId"`
+	AuthorUserId   uint32            `json:"authorUserId"`
+	Subject        string            `json:"subject"`
+	Body           string            `json:"body"`
+	Audience       string            `json:"audience"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	Stats          AnnouncementStats `json:"stats"`
+}
+
+// Delivery of an announcement, by the status of its outbox emails. OptedOut
+// counts the recipients not emailed, either unsubscribed or notified in-app only
+type AnnouncementStats struct {
+	Recipients uint32 `json:"recipients"`
+	Pending    uint32 `json:"pending"`
+	Sent       uint32 `json:"sent"`
+	Dead       uint32 `json:"dead"`
+	OptedOut   uint32 `json:"optedOut"`
+}
+
+// EmailStatus is nil if the user opted out of announcements
+type AnnouncementRecipient struct {
+	UserId      uint32     `json:"userId"`
+	Email       string     `json:"email"`
+	FirstName   string     `json:"firstName"`
+	EmailStatus *string    `json:"emailStatus"`
+	SentAt      *time.Time `json:"sentAt"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type EventAttachment struct {
+	AttachmentId   string `json:"attachmentId"`
+	EventId        string `json:"eventId"`
+	OrganizationId string `json:"organizationId"`
+	UploadedBy     uint32 `json:"uploadedBy"`
+	FileName       string `json:"fileName"`
+	ContentType    string `json:"contentType"`
+	Size           int64  `json:"size"`
+	Visibility     string `json:"visibility"`
+	// only for the public ones, the private ones need a signed url
+	Url         *string    `json:"url"`
+	Exp         time.Time  `json:"-"`
+	ConfirmedAt *time.Time `json:"confirmedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type StorageUsage struct {
+	OrganizationId string `json:"organizationId"`
+	UsedBytes      int64  `json:"usedBytes"`
+	QuotaBytes     int64  `json:"quotaBytes"`
+}
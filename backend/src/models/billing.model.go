@@ -3,12 +3,29 @@ package models
 import "time"
 
 type Payment struct {
-	PaymentId               string     `json:"paymentId"`
-	UserId                  uint32     `json:"userId"`
-	UnitAmmount             uint32     `json:"unitAmmount"`
-	UnitCurrency            string     `json:"unitCurrency"`
-	PaymentStatus           string     `json:"paymentStatus"`
-	StripeCheckoutSessionId string     `json:"stripeCheckoutSessionId"`
-	CreatedAt               time.Time  `json:"createdAt"`
-	CompletedAt             *time.Time `json:"completedAt"`
+	PaymentId       string     `json:"paymentId"`
+	UserId          uint32     `json:"userId"`
+	UnitAmmount     uint32     `json:"unitAmmount"`
+	UnitCurrency    string     `json:"unitCurrency"`
+	PaymentStatus   string     `json:"paymentStatus"`
+	PaymentProvider *string    `json:"paymentProvider"`
+	CheckoutId      *string    `json:"checkoutId"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	CompletedAt     *time.Time `json:"completedAt"`
+	EventId         *string    `json:"eventId"`
+	PromoCodeId     *uint32    `json:"promoCodeId"`
+	DiscountAmmount uint32     `json:"discountAmmount"`
+	FeeAmmount      int64      `json:"feeAmmount"`
+}
+
+// Completed payments of an event, aggregated over a period
+type RevenueEntry struct {
+	EventId     string    `json:"eventId"`
+	EventName   string    `json:"eventName"`
+	PeriodStart time.Time `json:"periodStart"`
+	Currency    string    `json:"currency"`
+	Payments    uint32    `json:"payments"`
+	Gross       int64     `json:"gross"`
+	Fees        int64     `json:"fees"`
+	Net         int64     `json:"net"`
 }
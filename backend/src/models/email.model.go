@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type OutboxEmail struct {
+	EmailId       uint64          `json:"emailId"`
+	Recipient     string          `json:"recipient"`
+	Locale        string          `json:"locale"`
+	TemplateName  string          `json:"templateName"`
+	TemplateVars  json.RawMessage `json:"templateVars" swaggertype:"object"`
+	EmailStatus   string          `json:"emailStatus"`
+	Attempts      uint32          `json:"attempts"`
+	LastError     *string         `json:"lastError"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	SentAt        *time.Time      `json:"sentAt"`
+}
+
+type EmailTemplate struct {
+	TemplateName string         `json:"templateName"`
+	Locales      []string       `json:"locales"`
+	SampleVars   map[string]any `json:"sampleVars"`
+}
+
+type EmailPreview struct {
+	Subject string `json:"subject"`
+	Html    string `json:"html"`
+	Text    string `json:"text"`
+}
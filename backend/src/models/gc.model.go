@@ -0,0 +1,18 @@
+package models
+
+// The row owning an object, only one of the ids is set
+type ObjectReference struct {
+	ObjectPath   string  `json:"objectPath"`
+	UserId       *uint32 `json:"userId"`
+	EventId      *string `json:"eventId"`
+	AttachmentId *string `json:"attachmentId"`
+	UploadId     *string `json:"uploadId"`
+}
+
+// The outcome of a garbage collection, in dry-run nothing is deleted
+type GcReport struct {
+	Objects int      `json:"objects"`
+	Orphans []string `json:"orphans"`
+	Deleted int      `json:"deleted"`
+	DryRun  bool     `json:"dryRun"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// An encoded size of an image, `Name` is one of common.IMAGE_VARIANTS
+type ImageVariant struct {
+	Name        string
+	Width       int
+	Height      int
+	Ext         string
+	ContentType string
+	Data        []byte
+}
+
+type ImageSources struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Webp   string `json:"webp"`
+	Jpeg   string `json:"jpeg"`
+}
+
+// The urls of each variant of an image, by variant name, for building the
+// `srcset` of a <picture>
+type ImageSrcset map[string]ImageSources
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Notification struct {
+	NotificationId   uint64          `json:"notificationId"`
+	UserId           uint32          `json:"userId"`
+	NotificationType string          `json:"notificationType"`
+	Payload          json.RawMessage `json:"payload" swaggertype:"object"`
+	ReadAt           *time.Time      `json:"readAt"`
+	CreatedAt        time.Time       `json:"createdAt"`
+}
+
+type NotificationPreference struct {
+	NotificationType string `json:"notificationType"`
+	Channel          string `json:"channel"`
+}
+
+// Payload of the organization-invite notifications
+type OrganizationInviteNotification struct {
+	OrganizationId   string `json:"organizationId"`
+	OrganizationName string `json:"organizationName"`
+	IsAdmin          bool   `json:"isAdmin"`
+	AcceptUrl        string `json:"acceptUrl"`
+}
+
+// Payload of the payment-accepted notifications
+type PaymentAcceptedNotification struct {
+	PaymentId string  `json:"paymentId"`
+	EventId   *string `json:"eventId"`
+}
+
+// Payload of the event-announcement notifications
+type EventAnnouncementNotification struct {
+	AnnouncementId uint64 `json:"announcementId"`
+	EventId        string `json:"eventId"`
+	EventName      string `json:"eventName"`
+	Subject        string `json:"subject"`
+	Body           string `json:"body"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type ObjectInfo struct {
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType"`
+	ModifiedAt  time.Time `json:"modifiedAt"`
+}
+
+// A direct to storage upload, the object is at a private path until confirmed
+type Upload struct {
+	UploadId    string     `json:"uploadId"`
+	UserId      uint32     `json:"userId"`
+	Purpose     string     `json:"purpose"`
+	TargetId    string     `json:"targetId"`
+	ContentType string     `json:"contentType"`
+	Size        int64      `json:"size"`
+	Exp         time.Time  `json:"exp"`
+	ConfirmedAt *time.Time `json:"confirmedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
@@ -11,6 +11,7 @@ type Organization struct {
 	CreatedAt        time.Time  `json:"createdAt" binding:"required"`
 	DeletedAt        *time.Time `json:"deletedAt,omitempty"`
 	OwnerUserId      uint32     `json:"ownerUserId,omitempty"`
+	PayoutAccountId  *string    `json:"payoutAccountId,omitempty"`
 }
 
 type FrontendConfig struct {
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type PromoCode struct {
+	PromoCodeId    uint32     `json:"promoCodeId"`
+	OrganizationId string     `json:"organizationId"`
+	Code           string     `json:"code"`
+	DiscountType   string     `json:"discountType"`
+	DiscountValue  int64      `json:"discountValue"`
+	MaxUses        *uint32    `json:"maxUses"`
+	Uses           uint32     `json:"uses"`
+	Exp            *time.Time `json:"exp"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	EventIds       []string   `json:"eventIds"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type StreamEvent struct {
+	StreamEventId uint64          `json:"streamEventId"`
+	Topic         string          `json:"topic"`
+	EventType     string          `json:"eventType"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// Payload of the registrations stream events, sent to the organization of the
+// event every time a payment for it completes
+type RegistrationsStreamEvent struct {
+	EventId       string `json:"eventId"`
+	Registrations uint32 `json:"registrations"`
+}
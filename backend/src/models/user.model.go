@@ -14,6 +14,7 @@ type User struct {
 	CreatedAt *time.Time
 	UpdatedAt *time.Time
 	IsActive  bool
+	Locale    string
 }
 
 type UnconfirmedUser struct {
@@ -23,6 +24,7 @@ type UnconfirmedUser struct {
 	FirstName    string
 	LastName     string
 	DateOfBirth  *time.Time
+	Locale       string
 }
 
 // type User struct {
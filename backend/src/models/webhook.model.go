@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Webhook struct {
+	WebhookId      uint32 `json:"webhookId"`
+	OrganizationId string `json:"organizationId"`
+	Url            string `json:"url"`
+	// only returned on creation
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"eventTypes"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type WebhookDelivery struct {
+	DeliveryId     uint64          `json:"deliveryId"`
+	WebhookId      uint32          `json:"webhookId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	DeliveryStatus string          `json:"deliveryStatus"`
+	Attempts       uint32          `json:"attempts"`
+	ResponseStatus *int            `json:"responseStatus"`
+	LastError      *string         `json:"lastError"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+}
+
+// Body POSTed to the webhook Url, `data` depends on the `type`
+type WebhookEnvelope struct {
+	DeliveryId     uint64          `json:"deliveryId"`
+	Type           string          `json:"type"`
+	OrganizationId string          `json:"organizationId"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	Data           json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// Data of the registration.created webhooks
+type RegistrationWebhook struct {
+	EventId   string `json:"eventId"`
+	PaymentId string `json:"paymentId"`
+	UserId    uint32 `json:"userId"`
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
@@ -34,18 +34,17 @@ func (p *GithubProvider) GetAuthUrl() string {
 }
 
 func (p *GithubProvider) Auth(ctx context.Context, code string) (*User, error) {
-	token, err := p.Config.Exchange(ctx, code)
+	token, err := p.Config.Exchange(withHttpClient(ctx), code)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodGet, githubUserInfoRetrievalUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubUserInfoRetrievalUrl, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
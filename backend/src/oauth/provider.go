@@ -1,6 +1,11 @@
 package oauth
 
-import "context"
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/tracing"
+	"golang.org/x/oauth2"
+)
 
 const (
 	GOOGLE_PROVIDER string = "google"
@@ -11,3 +16,11 @@ type Provider interface {
 	GetAuthUrl() string
 	Auth(ctx context.Context, code string) (*User, error)
 }
+
+// the token exchange of oauth2 uses the client in the context, the user info
+// requests use it directly, so both are traced
+var httpClient = tracing.HttpClient()
+
+func withHttpClient(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+}
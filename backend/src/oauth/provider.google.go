@@ -11,7 +11,7 @@ import (
 )
 
 const (
-	googleUserInfoRetrievalUrl string = "https://www.googleapis.com/oauth2/v2/userinfo?access_token="
+	googleUserInfoRetrievalUrl string = "https://www.googleapis.com/oauth2/v2/userinfo"
 )
 
 type GoogleProvider struct {
@@ -33,12 +33,18 @@ func (p *GoogleProvider) GetAuthUrl() string {
 }
 
 func (p *GoogleProvider) Auth(ctx context.Context, code string) (*User, error) {
-	token, err := p.Config.Exchange(ctx, code)
+	token, err := p.Config.Exchange(withHttpClient(ctx), code)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := http.Get(googleUserInfoRetrievalUrl + token.AccessToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, googleUserInfoRetrievalUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	// in the header, the url is recorded in the traces
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
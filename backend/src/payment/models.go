@@ -0,0 +1,38 @@
+package payment
+
+type Currency string
+
+const (
+	CURRENCY_BRL Currency = "brl"
+	CURRENCY_USD Currency = "usd"
+)
+
+type CheckoutStatus string
+
+const (
+	CHECKOUT_STATUS_OPEN     CheckoutStatus = "open"
+	CHECKOUT_STATUS_COMPLETE CheckoutStatus = "complete"
+	CHECKOUT_STATUS_EXPIRED  CheckoutStatus = "expired"
+)
+
+type Checkout struct {
+	Id       string         `json:"id"`
+	Url      string         `json:"url"`
+	Status   CheckoutStatus `json:"status"`
+	Paid     bool           `json:"paid"`
+	Provider string         `json:"provider"`
+}
+
+type CheckoutParams struct {
+	ReferenceId   string
+	Currency      Currency
+	UnitAmmount   int64
+	ProductName   string
+	CustomerEmail string
+	SuccessUrl    string
+	CancelUrl     string
+
+	// Set to route the payment to the organization, see ConnectProvider
+	ConnectedAccountId *string
+	PlatformFeeAmmount int64
+}
@@ -0,0 +1,101 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+var ErrCheckoutNotFound = errors.New("checkoutNotFoundError")
+
+// FakeProvider keeps checkouts in memory, nothing is ever charged. Meant for tests
+// and local development, use SetPaid to simulate the customer paying.
+type FakeProvider struct {
+	mu        sync.Mutex
+	checkouts map[string]*Checkout
+	nextId    int
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		checkouts: make(map[string]*Checkout),
+	}
+}
+
+func (p *FakeProvider) GetName() string {
+	return FAKE_PROVIDER
+}
+
+func (p *FakeProvider) CreateCheckout(ctx context.Context, params CheckoutParams) (*Checkout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextId++
+	id := fmt.Sprintf("fake_%d", p.nextId)
+	p.checkouts[id] = &Checkout{
+		Id:       id,
+		Url:      params.SuccessUrl + "?checkout=" + id,
+		Status:   CHECKOUT_STATUS_OPEN,
+		Provider: FAKE_PROVIDER,
+	}
+
+	c := *p.checkouts[id]
+	return &c, nil
+}
+
+func (p *FakeProvider) GetCheckout(ctx context.Context, checkoutId string) (*Checkout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.checkouts[checkoutId]
+	if !ok {
+		return nil, ErrCheckoutNotFound
+	}
+
+	cp := *c
+	return &cp, nil
+}
+
+func (p *FakeProvider) ExpireCheckout(ctx context.Context, checkoutId string) (*Checkout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.checkouts[checkoutId]
+	if !ok {
+		return nil, ErrCheckoutNotFound
+	}
+	if c.Status != CHECKOUT_STATUS_OPEN {
+		return nil, fmt.Errorf("checkout %s is %s", checkoutId, c.Status)
+	}
+	c.Status = CHECKOUT_STATUS_EXPIRED
+
+	cp := *c
+	return &cp, nil
+}
+
+// ParseWebhook expects `{"checkoutId": "..."}`
+func (p *FakeProvider) ParseWebhook(payload []byte, header http.Header) (string, error) {
+	var notification struct {
+		CheckoutId string `json:"checkoutId"`
+	}
+	err := json.Unmarshal(payload, &notification)
+	return notification.CheckoutId, err
+}
+
+// SetPaid completes the checkout as if the customer had paid it
+func (p *FakeProvider) SetPaid(checkoutId string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.checkouts[checkoutId]
+	if !ok {
+		return ErrCheckoutNotFound
+	}
+	c.Status = CHECKOUT_STATUS_COMPLETE
+	c.Paid = true
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	STRIPE_PROVIDER      string = "stripe"
+	MERCADOPAGO_PROVIDER string = "mercadopago"
+	FAKE_PROVIDER        string = "fake"
+)
+
+type Provider interface {
+	GetName() string
+
+	// Creates the checkout the customer is redirected to, `params.ReferenceId` is our payment id
+	CreateCheckout(ctx context.Context, params CheckoutParams) (*Checkout, error)
+
+	GetCheckout(ctx context.Context, checkoutId string) (*Checkout, error)
+
+	// Expires an open checkout, so the customer can no longer pay it
+	ExpireCheckout(ctx context.Context, checkoutId string) (*Checkout, error)
+
+	// Gets the checkout id a webhook notification refers to, "" if the notification
+	// should be ignored. The checkout must still be fetched with GetCheckout, payloads
+	// are not trusted.
+	ParseWebhook(payload []byte, header http.Header) (string, error)
+}
+
+// ConnectProvider is implemented by providers able to route payments to an account
+// owned by the organization, keeping `CheckoutParams.PlatformFeeAmmount` for the platform.
+type ConnectProvider interface {
+	// Creates the account that will receive the payouts, returns its id
+	CreateConnectedAccount(ctx context.Context, email string) (string, error)
+
+	// Gets the Url where the organization fills in its payout details
+	GetOnboardingUrl(ctx context.Context, accountId string, refreshUrl string, returnUrl string) (string, error)
+}
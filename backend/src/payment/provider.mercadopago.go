@@ -0,0 +1,176 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/tracing"
+)
+
+const (
+	mercadoPagoApiUrl        string = "https://api.mercadopago.com"
+	mercadoPagoPixMethod     string = "pix"
+	mercadoPagoTimeFormat    string = "2006-01-02T15:04:05.000-07:00"
+	mercadoPagoPixExpiration        = 24 * time.Hour
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupportedCurrencyError")
+	ErrConnectUnsupported  = errors.New("connectUnsupportedError")
+)
+
+// MercadoPagoProvider charges through Pix using the Mercado Pago Payments API, the
+// checkout Url is the Mercado Pago page showing the Pix QR code.
+//
+// https://www.mercadopago.com.br/developers/en/docs/checkout-api/integration-configuration/integrate-with-pix
+type MercadoPagoProvider struct {
+	accessToken string
+	apiUrl      string
+	client      *http.Client
+}
+
+func NewMercadoPagoProvider(accessToken string) Provider {
+	return NewMercadoPagoProviderWithUrl(accessToken, mercadoPagoApiUrl)
+}
+
+// NewMercadoPagoProviderWithUrl is used to point the provider to another API, e.g. in tests
+func NewMercadoPagoProviderWithUrl(accessToken string, apiUrl string) Provider {
+	return &MercadoPagoProvider{
+		accessToken: accessToken,
+		apiUrl:      apiUrl,
+		client: &http.Client{
+			Transport: tracing.Transport(nil),
+			Timeout:   30 * time.Second,
+		},
+	}
+}
+
+func (p *MercadoPagoProvider) GetName() string {
+	return MERCADOPAGO_PROVIDER
+}
+
+func (p *MercadoPagoProvider) CreateCheckout(ctx context.Context, params CheckoutParams) (*Checkout, error) {
+	if params.Currency != CURRENCY_BRL {
+		return nil, ErrUnsupportedCurrency
+	}
+
+	// split payments need the organization's own Mercado Pago credentials
+	if params.ConnectedAccountId != nil {
+		return nil, ErrConnectUnsupported
+	}
+
+	body := mercadoPagoCreatePaymentSchema{
+		TransactionAmount: float64(params.UnitAmmount) / 100,
+		Description:       params.ProductName,
+		PaymentMethodId:   mercadoPagoPixMethod,
+		ExternalReference: params.ReferenceId,
+		DateOfExpiration:  time.Now().Add(mercadoPagoPixExpiration).Format(mercadoPagoTimeFormat),
+		Payer:             mercadoPagoPayerSchema{Email: params.CustomerEmail},
+	}
+
+	var mpPayment mercadoPagoPaymentSchema
+	// our payment id makes retries of the same checkout idempotent
+	err := p.do(ctx, http.MethodPost, "/v1/payments", params.ReferenceId, body, &mpPayment)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toCheckout(mpPayment), nil
+}
+
+func (p *MercadoPagoProvider) GetCheckout(ctx context.Context, checkoutId string) (*Checkout, error) {
+	var mpPayment mercadoPagoPaymentSchema
+	err := p.do(ctx, http.MethodGet, "/v1/payments/"+checkoutId, "", nil, &mpPayment)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toCheckout(mpPayment), nil
+}
+
+func (p *MercadoPagoProvider) ExpireCheckout(ctx context.Context, checkoutId string) (*Checkout, error) {
+	var mpPayment mercadoPagoPaymentSchema
+	err := p.do(ctx, http.MethodPut, "/v1/payments/"+checkoutId, "", map[string]string{"status": "cancelled"}, &mpPayment)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toCheckout(mpPayment), nil
+}
+
+func (p *MercadoPagoProvider) ParseWebhook(payload []byte, header http.Header) (string, error) {
+	var notification mercadoPagoWebhookSchema
+	err := json.Unmarshal(payload, &notification)
+	if err != nil {
+		return "", err
+	}
+
+	if notification.Type != "payment" {
+		return "", nil
+	}
+
+	return notification.Data.Id, nil
+}
+
+func (p *MercadoPagoProvider) do(ctx context.Context, method string, path string, idempotencyKey string, reqBody any, respBody any) error {
+	var body io.Reader
+	if reqBody != nil {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.apiUrl+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("X-Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("mercadopago %s %s: status %d: %s", method, path, resp.StatusCode, string(b))
+	}
+
+	return json.Unmarshal(b, respBody)
+}
+
+func (p *MercadoPagoProvider) toCheckout(mpPayment mercadoPagoPaymentSchema) *Checkout {
+	// https://www.mercadopago.com.br/developers/en/reference/payments/_payments_id/get
+	status := CHECKOUT_STATUS_OPEN
+	switch mpPayment.Status {
+	case "approved", "refunded", "charged_back":
+		status = CHECKOUT_STATUS_COMPLETE
+	case "cancelled", "rejected":
+		status = CHECKOUT_STATUS_EXPIRED
+	}
+
+	return &Checkout{
+		Id:       fmt.Sprint(mpPayment.Id),
+		Url:      mpPayment.PointOfInteraction.TransactionData.TicketUrl,
+		Status:   status,
+		Paid:     mpPayment.Status == "approved",
+		Provider: MERCADOPAGO_PROVIDER,
+	}
+}
@@ -0,0 +1,68 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMercadoPagoProvider_Checkout(t *testing.T) {
+	ctx := context.Background()
+
+	status := "pending"
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/payments", func(w http.ResponseWriter, r *http.Request) {
+		var body mercadoPagoCreatePaymentSchema
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.PaymentMethodId != "pix" || body.TransactionAmount != 300 || r.Header.Get("X-Idempotency-Key") != "payment-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"id": 42, "status": "pending", "point_of_interaction": {"transaction_data": {"ticket_url": "https://mp.test/pix/42"}}}`))
+	})
+	mux.HandleFunc("GET /v1/payments/42", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 42, "status": "` + status + `"}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	p := NewMercadoPagoProviderWithUrl("token", srv.URL)
+
+	_, err := p.CreateCheckout(ctx, CheckoutParams{ReferenceId: "payment-1", Currency: CURRENCY_USD, UnitAmmount: 30000})
+	if err != ErrUnsupportedCurrency {
+		t.Errorf("MercadoPagoProvider.CreateCheckout() error = %v, want %v", err, ErrUnsupportedCurrency)
+	}
+
+	checkout, err := p.CreateCheckout(ctx, CheckoutParams{ReferenceId: "payment-1", Currency: CURRENCY_BRL, UnitAmmount: 30000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkout.Id != "42" || checkout.Url != "https://mp.test/pix/42" || checkout.Status != CHECKOUT_STATUS_OPEN {
+		t.Errorf("MercadoPagoProvider.CreateCheckout() = %+v", checkout)
+	}
+
+	tests := []struct {
+		status     string
+		wantStatus CheckoutStatus
+		wantPaid   bool
+	}{
+		{"pending", CHECKOUT_STATUS_OPEN, false},
+		{"approved", CHECKOUT_STATUS_COMPLETE, true},
+		{"refunded", CHECKOUT_STATUS_COMPLETE, false},
+		{"cancelled", CHECKOUT_STATUS_EXPIRED, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			status = tt.status
+			checkout, err := p.GetCheckout(ctx, "42")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if checkout.Status != tt.wantStatus || checkout.Paid != tt.wantPaid {
+				t.Errorf("MercadoPagoProvider.GetCheckout() = %+v, want status %v paid %v", checkout, tt.wantStatus, tt.wantPaid)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/tracing"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/account"
+	"github.com/stripe/stripe-go/v81/accountlink"
+	"github.com/stripe/stripe-go/v81/checkout/session"
+)
+
+// same as the default client of stripe-go
+const stripeTimeout = 80 * time.Second
+
+// https://www.youtube.com/watch?v=M4aCgy67f243
+// https://www.youtube.com/playlist?list=PLy1nL-pvL2M5eqpSBR9KL7K0lcnWo0V0a
+// https://docs.stripe.com/payments/accept-a-payment?platform=web&ui=stripe-hosted
+// https://www.youtube.com/watch?v=ePmEVBu8w6Y
+
+type StripeProvider struct {
+	sessions     *session.Client
+	accounts     *account.Client
+	accountLinks *accountlink.Client
+}
+
+func NewStripeProvider(apiKey string) Provider {
+	backend := stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+		HTTPClient: &http.Client{
+			Transport: tracing.Transport(nil),
+			Timeout:   stripeTimeout,
+		},
+	})
+
+	return NewStripeProviderWithBackend(backend, apiKey)
+}
+
+// NewStripeProviderWithBackend is used to point the provider to another API, e.g. in tests
+func NewStripeProviderWithBackend(backend stripe.Backend, apiKey string) Provider {
+	return &StripeProvider{
+		sessions:     &session.Client{B: backend, Key: apiKey},
+		accounts:     &account.Client{B: backend, Key: apiKey},
+		accountLinks: &accountlink.Client{B: backend, Key: apiKey},
+	}
+}
+
+func (p *StripeProvider) GetName() string {
+	return STRIPE_PROVIDER
+}
+
+func (p *StripeProvider) CreateCheckout(ctx context.Context, params CheckoutParams) (*Checkout, error) {
+	sessionParams := &stripe.CheckoutSessionParams{
+		ClientReferenceID: stripe.String(params.ReferenceId),
+		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+					Currency: stripe.String(string(params.Currency)),
+					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+						Name: stripe.String(params.ProductName),
+					},
+					UnitAmount: stripe.Int64(params.UnitAmmount),
+				},
+				Quantity: stripe.Int64(1),
+			},
+		},
+		SuccessURL: stripe.String(params.SuccessUrl),
+		CancelURL:  stripe.String(params.CancelUrl),
+	}
+	sessionParams.Context = ctx
+
+	if params.CustomerEmail != "" {
+		sessionParams.CustomerEmail = stripe.String(params.CustomerEmail)
+	}
+
+	// destination charge, Stripe transfers everything but the fee to the organization
+	if params.ConnectedAccountId != nil {
+		sessionParams.PaymentIntentData = &stripe.CheckoutSessionPaymentIntentDataParams{
+			ApplicationFeeAmount: stripe.Int64(params.PlatformFeeAmmount),
+			TransferData: &stripe.CheckoutSessionPaymentIntentDataTransferDataParams{
+				Destination: params.ConnectedAccountId,
+			},
+		}
+	}
+
+	cs, err := p.sessions.New(sessionParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toCheckout(cs), nil
+}
+
+func (p *StripeProvider) GetCheckout(ctx context.Context, checkoutId string) (*Checkout, error) {
+	params := &stripe.CheckoutSessionParams{}
+	params.Context = ctx
+
+	cs, err := p.sessions.Get(checkoutId, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toCheckout(cs), nil
+}
+
+func (p *StripeProvider) ExpireCheckout(ctx context.Context, checkoutId string) (*Checkout, error) {
+	params := &stripe.CheckoutSessionExpireParams{}
+	params.Context = ctx
+
+	cs, err := p.sessions.Expire(checkoutId, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toCheckout(cs), nil
+}
+
+func (p *StripeProvider) ParseWebhook(payload []byte, header http.Header) (string, error) {
+	var stripeEvent stripe.Event
+	err := json.Unmarshal(payload, &stripeEvent)
+	if err != nil {
+		return "", err
+	}
+
+	switch stripeEvent.Type {
+	case stripe.EventTypeCheckoutSessionCompleted,
+		stripe.EventTypeCheckoutSessionAsyncPaymentSucceeded,
+		stripe.EventTypeCheckoutSessionExpired:
+		var cs stripe.CheckoutSession
+		err := json.Unmarshal(stripeEvent.Data.Raw, &cs)
+		if err != nil {
+			return "", err
+		}
+		return cs.ID, nil
+	default:
+		return "", nil
+	}
+}
+
+// https://docs.stripe.com/connect/express-accounts
+func (p *StripeProvider) CreateConnectedAccount(ctx context.Context, email string) (string, error) {
+	params := &stripe.AccountParams{
+		Type:  stripe.String(string(stripe.AccountTypeExpress)),
+		Email: stripe.String(email),
+		Capabilities: &stripe.AccountCapabilitiesParams{
+			CardPayments: &stripe.AccountCapabilitiesCardPaymentsParams{Requested: stripe.Bool(true)},
+			Transfers:    &stripe.AccountCapabilitiesTransfersParams{Requested: stripe.Bool(true)},
+		},
+	}
+	params.Context = ctx
+
+	acc, err := p.accounts.New(params)
+	if err != nil {
+		return "", err
+	}
+
+	return acc.ID, nil
+}
+
+func (p *StripeProvider) GetOnboardingUrl(ctx context.Context, accountId string, refreshUrl string, returnUrl string) (string, error) {
+	params := &stripe.AccountLinkParams{
+		Account:    stripe.String(accountId),
+		RefreshURL: stripe.String(refreshUrl),
+		ReturnURL:  stripe.String(returnUrl),
+		Type:       stripe.String("account_onboarding"),
+	}
+	params.Context = ctx
+
+	link, err := p.accountLinks.New(params)
+	if err != nil {
+		return "", err
+	}
+
+	return link.URL, nil
+}
+
+func (p *StripeProvider) toCheckout(cs *stripe.CheckoutSession) *Checkout {
+	status := CHECKOUT_STATUS_OPEN
+	switch cs.Status {
+	case stripe.CheckoutSessionStatusComplete:
+		status = CHECKOUT_STATUS_COMPLETE
+	case stripe.CheckoutSessionStatusExpired:
+		status = CHECKOUT_STATUS_EXPIRED
+	}
+
+	return &Checkout{
+		Id:       cs.ID,
+		Url:      cs.URL,
+		Status:   status,
+		Paid:     cs.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid,
+		Provider: STRIPE_PROVIDER,
+	}
+}
+
+// func (s *StripeProvider) GetClientSecret(ctx context.Context, currencyUnit stripe.Currency, unitAmmount int64, planName string) (string, error) {
+// 	panic("not impl")
+// 	params := &stripe.CheckoutSessionParams{
+// 		Mode:   stripe.String(string(stripe.CheckoutSessionModePayment)),
+// 		UIMode: stripe.String("embedded"),
+// 		LineItems: []*stripe.CheckoutSessionLineItemParams{
+// 			{
+// 				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+// 					Currency: stripe.String(string(currencyUnit)),
+// 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+// 						Name: stripe.String(planName),
+// 					},
+// 					UnitAmount: stripe.Int64(unitAmmount),
+// 				},
+// 				Quantity: stripe.Int64(1),
+// 			},
+// 		},
+// 		ReturnURL: stripe.String("https://example.com/checkout/return?session_id={CHECKOUT_SESSION_ID}"),
+// 	}
+
+// 	checkout, err := session.New(params)
+// 	if err != nil {
+// 		return "", err
+// 	}
+// 	return checkout.URL, nil
+// }
@@ -0,0 +1,81 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/stripe/stripe-go/v81"
+)
+
+func TestStripeProvider_ExpireCheckout(t *testing.T) {
+	ctx := context.Background()
+
+	fakeStripe := helpers.NewFakeStripeServer()
+	t.Cleanup(fakeStripe.Close)
+
+	fakeStripe.SetSession("cs_open", stripe.CheckoutSessionStatusOpen, stripe.CheckoutSessionPaymentStatusUnpaid)
+	fakeStripe.SetSession("cs_paid", stripe.CheckoutSessionStatusComplete, stripe.CheckoutSessionPaymentStatusPaid)
+
+	tests := []struct {
+		name       string
+		checkoutId string
+		wantErr    bool
+	}{
+		{"expire open session", "cs_open", false},
+		{"expire completed session", "cs_paid", true},
+		{"expire missing session", "cs_missing", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewStripeProviderWithBackend(fakeStripe.Backend(), "sk_test_fake")
+			checkout, err := p.ExpireCheckout(ctx, tt.checkoutId)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StripeProvider.ExpireCheckout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && checkout.Status != CHECKOUT_STATUS_EXPIRED {
+				t.Errorf("StripeProvider.ExpireCheckout() status = %v, want %v", checkout.Status, CHECKOUT_STATUS_EXPIRED)
+			}
+		})
+	}
+}
+
+func TestStripeProvider_ParseWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+		wantErr bool
+	}{
+		{
+			"checkout completed",
+			`{"type": "checkout.session.completed", "data": {"object": {"id": "cs_123"}}}`,
+			"cs_123",
+			false,
+		},
+		{
+			"unhandled event",
+			`{"type": "customer.created", "data": {"object": {"id": "cus_123"}}}`,
+			"",
+			false,
+		},
+		{
+			"invalid json",
+			`{`,
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &StripeProvider{}
+			got, err := p.ParseWebhook([]byte(tt.payload), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StripeProvider.ParseWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("StripeProvider.ParseWebhook() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package payment
+
+type mercadoPagoPayerSchema struct {
+	Email string `json:"email"`
+}
+
+type mercadoPagoCreatePaymentSchema struct {
+	TransactionAmount float64                `json:"transaction_amount"`
+	Description       string                 `json:"description"`
+	PaymentMethodId   string                 `json:"payment_method_id"`
+	ExternalReference string                 `json:"external_reference"`
+	DateOfExpiration  string                 `json:"date_of_expiration"`
+	Payer             mercadoPagoPayerSchema `json:"payer"`
+}
+
+type mercadoPagoPaymentSchema struct {
+	Id                 int64  `json:"id"`
+	Status             string `json:"status"`
+	StatusDetail       string `json:"status_detail"`
+	ExternalReference  string `json:"external_reference"`
+	PointOfInteraction struct {
+		TransactionData struct {
+			QrCode       string `json:"qr_code"`
+			QrCodeBase64 string `json:"qr_code_base64"`
+			TicketUrl    string `json:"ticket_url"`
+		} `json:"transaction_data"`
+	} `json:"point_of_interaction"`
+}
+
+type mercadoPagoWebhookSchema struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	Data   struct {
+		Id string `json:"id"`
+	} `json:"data"`
+}
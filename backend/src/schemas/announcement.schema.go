@@ -0,0 +1,11 @@
+package schemas
+
+type CreateAnnouncement struct {
+	Subject  string `json:"subject" binding:"required,max=255"`
+	Body     string `json:"body" binding:"required,max=10000"`
+	Audience string `json:"audience" binding:"required,oneof=registrants" example:"registrants"`
+}
+
+type AnnouncementsSubscription struct {
+	Subscribed *bool `json:"subscribed" binding:"required"`
+}
@@ -0,0 +1,13 @@
+package schemas
+
+type CreateAttachment struct {
+	FileName    string `json:"fileName" binding:"required,max=255"`
+	ContentType string `json:"contentType" binding:"required"`
+	Size        int64  `json:"size" binding:"required,gt=0"`
+	Visibility  string `json:"visibility" binding:"required,oneof=public private"`
+}
+
+type SetStorageQuota struct {
+	// null goes back to the default quota
+	QuotaBytes *int64 `json:"quotaBytes" binding:"omitempty,gte=0"`
+}
@@ -0,0 +1,11 @@
+package schemas
+
+type EmailPreview struct {
+	Locale string         `json:"locale" binding:"omitempty,oneof=pt-BR en" example:"pt-BR"`
+	Vars   map[string]any `json:"vars"`
+}
+
+type EmailPreviewQuery struct {
+	Locale string `form:"locale" binding:"omitempty,oneof=pt-BR en"`
+	Format string `form:"format" binding:"omitempty,oneof=html text"`
+}
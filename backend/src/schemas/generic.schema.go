@@ -1,5 +1,7 @@
 package schemas
 
+import "time"
+
 type Id struct {
 	Id string `json:"id" binding:"required"`
 }
@@ -15,3 +17,27 @@ type Name struct {
 type UploadPicture struct {
 	Content string `json:"content" binding:"required" example:"base64 encoded string"`
 }
+
+type CreateUpload struct {
+	ContentType string `json:"contentType" binding:"required,oneof=image/png image/jpeg" example:"image/png"`
+	Size        int64  `json:"size" binding:"required,gt=0" example:"102400"`
+}
+
+// The object is PUT to `url`, with the declared Content-Type, before `exp`
+type UploadUrl struct {
+	UploadId string    `json:"uploadId"`
+	Url      string    `json:"url"`
+	Method   string    `json:"method" example:"PUT"`
+	Exp      time.Time `json:"exp"`
+}
+
+type Period struct {
+	Period string     `form:"period" binding:"omitempty,oneof=day week month year"`
+	From   *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00" example:"2006-01-02T15:04:05-07:00"`
+	To     *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00" example:"2006-01-02T15:04:05-07:00"`
+}
+
+type Page struct {
+	Limit  int `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
@@ -0,0 +1,15 @@
+package schemas
+
+type NotificationsQuery struct {
+	Page
+	Unread bool `form:"unread"`
+}
+
+type NotificationPreference struct {
+	NotificationType string `json:"notificationType" binding:"required,oneof=organization-invite payment-accepted event-announcement" example:"event-announcement"`
+	Channel          string `json:"channel" binding:"required,oneof=in-app email both" example:"both"`
+}
+
+type Count struct {
+	Count uint32 `json:"count"`
+}
@@ -0,0 +1,23 @@
+package schemas
+
+// RFC 7807 problem details, every error response is served as
+// application/problem+json with this body
+type Problem struct {
+	Type      string       `json:"type" example:"about:blank"`
+	Title     string       `json:"title" example:"Not Found"`
+	Status    int          `json:"status" example:"404"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty" example:"/v1/events/0d5e3b4c-97a8-4a63-b3c4-5a5f3f6b0e7d"`
+	Code      string       `json:"code" example:"notFound"`
+	RequestId string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// The field that failed validation, named as in the request
+type FieldError struct {
+	Field string `json:"field" example:"email"`
+	// the failed binding rule, like required, max or oneof
+	Rule    string `json:"rule" example:"required"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message" example:"email is required"`
+}
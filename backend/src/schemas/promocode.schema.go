@@ -0,0 +1,12 @@
+package schemas
+
+import "time"
+
+type CreatePromoCode struct {
+	Code          string     `json:"code" binding:"required,max=50"`
+	DiscountType  string     `json:"discountType" binding:"required,oneof=percentage fixed"`
+	DiscountValue int64      `json:"discountValue" binding:"required,gt=0"`
+	MaxUses       *uint32    `json:"maxUses" binding:"omitempty,gt=0"`
+	Exp           *time.Time `json:"exp" example:"2006-01-02T15:04:05-07:00"`
+	EventIds      []string   `json:"eventIds"`
+}
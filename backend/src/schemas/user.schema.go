@@ -8,6 +8,7 @@ type CreateUser struct {
 	FirstName   string     `json:"firstName" binding:"required"`
 	LastName    string     `json:"lastName" binding:"required"`
 	DateOfBirth *time.Time `json:"dateOfBirth" example:"2006-01-02T15:04:05-07:00"`
+	Locale      string     `json:"locale" binding:"omitempty,oneof=pt-BR en" example:"pt-BR"`
 }
 
 type EditUser struct {
@@ -15,3 +16,7 @@ type EditUser struct {
 	LastName    string     `json:"lastName" binding:"required"`
 	DateOfBirth *time.Time `json:"dateOfBirth" example:"2006-01-02T15:04:05-07:00"`
 }
+
+type Locale struct {
+	Locale string `json:"locale" binding:"required,oneof=pt-BR en" example:"pt-BR"`
+}
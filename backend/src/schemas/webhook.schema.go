@@ -0,0 +1,6 @@
+package schemas
+
+type CreateWebhook struct {
+	Url        string   `json:"url" binding:"required,http_url,max=2048" example:"https://example.com/webhooks/patos"`
+	EventTypes []string `json:"eventTypes" binding:"required,min=1,dive,oneof=registration.created payment.completed event.published" example:"registration.created"`
+}
@@ -0,0 +1,14 @@
+package services
+
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type AnnouncementService interface {
+	// Queues the announcement email to its audience, skipping who opted out
+	CreateAnnouncement(ctx context.Context, orgId string, announcement models.Announcement) (models.Announcement, error)
+	GetAnnouncements(ctx context.Context, orgId string, eventId string) ([]models.Announcement, error)
+	GetAnnouncementRecipients(ctx context.Context, orgId string, eventId string, announcementId uint64) ([]models.AnnouncementRecipient, error)
+}
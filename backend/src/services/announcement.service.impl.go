@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type AnnouncementServicePgImpl struct {
+	db *sql.DB
+
+	unsubscribeUrl string
+}
+
+func NewAnnouncementServicePgImpl(db *sql.DB) AnnouncementService {
+	unsubscribeUrl, err := url.JoinPath(common.API_HOST_URL, "/v1/users/unsubscribe")
+	if err != nil {
+		panic(err)
+	}
+
+	return &AnnouncementServicePgImpl{
+		db:             db,
+		unsubscribeUrl: unsubscribeUrl,
+	}
+}
+
+type announcementRecipient struct {
+	userId           uint32
+	email            string
+	locale           string
+	firstName        string
+	optedOut         bool
+	unsubscribeToken string
+}
+
+func (s *AnnouncementServicePgImpl) CreateAnnouncement(ctx context.Context, orgId string, announcement models.Announcement) (models.Announcement, error) {
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return announcement, err
+	}
+	defer tx.Rollback()
+
+	// only the organization of the event can announce to it
+	var eventName string
+	err = tx.QueryRowContext(ctx, `
+		SELECT event_name
+		FROM events
+		WHERE event_id = $1 AND owner_organization_id = $2;
+		`,
+		announcement.EventId,
+		orgId,
+	).Scan(&eventName)
+	if err != nil {
+		return announcement, common.FilterSqlPgError(err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO announcements (event_id, author_user_id, subject, body, audience)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING announcement_id, created_at;
+		`,
+		announcement.EventId,
+		announcement.AuthorUserId,
+		announcement.Subject,
+		announcement.Body,
+		announcement.Audience,
+	).Scan(
+		&announcement.AnnouncementId,
+		&announcement.CreatedAt,
+	)
+	if err != nil {
+		return announcement, common.FilterSqlPgError(err)
+	}
+
+	recipients, err := s.getAudience(ctx, tx, announcement.EventId, announcement.Audience)
+	if err != nil {
+		return announcement, err
+	}
+
+	announcement.Stats = models.AnnouncementStats{}
+	for _, r := range recipients {
+		sendEmail, err := notifyUser(ctx, tx, r.userId, common.NOTIFICATION_TYPE_EVENT_ANNOUNCEMENT, models.EventAnnouncementNotification{
+			AnnouncementId: announcement.AnnouncementId,
+			EventId:        announcement.EventId,
+			EventName:      eventName,
+			Subject:        announcement.Subject,
+			Body:           announcement.Body,
+		})
+		if err != nil {
+			return announcement, err
+		}
+
+		var emailId *uint64
+		if sendEmail && !r.optedOut {
+			id, err := enqueueEmail(ctx, tx, common.EMAIL_PRIORITY_BULK, r.email, r.locale, common.EMAIL_TEMPLATE_ANNOUNCEMENT, htmlAnnouncementVars{
+				FirstName:      r.firstName,
+				EventName:      eventName,
+				Subject:        announcement.Subject,
+				Body:           announcement.Body,
+				UnsubscribeUrl: s.unsubscribeUrl + "?token=" + r.unsubscribeToken,
+			})
+			if err != nil {
+				return announcement, err
+			}
+			emailId = &id
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO announcement_recipients (announcement_id, user_id, email_id)
+			VALUES ($1, $2, $3);
+			`,
+			announcement.AnnouncementId,
+			r.userId,
+			emailId,
+		)
+		if err != nil {
+			return announcement, err
+		}
+
+		announcement.Stats.Recipients++
+		if emailId == nil {
+			announcement.Stats.OptedOut++
+		} else {
+			announcement.Stats.Pending++
+		}
+	}
+
+	return announcement, tx.Commit()
+}
+
+// The users an announcement of the event goes to, the registrants are the
+// users with a complete payment for it
+func (s *AnnouncementServicePgImpl) getAudience(ctx context.Context, tx Querier, eventId string, audience string) ([]announcementRecipient, error) {
+	recipients := []announcementRecipient{}
+
+	if audience != common.ANNOUNCEMENT_AUDIENCE_REGISTRANTS {
+		return recipients, common.ErrAnnouncementAudienceInvalid
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT
+			u.user_id,
+			u.email,
+			u.locale,
+			u.first_name,
+			u.announcements_opt_out,
+			u.unsubscribe_token
+		FROM payments p
+		INNER JOIN users u ON u.user_id = p.user_id
+		WHERE
+			p.event_id = $1 AND
+			p.payment_status = 'complete' AND
+			u.is_active;
+		`,
+		eventId,
+	)
+	if err != nil {
+		return recipients, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r := announcementRecipient{}
+		err := rows.Scan(&r.userId, &r.email, &r.locale, &r.firstName, &r.optedOut, &r.unsubscribeToken)
+		if err != nil {
+			return recipients, err
+		}
+		recipients = append(recipients, r)
+	}
+
+	return recipients, rows.Err()
+}
+
+func (s *AnnouncementServicePgImpl) GetAnnouncements(ctx context.Context, orgId string, eventId string) ([]models.Announcement, error) {
+	announcements := []models.Announcement{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT
+			a.announcement_id,
+			a.event_id,
+			a.author_user_id,
+			a.subject,
+			a.body,
+			a.audience,
+			a.created_at,
+			COUNT(r.user_id),
+			COUNT(*) FILTER (WHERE o.email_status = $3),
+			COUNT(*) FILTER (WHERE o.email_status = $4),
+			COUNT(*) FILTER (WHERE o.email_status = $5),
+			COUNT(*) FILTER (WHERE r.user_id IS NOT NULL AND r.email_id IS NULL)
+		FROM announcements a
+		INNER JOIN events e ON e.event_id = a.event_id
+		LEFT JOIN announcement_recipients r ON r.announcement_id = a.announcement_id
+		LEFT JOIN email_outbox o ON o.email_id = r.email_id
+		WHERE
+			a.event_id = $1 AND
+			e.owner_organization_id = $2
+		GROUP BY a.announcement_id
+		ORDER BY a.created_at DESC;
+		`,
+		eventId,
+		orgId,
+		common.EMAIL_STATUS_PENDING,
+		common.EMAIL_STATUS_SENT,
+		common.EMAIL_STATUS_DEAD,
+	)
+	if err != nil {
+		return announcements, common.FilterSqlPgError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := models.Announcement{}
+		err := rows.Scan(
+			&a.AnnouncementId,
+			&a.EventId,
+			&a.AuthorUserId,
+			&a.Subject,
+			&a.Body,
+			&a.Audience,
+			&a.CreatedAt,
+			&a.Stats.Recipients,
+			&a.Stats.Pending,
+			&a.Stats.Sent,
+			&a.Stats.Dead,
+			&a.Stats.OptedOut,
+		)
+		if err != nil {
+			return announcements, err
+		}
+		announcements = append(announcements, a)
+	}
+
+	return announcements, rows.Err()
+}
+
+func (s *AnnouncementServicePgImpl) GetAnnouncementRecipients(ctx context.Context, orgId string, eventId string, announcementId uint64) ([]models.AnnouncementRecipient, error) {
+	recipients := []models.AnnouncementRecipient{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT
+			u.user_id,
+			u.email,
+			u.first_name,
+			o.email_status,
+			o.sent_at
+		FROM announcement_recipients r
+		INNER JOIN announcements a ON a.announcement_id = r.announcement_id
+		INNER JOIN events e ON e.event_id = a.event_id
+		INNER JOIN users u ON u.user_id = r.user_id
+		LEFT JOIN email_outbox o ON o.email_id = r.email_id
+		WHERE
+			r.announcement_id = $1 AND
+			a.event_id = $2 AND
+			e.owner_organization_id = $3
+		ORDER BY u.user_id;
+		`,
+		announcementId,
+		eventId,
+		orgId,
+	)
+	if err != nil {
+		return recipients, common.FilterSqlPgError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r := models.AnnouncementRecipient{}
+		err := rows.Scan(&r.UserId, &r.Email, &r.FirstName, &r.EmailStatus, &r.SentAt)
+		if err != nil {
+			return recipients, err
+		}
+		recipients = append(recipients, r)
+	}
+
+	return recipients, rows.Err()
+}
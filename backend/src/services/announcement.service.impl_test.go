@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestAnnouncementServicePgImpl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	db := pgContainer.DB
+	userService := &UserServicePgImpl{db: db}
+
+	users := map[string]models.User{}
+	for _, email := range []string{"owner@email.com", "paid@email.com", "optout@email.com", "pending@email.com"} {
+		err = userService.CreateUser(ctx, models.User{Email: email, PasswordHash: "hashtest", FirstName: "Test", LastName: "User"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[email], err = userService.GetUser(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var eventId string
+	err = db.QueryRowContext(ctx, `
+		WITH org AS (
+			INSERT INTO organizations (organization_id, organization_name, owner_user_id)
+			VALUES ('ORG01', 'Patos', $1)
+			RETURNING organization_id
+		)
+		INSERT INTO events (event_name, owner_user_id, owner_organization_id, event_description)
+		SELECT 'Quack Week', $1, organization_id, '' FROM org
+		RETURNING event_id;
+	`, users["owner@email.com"].UserId).Scan(&eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payments := map[string]string{"paid@email.com": "complete", "optout@email.com": "complete", "pending@email.com": "pending"}
+	for email, status := range payments {
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO payments (user_id, unit_ammount, unit_currency, payment_status, event_id)
+			VALUES ($1, 300, 'BRL', $2, $3);
+		`, users[email].UserId, status, eventId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var token string
+	err = db.QueryRowContext(ctx, `SELECT unsubscribe_token FROM users WHERE email = 'optout@email.com';`).Scan(&token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = userService.Unsubscribe(ctx, token)
+	if err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+	err = userService.Unsubscribe(ctx, "not-a-token")
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("Unsubscribe() unknown token error = %v, want %v", err, common.ErrNotFound)
+	}
+
+	s := NewAnnouncementServicePgImpl(db)
+
+	announcement := models.Announcement{
+		EventId:      eventId,
+		AuthorUserId: users["owner@email.com"].UserId,
+		Subject:      "Room change",
+		Body:         "Room 42",
+		Audience:     common.ANNOUNCEMENT_AUDIENCE_REGISTRANTS,
+	}
+
+	_, err = s.CreateAnnouncement(ctx, "OTHER", announcement)
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("CreateAnnouncement() from another organization error = %v, want %v", err, common.ErrNotFound)
+	}
+
+	created, err := s.CreateAnnouncement(ctx, "ORG01", announcement)
+	if err != nil {
+		t.Fatalf("CreateAnnouncement() error = %v", err)
+	}
+	wantStats := models.AnnouncementStats{Recipients: 2, Pending: 1, OptedOut: 1}
+	if created.Stats != wantStats {
+		t.Errorf("CreateAnnouncement() stats = %+v, want %+v", created.Stats, wantStats)
+	}
+
+	var priority int
+	err = db.QueryRowContext(ctx, `
+		SELECT priority FROM email_outbox WHERE recipient = 'paid@email.com' AND template_name = $1;
+	`, common.EMAIL_TEMPLATE_ANNOUNCEMENT).Scan(&priority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priority != common.EMAIL_PRIORITY_BULK {
+		t.Errorf("announcement email priority = %d, want %d", priority, common.EMAIL_PRIORITY_BULK)
+	}
+
+	announcements, err := s.GetAnnouncements(ctx, "ORG01", eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(announcements) != 1 || announcements[0].Stats != wantStats {
+		t.Errorf("GetAnnouncements() = %+v, want one with stats %+v", announcements, wantStats)
+	}
+
+	recipients, err := s.GetAnnouncementRecipients(ctx, "ORG01", eventId, created.AnnouncementId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) != 2 {
+		t.Fatalf("GetAnnouncementRecipients() len = %d, want 2", len(recipients))
+	}
+	for _, r := range recipients {
+		optedOut := r.Email == "optout@email.com"
+		if optedOut != (r.EmailStatus == nil) {
+			t.Errorf("recipient %s email status = %v", r.Email, r.EmailStatus)
+		}
+	}
+
+	other, err := s.GetAnnouncements(ctx, "OTHER", eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other) != 0 {
+		t.Errorf("GetAnnouncements() from another organization len = %d, want 0", len(other))
+	}
+}
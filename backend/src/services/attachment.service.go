@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type AttachmentService interface {
+	// Reserves the quota of the organization for the attachment and returns the
+	// url the browser PUTs the file to
+	CreateAttachment(ctx context.Context, attachment models.EventAttachment) (models.EventAttachment, string, error)
+	ConfirmAttachment(ctx context.Context, orgId string, eventId string, attachmentId string) (models.EventAttachment, error)
+	// Gets the confirmed attachments of the event, the private ones only with `includePrivate`
+	GetAttachments(ctx context.Context, eventId string, includePrivate bool) ([]models.EventAttachment, error)
+	// Returns common.ErrAuth if the user is neither of the organization of the
+	// event nor registered to it
+	GetDownloadUrl(ctx context.Context, userId uint32, orgId *string, eventId string, attachmentId string) (string, error)
+	// Only admins delete the attachments of others
+	DeleteAttachment(ctx context.Context, orgId string, attachmentId string, userId uint32, isAdmin bool) error
+	GetStorageUsage(ctx context.Context, orgId string) (models.StorageUsage, error)
+	SetStorageQuota(ctx context.Context, orgId string, quota *int64) error
+	DeleteExpiredAttachments() error
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+const attachmentColumns = `
+	attachment_id,
+	event_id,
+	organization_id,
+	uploaded_by,
+	file_name,
+	content_type,
+	size,
+	visibility,
+	exp,
+	confirmed_at,
+	created_at`
+
+func scanAttachment(row interface{ Scan(...any) error }) (models.EventAttachment, error) {
+	a := models.EventAttachment{}
+	err := row.Scan(
+		&a.AttachmentId,
+		&a.EventId,
+		&a.OrganizationId,
+		&a.UploadedBy,
+		&a.FileName,
+		&a.ContentType,
+		&a.Size,
+		&a.Visibility,
+		&a.Exp,
+		&a.ConfirmedAt,
+		&a.CreatedAt,
+	)
+	return a, err
+}
+
+// The used storage counts the uploads still in progress, so parallel ones
+// cannot go over the quota together
+const storageUsageQuery = `
+	SELECT
+		COALESCE(o.storage_quota_bytes, $2),
+		COALESCE((
+			SELECT SUM(a.size)
+			FROM event_attachments a
+			WHERE
+				a.organization_id = o.organization_id AND
+				(a.confirmed_at IS NOT NULL OR a.exp > NOW())
+		), 0)
+	FROM organizations o
+	WHERE o.organization_id = $1`
+
+type AttachmentServicePgImpl struct {
+	db         *sql.DB
+	objService ObjectService
+	bucket     string
+}
+
+func NewAttachmentServicePgImpl(db *sql.DB, objService ObjectService, bucket string) AttachmentService {
+	return &AttachmentServicePgImpl{
+		db:         db,
+		objService: objService,
+		bucket:     bucket,
+	}
+}
+
+func (s *AttachmentServicePgImpl) setUrl(a *models.EventAttachment) error {
+	if a.Visibility != common.ATTACHMENT_VISIBILITY_PUBLIC {
+		return nil
+	}
+
+	objUrl, err := storage.GetFullObjUrl(fiddlers.GetAttachmentPath(a.Visibility, a.EventId, a.AttachmentId))
+	if err != nil {
+		return err
+	}
+	a.Url = &objUrl
+
+	return nil
+}
+
+func (s *AttachmentServicePgImpl) CreateAttachment(ctx context.Context, attachment models.EventAttachment) (models.EventAttachment, string, error) {
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return attachment, "", err
+	}
+	defer tx.Rollback()
+
+	// the organization is locked until the reservation is committed
+	_, err = tx.ExecContext(ctx, `
+		SELECT 1
+		FROM organizations
+		WHERE organization_id = $1
+		FOR UPDATE;
+		`,
+		attachment.OrganizationId,
+	)
+	if err != nil {
+		return attachment, "", err
+	}
+
+	var quota, used int64
+	err = tx.QueryRowContext(ctx, storageUsageQuery+`;`,
+		attachment.OrganizationId,
+		common.ATTACHMENT_DEFAULT_QUOTA_BYTES,
+	).Scan(
+		&quota,
+		&used,
+	)
+	if err != nil {
+		return attachment, "", common.FilterSqlPgError(err)
+	}
+
+	err = fiddlers.ValidateAttachmentRequest(attachment.ContentType, attachment.Size, used, quota)
+	if err != nil {
+		return attachment, "", err
+	}
+
+	expIn := time.Duration(common.ATTACHMENT_URL_EXP_MINS) * time.Minute
+	attachment.Exp = time.Now().Add(expIn)
+
+	// only to events of the organization
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO event_attachments
+			(event_id, organization_id, uploaded_by, file_name, content_type, size, visibility, exp)
+		SELECT
+			event_id, owner_organization_id, $3::INT, $4, $5, $6::BIGINT, $7, $8::TIMESTAMPTZ
+		FROM events
+		WHERE
+			event_id = $1 AND
+			owner_organization_id = $2
+		RETURNING
+			attachment_id,
+			created_at;
+		`,
+		attachment.EventId,
+		attachment.OrganizationId,
+		attachment.UploadedBy,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.Visibility,
+		attachment.Exp,
+	).Scan(
+		&attachment.AttachmentId,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return attachment, "", common.FilterSqlPgError(err)
+	}
+
+	objPath := fiddlers.GetAttachmentPath(attachment.Visibility, attachment.EventId, attachment.AttachmentId)
+	err = referenceObjects(ctx, tx, models.ObjectReference{AttachmentId: &attachment.AttachmentId}, objPath)
+	if err != nil {
+		return attachment, "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return attachment, "", err
+	}
+
+	uploadUrl, err := s.objService.UploadUrl(ctx, s.bucket, objPath, int64(attachment.Size), expIn)
+	if err != nil {
+		return attachment, "", err
+	}
+
+	return attachment, uploadUrl, nil
+}
+
+func (s *AttachmentServicePgImpl) ConfirmAttachment(ctx context.Context, orgId string, eventId string, attachmentId string) (models.EventAttachment, error) {
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return models.EventAttachment{}, err
+	}
+	defer tx.Rollback()
+
+	attachment, err := scanAttachment(tx.QueryRowContext(ctx, `
+		SELECT`+attachmentColumns+`
+		FROM event_attachments
+		WHERE
+			attachment_id = $1 AND
+			organization_id = $2 AND
+			event_id = $3 AND
+			confirmed_at IS NULL AND
+			exp > NOW()
+		FOR UPDATE;
+		`,
+		attachmentId,
+		orgId,
+		eventId,
+	))
+	if err != nil {
+		return attachment, common.FilterSqlPgError(err)
+	}
+
+	objPath := fiddlers.GetAttachmentPath(attachment.Visibility, attachment.EventId, attachment.AttachmentId)
+	info, err := s.objService.Stat(ctx, s.bucket, objPath)
+	if err != nil {
+		return attachment, err
+	}
+	// before downloading it, the object could be anything
+	if info.Size != attachment.Size {
+		return attachment, common.ErrUploadMismatch
+	}
+
+	data, err := s.objService.Download(ctx, s.bucket, objPath)
+	if err != nil {
+		return attachment, err
+	}
+
+	err = fiddlers.ValidateUploadedAttachment(attachment, info, data)
+	if err != nil {
+		return attachment, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE event_attachments
+		SET confirmed_at = NOW()
+		WHERE attachment_id = $1
+		RETURNING confirmed_at;
+		`,
+		attachment.AttachmentId,
+	).Scan(
+		&attachment.ConfirmedAt,
+	)
+	if err != nil {
+		return attachment, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return attachment, err
+	}
+
+	return attachment, s.setUrl(&attachment)
+}
+
+func (s *AttachmentServicePgImpl) GetAttachments(ctx context.Context, eventId string, includePrivate bool) ([]models.EventAttachment, error) {
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT`+attachmentColumns+`
+		FROM event_attachments
+		WHERE
+			event_id = $1 AND
+			confirmed_at IS NOT NULL AND
+			($2 OR visibility = 'public')
+		ORDER BY created_at;
+		`,
+		eventId,
+		includePrivate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.EventAttachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.setUrl(&a)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (s *AttachmentServicePgImpl) GetDownloadUrl(ctx context.Context, userId uint32, orgId *string, eventId string, attachmentId string) (string, error) {
+	var visibility string
+	var allowed bool
+	err := querier(ctx, s.db).QueryRowContext(ctx, `
+		SELECT
+			a.visibility,
+			a.visibility = 'public' OR
+			a.organization_id = COALESCE($3, '') OR
+			EXISTS (
+				SELECT 1
+				FROM payments p
+				WHERE
+					p.event_id = a.event_id AND
+					p.user_id = $4 AND
+					p.payment_status = 'complete'
+			)
+		FROM event_attachments a
+		WHERE
+			a.attachment_id = $1 AND
+			a.event_id = $2 AND
+			a.confirmed_at IS NOT NULL;
+		`,
+		attachmentId,
+		eventId,
+		orgId,
+		userId,
+	).Scan(
+		&visibility,
+		&allowed,
+	)
+	if err != nil {
+		return "", common.FilterSqlPgError(err)
+	}
+
+	if !allowed {
+		return "", common.ErrAuth
+	}
+
+	objPath := fiddlers.GetAttachmentPath(visibility, eventId, attachmentId)
+	if visibility == common.ATTACHMENT_VISIBILITY_PUBLIC {
+		return storage.GetFullObjUrl(objPath)
+	}
+
+	return s.objService.SignedUrl(ctx, s.bucket, objPath, time.Duration(common.ATTACHMENT_URL_EXP_MINS)*time.Minute)
+}
+
+func (s *AttachmentServicePgImpl) DeleteAttachment(ctx context.Context, orgId string, attachmentId string, userId uint32, isAdmin bool) error {
+	var eventId, visibility string
+	err := querier(ctx, s.db).QueryRowContext(ctx, `
+		DELETE FROM event_attachments
+		WHERE
+			attachment_id = $1 AND
+			organization_id = $2 AND
+			($3 OR uploaded_by = $4)
+		RETURNING
+			event_id,
+			visibility;
+		`,
+		attachmentId,
+		orgId,
+		isAdmin,
+		userId,
+	).Scan(
+		&eventId,
+		&visibility,
+	)
+	if err != nil {
+		return common.FilterSqlPgError(err)
+	}
+
+	return s.objService.Delete(ctx, s.bucket, fiddlers.GetAttachmentPath(visibility, eventId, attachmentId))
+}
+
+func (s *AttachmentServicePgImpl) GetStorageUsage(ctx context.Context, orgId string) (models.StorageUsage, error) {
+	usage := models.StorageUsage{OrganizationId: orgId}
+	err := querier(ctx, s.db).QueryRowContext(ctx, storageUsageQuery+`;`,
+		orgId,
+		common.ATTACHMENT_DEFAULT_QUOTA_BYTES,
+	).Scan(
+		&usage.QuotaBytes,
+		&usage.UsedBytes,
+	)
+	if err != nil {
+		return usage, common.FilterSqlPgError(err)
+	}
+
+	return usage, nil
+}
+
+func (s *AttachmentServicePgImpl) SetStorageQuota(ctx context.Context, orgId string, quota *int64) error {
+	res, err := querier(ctx, s.db).ExecContext(ctx, `
+		UPDATE organizations
+		SET storage_quota_bytes = $2
+		WHERE organization_id = $1;
+		`,
+		orgId,
+		quota,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return common.ErrNotFound
+	}
+
+	return nil
+}
+
+// Forgets the attachments never confirmed, giving their quota back
+func (s *AttachmentServicePgImpl) DeleteExpiredAttachments() error {
+	ctx := context.Background()
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		DELETE FROM event_attachments
+		WHERE
+			exp < NOW() AND
+			confirmed_at IS NULL
+		RETURNING
+			attachment_id,
+			event_id,
+			visibility;
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attachmentId, eventId, visibility string
+		err := rows.Scan(&attachmentId, &eventId, &visibility)
+		if err != nil {
+			return err
+		}
+
+		err = s.objService.Delete(ctx, s.bucket, fiddlers.GetAttachmentPath(visibility, eventId, attachmentId))
+		if err != nil {
+			common.Logger(ctx).Warn(fmt.Sprintf("deleting expired attachment %s: %s", attachmentId, err.Error()))
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestAttachmentServicePgImpl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	db := pgContainer.DB
+	userService := &UserServicePgImpl{db: db}
+
+	users := map[string]models.User{}
+	for _, email := range []string{"owner@email.com", "speaker@email.com", "paid@email.com", "other@email.com"} {
+		err = userService.CreateUser(ctx, models.User{Email: email, PasswordHash: "hashtest", FirstName: "Test", LastName: "User"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[email], err = userService.GetUser(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var eventId string
+	err = db.QueryRowContext(ctx, `
+		WITH org AS (
+			INSERT INTO organizations (organization_id, organization_name, owner_user_id)
+			VALUES ('ORG01', 'Patos', $1)
+			RETURNING organization_id
+		)
+		INSERT INTO events (event_name, owner_user_id, owner_organization_id, event_description)
+		SELECT 'Quack Week', $1, organization_id, '' FROM org
+		RETURNING event_id;
+	`, users["owner@email.com"].UserId).Scan(&eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO payments (user_id, unit_ammount, unit_currency, payment_status, event_id)
+		VALUES ($1, 300, 'BRL', 'complete', $2);
+	`, users["paid@email.com"].UserId, eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objService := NewObjectServiceFsImpl(t.TempDir(), "http://127.0.0.1:8080/", "secret")
+	s := NewAttachmentServicePgImpl(db, objService, "bucket")
+
+	pdf := []byte("%PDF-1.7\n%quack week rules")
+	quota := int64(2 * len(pdf))
+	err = s.SetStorageQuota(ctx, "ORG01", &quota)
+	if err != nil {
+		t.Fatalf("SetStorageQuota() error = %v", err)
+	}
+
+	create := func(visibility string) (models.EventAttachment, error) {
+		a, _, err := s.CreateAttachment(ctx, models.EventAttachment{
+			EventId:        eventId,
+			OrganizationId: "ORG01",
+			UploadedBy:     users["speaker@email.com"].UserId,
+			FileName:       "rules.pdf",
+			ContentType:    "application/pdf",
+			Size:           int64(len(pdf)),
+			Visibility:     visibility,
+		})
+		return a, err
+	}
+
+	private, err := create(common.ATTACHMENT_VISIBILITY_PRIVATE)
+	if err != nil {
+		t.Fatalf("CreateAttachment() error = %v", err)
+	}
+	public, err := create(common.ATTACHMENT_VISIBILITY_PUBLIC)
+	if err != nil {
+		t.Fatalf("CreateAttachment() error = %v", err)
+	}
+
+	// the pending uploads already count
+	_, err = create(common.ATTACHMENT_VISIBILITY_PUBLIC)
+	if err != common.ErrStorageQuotaExceeded {
+		t.Errorf("CreateAttachment() over quota error = %v, want %v", err, common.ErrStorageQuotaExceeded)
+	}
+
+	_, err = s.ConfirmAttachment(ctx, "ORG01", eventId, private.AttachmentId)
+	if err != common.ErrObjectNotFound {
+		t.Errorf("ConfirmAttachment() not uploaded error = %v, want %v", err, common.ErrObjectNotFound)
+	}
+
+	for _, a := range []models.EventAttachment{private, public} {
+		objPath := fiddlers.GetAttachmentPath(a.Visibility, a.EventId, a.AttachmentId)
+		err = objService.Upload(ctx, "bucket", objPath, int64(len(pdf)), bytes.NewReader(pdf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.ConfirmAttachment(ctx, "ORG01", eventId, a.AttachmentId)
+		if err != nil {
+			t.Fatalf("ConfirmAttachment() error = %v", err)
+		}
+	}
+
+	attachments, err := s.GetAttachments(ctx, eventId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 1 || attachments[0].AttachmentId != public.AttachmentId || attachments[0].Url == nil {
+		t.Errorf("GetAttachments() public = %+v", attachments)
+	}
+	attachments, err = s.GetAttachments(ctx, eventId, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 2 || attachments[0].Url != nil {
+		t.Errorf("GetAttachments() all = %+v", attachments)
+	}
+
+	orgId := "ORG01"
+	downloads := []struct {
+		name    string
+		userId  uint32
+		orgId   *string
+		wantErr error
+	}{
+		{"organization", users["speaker@email.com"].UserId, &orgId, nil},
+		{"registered", users["paid@email.com"].UserId, nil, nil},
+		{"not registered", users["other@email.com"].UserId, nil, common.ErrAuth},
+	}
+	for _, tt := range downloads {
+		t.Run(tt.name, func(t *testing.T) {
+			url, err := s.GetDownloadUrl(ctx, tt.userId, tt.orgId, eventId, private.AttachmentId)
+			if err != tt.wantErr {
+				t.Fatalf("GetDownloadUrl() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Contains([]byte(url), []byte(common.OBJECT_URL_SIGNATURE_PARAM+"=")) {
+				t.Errorf("GetDownloadUrl() = %s, want a signed url", url)
+			}
+		})
+	}
+
+	usage, err := s.GetStorageUsage(ctx, "ORG01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.UsedBytes != quota || usage.QuotaBytes != quota {
+		t.Errorf("GetStorageUsage() = %+v", usage)
+	}
+
+	// only the uploader or an admin
+	err = s.DeleteAttachment(ctx, "ORG01", private.AttachmentId, users["other@email.com"].UserId, false)
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("DeleteAttachment() other user error = %v, want %v", err, common.ErrNotFound)
+	}
+	err = s.DeleteAttachment(ctx, "ORG01", private.AttachmentId, users["speaker@email.com"].UserId, false)
+	if err != nil {
+		t.Fatalf("DeleteAttachment() error = %v", err)
+	}
+	_, err = objService.Stat(ctx, "bucket", fiddlers.GetAttachmentPath(private.Visibility, eventId, private.AttachmentId))
+	if err != common.ErrObjectNotFound {
+		t.Errorf("deleted object error = %v, want %v", err, common.ErrObjectNotFound)
+	}
+
+	// html declared as a pdf is refused
+	fake, err := create(common.ATTACHMENT_VISIBILITY_PUBLIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := []byte("<html>quack</html>")
+	html = append(html, bytes.Repeat([]byte(" "), len(pdf)-len(html))...)
+	err = objService.Upload(ctx, "bucket", fiddlers.GetAttachmentPath(fake.Visibility, eventId, fake.AttachmentId), int64(len(html)), bytes.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ConfirmAttachment(ctx, "ORG01", eventId, fake.AttachmentId)
+	if err != common.ErrUploadContentTypeInvalid {
+		t.Errorf("ConfirmAttachment() html error = %v, want %v", err, common.ErrUploadContentTypeInvalid)
+	}
+
+	_, err = db.ExecContext(ctx, `UPDATE event_attachments SET exp = NOW() - INTERVAL '1 minute' WHERE confirmed_at IS NULL;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.DeleteExpiredAttachments()
+	if err != nil {
+		t.Fatalf("DeleteExpiredAttachments() error = %v", err)
+	}
+	usage, err = s.GetStorageUsage(ctx, "ORG01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.UsedBytes != int64(len(pdf)) {
+		t.Errorf("GetStorageUsage() after cleanup = %+v", usage)
+	}
+}
@@ -23,7 +23,7 @@ type AuthService interface {
 	// Parses the special password-reset-JWT to its claims Struct
 	ParsePasswordResetToken(tokenString string) (models.JwtPasswordResetClaims, error)
 
-	// LoginOauth logs in the Oauth user, returns bool=true if the user was just created
+	// LoginOauth logs in the Oauth user, returns bool=true if the user was just created with `locale`
 	// this is to be used in sending welcome email
-	LoginOauth(ctx context.Context, oathUser oauth.User) (models.User, bool, error)
+	LoginOauth(ctx context.Context, oathUser oauth.User, locale string) (models.User, bool, error)
 }
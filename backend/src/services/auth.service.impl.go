@@ -10,6 +10,7 @@ import (
 
 	"github.com/golang-jwt/jwt"
 	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
 	"github.com/patos-ufscar/quack-week/models"
 	"github.com/patos-ufscar/quack-week/oauth"
 )
@@ -123,9 +124,9 @@ func (s *AuthServiceJwtImpl) ParsePasswordResetToken(tokenString string) (models
 	return claims, nil
 }
 
-func (s *AuthServiceJwtImpl) LoginOauth(ctx context.Context, oauthUser oauth.User) (models.User, bool, error) {
+func (s *AuthServiceJwtImpl) LoginOauth(ctx context.Context, oauthUser oauth.User, locale string) (models.User, bool, error) {
 	user := models.User{}
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := beginTx(ctx, s.db)
 	if err != nil {
 		return user, false, err
 	}
@@ -145,7 +146,8 @@ func (s *AuthServiceJwtImpl) LoginOauth(ctx context.Context, oauthUser oauth.Use
 			avatar_url,
 			created_at,
 			updated_at,
-			is_active
+			is_active,
+			locale
 		FROM users WHERE email = $1;
 	`, oauthUser.Email).Scan(
 		&user.UserId,
@@ -158,6 +160,7 @@ func (s *AuthServiceJwtImpl) LoginOauth(ctx context.Context, oauthUser oauth.Use
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.IsActive,
+		&user.Locale,
 	)
 	if err != nil && err != sql.ErrNoRows {
 		return user, false, err
@@ -179,9 +182,9 @@ func (s *AuthServiceJwtImpl) LoginOauth(ctx context.Context, oauthUser oauth.Use
 	// here error is sql.ErrNoRows
 	err = tx.QueryRowContext(ctx, `
 			INSERT INTO users 
-				(email, password_hash, first_name, last_name, avatar_url)
+				(email, password_hash, first_name, last_name, avatar_url, locale)
 			VALUES
-				($1, $2, $3, $4, $5)
+				($1, $2, $3, $4, $5, $6)
 			RETURNING 
 				user_id,
 				email,
@@ -192,13 +195,15 @@ func (s *AuthServiceJwtImpl) LoginOauth(ctx context.Context, oauthUser oauth.Use
 				avatar_url,
 				created_at,
 				updated_at,
-				is_active;
+				is_active,
+				locale;
 		`,
 		oauthUser.Email,
 		"oauth",
 		oauthUser.FirstName,
 		oauthUser.LastName,
 		oauthUser.PictureUrl,
+		fiddlers.ResolveLocale(locale),
 	).Scan(
 		&user.UserId,
 		&user.Email,
@@ -210,6 +215,7 @@ func (s *AuthServiceJwtImpl) LoginOauth(ctx context.Context, oauthUser oauth.Use
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.IsActive,
+		&user.Locale,
 	)
 	if err != nil {
 		return user, false, err
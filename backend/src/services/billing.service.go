@@ -2,23 +2,47 @@ package services
 
 import (
 	"context"
+	"net/http"
+	"time"
 
 	"github.com/patos-ufscar/quack-week/models"
-	"github.com/stripe/stripe-go/v81"
+	"github.com/patos-ufscar/quack-week/payment"
 )
 
 type BillingService interface {
-	// Gets the Stripe Checkout URL to be redirected to in the frontend
-	CreatePayment(ctx context.Context, currencyUnit stripe.Currency, unitAmmount int64, planName string, userId uint32) (string, error)
+	// Gets the checkout URL of the payment provider to be redirected to in the frontend,
+	// `promoCode` is validated against `eventId` and counted as used. When the promo code
+	// makes it free, the payment is already complete and the URL is the app success page.
+	CreatePayment(ctx context.Context, currency payment.Currency, unitAmmount int64, productName string, userId uint32, eventId *string, promoCode *string) (models.Payment, string, error)
 
-	// Webhook to be used in a daemon
-	GetCheckoutSession(ctx context.Context, sessionId string) (*stripe.CheckoutSession, error)
+	// Gets the checkout from the payment provider, payloads received in webhooks are not trusted
+	GetCheckout(ctx context.Context, checkoutId string) (*payment.Checkout, error)
+
+	// Gets the checkout id a payment provider webhook refers to, "" if it should be ignored
+	ParseWebhook(payload []byte, header http.Header) (string, error)
 
 	// Webhook to be used in a daemon
-	SetCheckoutSessionAsComplete(ctx context.Context, sessionId string) (models.Payment, error)
+	SetCheckoutAsComplete(ctx context.Context, checkoutId string) (models.Payment, error)
+
+	// Marks a pending payment as canceled, used when the checkout expired
+	SetCheckoutAsCanceled(ctx context.Context, checkoutId string) (models.Payment, error)
+
+	// Cancels a pending payment left without a checkout, when creating it failed,
+	// giving its promo code use back. Returns common.ErrNotFound otherwise.
+	CancelPendingPayment(ctx context.Context, paymentId string) (models.Payment, error)
+
+	// Expires an open checkout, so the customer can no longer pay it
+	ExpireCheckout(ctx context.Context, checkoutId string) (*payment.Checkout, error)
+
+	// Gets payments of the current provider that are still pending after `olderThan`, oldest first
+	GetStalePendingPayments(ctx context.Context, olderThan time.Duration, limit int) ([]models.Payment, error)
+
+	// Aggregates completed payments of the organization's events by event and `period`
+	// (day, week, month or year), from `from` inclusive until `to` exclusive
+	GetOrganizationRevenue(ctx context.Context, orgId string, period string, from time.Time, to time.Time) ([]models.RevenueEntry, error)
 
-	// // Gets the Stripe Client Secret to be used in Embedded Checkout Form
-	// 	//
-	// 	// Take a look at: https://docs.stripe.com/payments/accept-a-payment?platform=web&ui=stripe-hosted
-	// 	GetClientSecret(ctx context.Context, currencyUnit stripe.Currency, unitAmmount int64, planName string) (string, error)
+	// Gets the Url where the organization sets up the account its revenue is paid out to,
+	// the account is created on the first call. Fails with common.ErrPayoutsUnsupported if
+	// the provider cannot route payments to organizations.
+	GetPayoutOnboardingUrl(ctx context.Context, orgId string, email string) (string, error)
 }
@@ -3,26 +3,28 @@ package services
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/metrics"
 	"github.com/patos-ufscar/quack-week/models"
-	"github.com/stripe/stripe-go/v81"
-	"github.com/stripe/stripe-go/v81/checkout/session"
+	"github.com/patos-ufscar/quack-week/payment"
 )
 
-type BillingServiceStripeImpl struct {
-	db            *sql.DB
-	appSuccessUrl string
-	appCancelUrl  string
+type BillingServicePgImpl struct {
+	db                  *sql.DB
+	provider            payment.Provider
+	platformFeePercent  int64
+	appSuccessUrl       string
+	appCancelUrl        string
+	appPayoutsReturnUrl string
 }
 
-// https://www.youtube.com/watch?v=M4aCgy67f243
-// https://www.youtube.com/playlist?list=PLy1nL-pvL2M5eqpSBR9KL7K0lcnWo0V0a
-// https://docs.stripe.com/payments/accept-a-payment?platform=web&ui=stripe-hosted
-// https://www.youtube.com/watch?v=ePmEVBu8w6Y
-
-func NewBillingService(db *sql.DB, stripeApiKey string) BillingService {
+func NewBillingServicePgImpl(db *sql.DB, provider payment.Provider, platformFeePercent int64) BillingService {
 	successUrl, err := url.JoinPath(common.APP_HOST_URL, "/billing/success")
 	if err != nil {
 		panic(err)
@@ -33,130 +35,586 @@ func NewBillingService(db *sql.DB, stripeApiKey string) BillingService {
 		panic(err)
 	}
 
-	stripe.Key = stripeApiKey
+	payoutsReturnUrl, err := url.JoinPath(common.APP_HOST_URL, "/billing/payouts")
+	if err != nil {
+		panic(err)
+	}
 
-	return &BillingServiceStripeImpl{
-		db:            db,
-		appSuccessUrl: successUrl,
-		appCancelUrl:  cancelUrl,
+	return &BillingServicePgImpl{
+		db:                  db,
+		provider:            provider,
+		platformFeePercent:  platformFeePercent,
+		appSuccessUrl:       successUrl,
+		appCancelUrl:        cancelUrl,
+		appPayoutsReturnUrl: payoutsReturnUrl,
 	}
 }
 
-func (s *BillingServiceStripeImpl) CreatePayment(ctx context.Context, currencyUnit stripe.Currency, unitAmmount int64, planName string, userId uint32) (string, error) {
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+func (s *BillingServicePgImpl) CreatePayment(ctx context.Context, currency payment.Currency, unitAmmount int64, productName string, userId uint32, eventId *string, promoCode *string) (models.Payment, string, error) {
+	tx, err := beginTx(ctx, s.db)
 	if err != nil {
-		return "", err
+		return models.Payment{}, "", err
 	}
 	defer tx.Rollback()
 
-	var paymentId string
+	var promoCodeId *uint32
+	var discountAmmount int64
+	if promoCode != nil {
+		if eventId == nil {
+			return models.Payment{}, "", common.ErrPromoCodeInvalid
+		}
+
+		var discountType string
+		var discountValue int64
+		var id uint32
+
+		// Counting the use in the same statement that checks `max_uses` makes
+		// concurrent redemptions wait on the row lock instead of overshooting.
+		err = tx.QueryRowContext(ctx, `
+			UPDATE promo_codes pc
+			SET uses = pc.uses + 1
+			FROM events e
+			WHERE
+				e.event_id = $1 AND
+				pc.organization_id = e.owner_organization_id AND
+				pc.code = $2 AND
+				(pc.max_uses IS NULL OR pc.uses < pc.max_uses) AND
+				(pc.exp IS NULL OR pc.exp > NOW()) AND
+				(
+					NOT EXISTS (
+						SELECT 1 FROM promo_code_events pce
+						WHERE pce.promo_code_id = pc.promo_code_id
+					) OR
+					EXISTS (
+						SELECT 1 FROM promo_code_events pce
+						WHERE pce.promo_code_id = pc.promo_code_id AND pce.event_id = e.event_id
+					)
+				)
+			RETURNING
+				pc.promo_code_id,
+				pc.discount_type,
+				pc.discount_value;
+			`,
+			*eventId,
+			fiddlers.NormalizePromoCode(*promoCode),
+		).Scan(&id, &discountType, &discountValue)
+		if err == sql.ErrNoRows {
+			return models.Payment{}, "", common.ErrPromoCodeInvalid
+		}
+		if err != nil {
+			return models.Payment{}, "", err
+		}
+
+		promoCodeId = &id
+		discountAmmount = fiddlers.GetDiscountAmmount(unitAmmount, discountType, discountValue)
+	}
+
+	total := unitAmmount - discountAmmount
+	feeAmmount := fiddlers.GetPlatformFeeAmmount(total, s.platformFeePercent)
+
+	var connectedAccountId *string
+	if eventId != nil {
+		err = tx.QueryRowContext(ctx, `
+			SELECT o.payout_account_id
+			FROM
+				events e
+			INNER JOIN
+				organizations o ON e.owner_organization_id = o.organization_id
+			WHERE e.event_id = $1;
+			`,
+			*eventId,
+		).Scan(&connectedAccountId)
+		if err != nil {
+			return models.Payment{}, "", common.FilterSqlPgError(err)
+		}
+	}
+
+	var customerEmail, customerLocale, customerName string
 	err = tx.QueryRowContext(ctx, `
+		SELECT email, locale, first_name FROM users WHERE user_id = $1;
+		`,
+		userId,
+	).Scan(&customerEmail, &customerLocale, &customerName)
+	if err != nil {
+		return models.Payment{}, "", common.FilterSqlPgError(err)
+	}
+
+	// the provider is set before the checkout exists, so the reconciliation
+	// daemon finds the payment if creating it fails midway
+	var provider *string
+	if total > 0 {
+		name := s.provider.GetName()
+		provider = &name
+	}
+
+	p, err := scanPayment(tx.QueryRowContext(ctx, `
 		INSERT INTO payments
-			(user_id, unit_ammount, unit_currency)
+			(user_id, unit_ammount, unit_currency, event_id, promo_code_id, discount_ammount, fee_ammount, payment_status, completed_at, payment_provider)
 		VALUES
-			($1, $2, LOWER($3))
-		RETURNING payment_id;
+			($1, $2, LOWER($3), $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+paymentColumns+`;
 		`,
 		userId,
-		unitAmmount,
-		string(currencyUnit),
-	).Scan(&paymentId)
+		total,
+		string(currency),
+		eventId,
+		promoCodeId,
+		discountAmmount,
+		feeAmmount,
+		fiddlers.GetInitialPaymentStatus(total),
+		fiddlers.GetInitialPaymentCompletedAt(total),
+		provider,
+	))
 	if err != nil {
-		return "", err
+		return p, "", common.FilterSqlPgError(err)
 	}
 
-	params := &stripe.CheckoutSessionParams{
-		ClientReferenceID: stripe.String(paymentId),
-		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
-		LineItems: []*stripe.CheckoutSessionLineItemParams{
-			{
-				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-					Currency: stripe.String(string(currencyUnit)),
-					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-						Name: stripe.String(planName),
-					},
-					UnitAmount: stripe.Int64(unitAmmount),
-				},
-				Quantity: stripe.Int64(1),
-			},
-		},
-		SuccessURL: &s.appSuccessUrl,
-		CancelURL:  &s.appCancelUrl,
+	// fully discounted, there is nothing to charge
+	if total == 0 {
+		err = notifyPaymentAccepted(ctx, tx, p, customerEmail, customerLocale, customerName)
+		if err != nil {
+			return p, "", err
+		}
+
+		err = publishRegistration(ctx, tx, p)
+		if err != nil {
+			return p, "", err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return p, "", err
+		}
+		metrics.Registrations.WithLabelValues(metrics.REGISTRATION_KIND_FREE).Inc()
+
+		return p, s.appSuccessUrl, nil
 	}
 
-	checkout, err := session.New(params)
+	// committed before calling the provider, so the promo code row is not
+	// locked while waiting for it
+	err = tx.Commit()
 	if err != nil {
-		return "", err
+		return p, "", err
 	}
 
-	_, err = tx.ExecContext(ctx, `
+	checkout, err := s.provider.CreateCheckout(ctx, payment.CheckoutParams{
+		ReferenceId:   p.PaymentId,
+		Currency:      currency,
+		UnitAmmount:   total,
+		ProductName:   productName,
+		CustomerEmail: customerEmail,
+		SuccessUrl:    s.appSuccessUrl,
+		CancelUrl:     s.appCancelUrl,
+
+		ConnectedAccountId: connectedAccountId,
+		PlatformFeeAmmount: feeAmmount,
+	})
+	if err != nil {
+		_, cancelErr := s.CancelPendingPayment(ctx, p.PaymentId)
+		if cancelErr != nil {
+			common.Logger(ctx).Error(fmt.Sprintf("canceling payment %s without checkout: %s", p.PaymentId, cancelErr.Error()))
+		}
+		return p, "", err
+	}
+
+	// if this fails the url is never handed out, and the daemon cancels the
+	// payment left without a checkout
+	_, err = querier(ctx, s.db).ExecContext(ctx, `
 		UPDATE payments
-		SET
-			stripe_checkout_session_id = $1,
-			completed_at = NOW()
+		SET checkout_id = $1
 		WHERE payment_id = $2;
 		`,
-		checkout.ID,
-		paymentId,
+		checkout.Id,
+		p.PaymentId,
 	)
 	if err != nil {
-		return "", err
+		return p, "", err
 	}
+	p.CheckoutId = &checkout.Id
 
-	return checkout.URL, tx.Commit()
+	return p, checkout.Url, nil
 }
 
-func (s *BillingServiceStripeImpl) GetCheckoutSession(ctx context.Context, sessionId string) (*stripe.CheckoutSession, error) {
-	checkout, err := session.Get(sessionId, nil)
-	return checkout, err
+func (s *BillingServicePgImpl) GetCheckout(ctx context.Context, checkoutId string) (*payment.Checkout, error) {
+	return s.provider.GetCheckout(ctx, checkoutId)
 }
 
-func (s *BillingServiceStripeImpl) SetCheckoutSessionAsComplete(ctx context.Context, sessionId string) (models.Payment, error) {
-	var p models.Payment
-	err := s.db.QueryRowContext(ctx, `
+func (s *BillingServicePgImpl) ParseWebhook(payload []byte, header http.Header) (string, error) {
+	return s.provider.ParseWebhook(payload, header)
+}
+
+// Only pending payments are updated, so the webhook and the reconciliation
+// daemon never complete the same payment twice. The payment accepted email is
+// queued along with the status change.
+func (s *BillingServicePgImpl) SetCheckoutAsComplete(ctx context.Context, checkoutId string) (models.Payment, error) {
+	return s.setPaymentStatus(ctx, checkoutId, "complete")
+}
+
+func (s *BillingServicePgImpl) SetCheckoutAsCanceled(ctx context.Context, checkoutId string) (models.Payment, error) {
+	return s.setPaymentStatus(ctx, checkoutId, "canceled")
+}
+
+func (s *BillingServicePgImpl) ExpireCheckout(ctx context.Context, checkoutId string) (*payment.Checkout, error) {
+	return s.provider.ExpireCheckout(ctx, checkoutId)
+}
+
+func (s *BillingServicePgImpl) GetStalePendingPayments(ctx context.Context, olderThan time.Duration, limit int) ([]models.Payment, error) {
+	payments := []models.Payment{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT `+paymentColumns+`
+		FROM payments
+		WHERE
+			payment_status = 'pending' AND
+			payment_provider = $1 AND
+			created_at < $2
+		ORDER BY created_at
+		LIMIT $3;
+		`,
+		s.provider.GetName(),
+		time.Now().Add(-olderThan),
+		limit,
+	)
+	if err != nil {
+		return payments, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return payments, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
+
+func (s *BillingServicePgImpl) GetOrganizationRevenue(ctx context.Context, orgId string, period string, from time.Time, to time.Time) ([]models.RevenueEntry, error) {
+	entries := []models.RevenueEntry{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT
+			e.event_id,
+			e.event_name,
+			DATE_TRUNC($2, p.completed_at) AS period_start,
+			p.unit_currency,
+			COUNT(*),
+			SUM(p.unit_ammount),
+			SUM(p.fee_ammount),
+			SUM(p.unit_ammount - p.fee_ammount)
+		FROM
+			payments p
+		INNER JOIN
+			events e ON p.event_id = e.event_id
+		WHERE
+			e.owner_organization_id = $1 AND
+			p.payment_status = 'complete' AND
+			p.completed_at >= $3 AND
+			p.completed_at < $4
+		GROUP BY
+			e.event_id,
+			e.event_name,
+			period_start,
+			p.unit_currency
+		ORDER BY
+			period_start,
+			e.event_name;
+		`,
+		orgId,
+		period,
+		from,
+		to,
+	)
+	if err != nil {
+		return entries, common.FilterSqlPgError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r := models.RevenueEntry{}
+		err := rows.Scan(
+			&r.EventId,
+			&r.EventName,
+			&r.PeriodStart,
+			&r.Currency,
+			&r.Payments,
+			&r.Gross,
+			&r.Fees,
+			&r.Net,
+		)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, r)
+	}
+
+	return entries, rows.Err()
+}
+
+func (s *BillingServicePgImpl) GetPayoutOnboardingUrl(ctx context.Context, orgId string, email string) (string, error) {
+	connect, ok := s.provider.(payment.ConnectProvider)
+	if !ok {
+		return "", common.ErrPayoutsUnsupported
+	}
+
+	var accountId *string
+	err := querier(ctx, s.db).QueryRowContext(ctx, `
+		SELECT payout_account_id
+		FROM organizations
+		WHERE organization_id = $1;
+		`,
+		orgId,
+	).Scan(&accountId)
+	if err != nil {
+		return "", common.FilterSqlPgError(err)
+	}
+
+	if accountId == nil {
+		newAccountId, err := connect.CreateConnectedAccount(ctx, email)
+		if err != nil {
+			return "", err
+		}
+
+		// a concurrent request may have stored its account first, that one is
+		// kept and the account just created is left unused
+		err = querier(ctx, s.db).QueryRowContext(ctx, `
+			UPDATE organizations
+			SET payout_account_id = COALESCE(payout_account_id, $1)
+			WHERE organization_id = $2
+			RETURNING payout_account_id;
+			`,
+			newAccountId,
+			orgId,
+		).Scan(&accountId)
+		if err != nil {
+			return "", common.FilterSqlPgError(err)
+		}
+		if *accountId != newAccountId {
+			common.Logger(ctx).Warn(fmt.Sprintf("organization %s already has payout account %s, %s is unused", orgId, *accountId, newAccountId))
+		}
+	}
+
+	// the refresh Url is hit when the link expired, the app requests a new one
+	return connect.GetOnboardingUrl(ctx, *accountId, s.appPayoutsReturnUrl+"?refresh=true", s.appPayoutsReturnUrl)
+}
+
+func (s *BillingServicePgImpl) setPaymentStatus(ctx context.Context, checkoutId string, status string) (models.Payment, error) {
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	defer tx.Rollback()
+
+	p, err := scanPayment(tx.QueryRowContext(ctx, `
 		UPDATE payments
-		SET payment_status = 'complete'
-		WHERE stripe_checkout_session_id = $1
-		RETURNING *;
+		SET
+			payment_status = $1,
+			completed_at = NOW()
+		WHERE
+			payment_provider = $2 AND
+			checkout_id = $3 AND
+			payment_status = 'pending'
+		RETURNING `+paymentColumns+`;
+		`,
+		status,
+		s.provider.GetName(),
+		checkoutId,
+	))
+	if err != nil {
+		return p, common.FilterSqlPgError(err)
+	}
+
+	if status == "complete" {
+		var email, locale, name string
+		err = tx.QueryRowContext(ctx, `
+			SELECT email, locale, first_name FROM users WHERE user_id = $1;
+			`,
+			p.UserId,
+		).Scan(&email, &locale, &name)
+		if err != nil {
+			return p, err
+		}
+
+		err = notifyPaymentAccepted(ctx, tx, p, email, locale, name)
+		if err != nil {
+			return p, err
+		}
+
+		err = publishRegistration(ctx, tx, p)
+		if err != nil {
+			return p, err
+		}
+	}
+
+	// a canceled checkout gives the promo code use back
+	if status == "canceled" {
+		err = releasePromoCodeUse(ctx, tx, p)
+		if err != nil {
+			return p, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return p, err
+	}
+
+	if status == "complete" {
+		metrics.PaymentsCompleted.WithLabelValues(s.provider.GetName()).Inc()
+		metrics.Registrations.WithLabelValues(metrics.REGISTRATION_KIND_PAID).Inc()
+	}
+
+	return p, nil
+}
+
+func (s *BillingServicePgImpl) CancelPendingPayment(ctx context.Context, paymentId string) (models.Payment, error) {
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	defer tx.Rollback()
+
+	p, err := scanPayment(tx.QueryRowContext(ctx, `
+		UPDATE payments
+		SET
+			payment_status = 'canceled',
+			completed_at = NOW()
+		WHERE
+			payment_id = $1 AND
+			checkout_id IS NULL AND
+			payment_status = 'pending'
+		RETURNING `+paymentColumns+`;
+		`,
+		paymentId,
+	))
+	if err != nil {
+		return p, common.FilterSqlPgError(err)
+	}
+
+	err = releasePromoCodeUse(ctx, tx, p)
+	if err != nil {
+		return p, err
+	}
+
+	return p, tx.Commit()
+}
+
+func releasePromoCodeUse(ctx context.Context, tx Querier, p models.Payment) error {
+	if p.PromoCodeId == nil {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE promo_codes
+		SET uses = uses - 1
+		WHERE promo_code_id = $1 AND uses > 0;
 		`,
-		sessionId,
-	).Scan(
+		*p.PromoCodeId,
+	)
+
+	return err
+}
+
+const paymentColumns = `
+			payment_id,
+			user_id,
+			unit_ammount,
+			unit_currency,
+			payment_status,
+			payment_provider,
+			checkout_id,
+			created_at,
+			completed_at,
+			event_id,
+			promo_code_id,
+			discount_ammount,
+			fee_ammount`
+
+func scanPayment(row interface{ Scan(dest ...any) error }) (models.Payment, error) {
+	var p models.Payment
+	err := row.Scan(
 		&p.PaymentId,
 		&p.UserId,
 		&p.UnitAmmount,
 		&p.UnitCurrency,
 		&p.PaymentStatus,
-		&p.StripeCheckoutSessionId,
+		&p.PaymentProvider,
+		&p.CheckoutId,
 		&p.CreatedAt,
 		&p.CompletedAt,
+		&p.EventId,
+		&p.PromoCodeId,
+		&p.DiscountAmmount,
+		&p.FeeAmmount,
 	)
 
 	return p, err
 }
 
-// func (s *BillingServiceStripeImpl) GetClientSecret(ctx context.Context, currencyUnit stripe.Currency, unitAmmount int64, planName string) (string, error) {
-// 	panic("not impl")
-// 	params := &stripe.CheckoutSessionParams{
-// 		Mode:   stripe.String(string(stripe.CheckoutSessionModePayment)),
-// 		UIMode: stripe.String("embedded"),
-// 		LineItems: []*stripe.CheckoutSessionLineItemParams{
-// 			{
-// 				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-// 					Currency: stripe.String(string(currencyUnit)),
-// 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-// 						Name: stripe.String(planName),
-// 					},
-// 					UnitAmount: stripe.Int64(unitAmmount),
-// 				},
-// 				Quantity: stripe.Int64(1),
-// 			},
-// 		},
-// 		ReturnURL: stripe.String("https://example.com/checkout/return?session_id={CHECKOUT_SESSION_ID}"),
-// 	}
-
-// 	checkout, err := session.New(params)
-// 	if err != nil {
-// 		return "", err
-// 	}
-// 	return checkout.URL, nil
-// }
+// Notifies the customer in-app and queues the email, as their preferences allow
+func notifyPaymentAccepted(ctx context.Context, tx Querier, p models.Payment, email string, locale string, name string) error {
+	sendEmail, err := notifyUser(ctx, tx, p.UserId, common.NOTIFICATION_TYPE_PAYMENT_ACCEPTED, models.PaymentAcceptedNotification{
+		PaymentId: p.PaymentId,
+		EventId:   p.EventId,
+	})
+	if err != nil || !sendEmail {
+		return err
+	}
+
+	_, err = enqueueEmail(ctx, tx, common.EMAIL_PRIORITY_TRANSACTIONAL, email, locale, common.EMAIL_TEMPLATE_PAYMENT_ACCEPTED, newPaymentAcceptedVars(name, p))
+
+	return err
+}
+
+// Announces the new registration of the event: the count to its organization
+// dashboards and the registration and payment to the organization webhooks
+func publishRegistration(ctx context.Context, tx Querier, p models.Payment) error {
+	if p.EventId == nil {
+		return nil
+	}
+
+	var orgId string
+	registrations := models.RegistrationsStreamEvent{EventId: *p.EventId}
+	err := tx.QueryRowContext(ctx, `
+		SELECT
+			e.owner_organization_id,
+			COUNT(p.payment_id)
+		FROM events e
+		LEFT JOIN payments p ON p.event_id = e.event_id AND p.payment_status = 'complete'
+		WHERE e.event_id = $1
+		GROUP BY e.event_id;
+		`,
+		*p.EventId,
+	).Scan(&orgId, &registrations.Registrations)
+	if err != nil {
+		return err
+	}
+
+	err = publishStreamEvent(ctx, tx, fiddlers.GetOrganizationStreamTopic(orgId), common.STREAM_EVENT_REGISTRATIONS, registrations)
+	if err != nil {
+		return err
+	}
+
+	registration := models.RegistrationWebhook{
+		EventId:   *p.EventId,
+		PaymentId: p.PaymentId,
+		UserId:    p.UserId,
+	}
+	err = tx.QueryRowContext(ctx, `
+		SELECT email, first_name, last_name FROM users WHERE user_id = $1;
+		`,
+		p.UserId,
+	).Scan(&registration.Email, &registration.FirstName, &registration.LastName)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhooks(ctx, tx, orgId, common.WEBHOOK_EVENT_REGISTRATION_CREATED, registration)
+	if err != nil {
+		return err
+	}
+
+	return enqueueWebhooks(ctx, tx, orgId, common.WEBHOOK_EVENT_PAYMENT_COMPLETED, p)
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+	"github.com/patos-ufscar/quack-week/payment"
+)
+
+func TestBillingServicePgImpl_Reconciliation(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	fakeProvider := payment.NewFakeProvider()
+
+	userService := &UserServicePgImpl{db: pgContainer.DB}
+	err = userService.CreateUser(ctx, models.User{
+		Email:        "buyer@email.com",
+		PasswordHash: "hashtest",
+		FirstName:    "Buyer",
+		LastName:     "One",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := userService.GetUser(ctx, "buyer@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &BillingServicePgImpl{
+		db:       pgContainer.DB,
+		provider: fakeProvider,
+	}
+
+	for range 2 {
+		_, _, err = s.CreatePayment(ctx, payment.CURRENCY_BRL, 300, "event", user.UserId, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := s.GetStalePendingPayments(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("GetStalePendingPayments() len = %d, want 2", len(pending))
+	}
+
+	paid, abandoned := *pending[0].CheckoutId, *pending[1].CheckoutId
+	err = fakeProvider.SetPaid(paid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkout, err := s.GetCheckout(ctx, paid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !checkout.Paid {
+		t.Errorf("GetCheckout() paid = %v, want true", checkout.Paid)
+	}
+
+	p, err := s.SetCheckoutAsComplete(ctx, paid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.PaymentStatus != "complete" || p.CompletedAt == nil {
+		t.Errorf("SetCheckoutAsComplete() = %+v, want complete with completedAt", p)
+	}
+
+	_, err = s.SetCheckoutAsComplete(ctx, paid)
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("SetCheckoutAsComplete() twice error = %v, want %v", err, common.ErrNotFound)
+	}
+
+	var queued int
+	err = pgContainer.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM email_outbox WHERE recipient = 'buyer@email.com' AND template_name = $1;
+	`, common.EMAIL_TEMPLATE_PAYMENT_ACCEPTED).Scan(&queued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued != 1 {
+		t.Errorf("payment accepted emails queued = %d, want 1", queued)
+	}
+
+	_, err = s.ExpireCheckout(ctx, abandoned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = s.SetCheckoutAsCanceled(ctx, abandoned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.PaymentStatus != "canceled" {
+		t.Errorf("SetCheckoutAsCanceled() status = %v, want canceled", p.PaymentStatus)
+	}
+
+	pending, err = s.GetStalePendingPayments(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("GetStalePendingPayments() len = %d, want 0", len(pending))
+	}
+}
+
+func TestBillingServicePgImpl_CreatePaymentPromoCode(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	fakeProvider := payment.NewFakeProvider()
+
+	var userId uint32
+	var eventId string
+	err = pgContainer.DB.QueryRowContext(ctx, `
+		INSERT INTO users (email, password_hash, first_name, last_name)
+		VALUES ('buyer@email.com', 'hashtest', 'Buyer', 'One')
+		RETURNING user_id;
+	`).Scan(&userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pgContainer.DB.ExecContext(ctx, `
+		INSERT INTO organizations (organization_id, organization_name, owner_user_id)
+		VALUES ('quack', 'Quack', $1);
+	`, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pgContainer.DB.QueryRowContext(ctx, `
+		INSERT INTO events (event_name, owner_user_id, owner_organization_id, event_description)
+		VALUES ('Quack Week', $1, 'quack', '')
+		RETURNING event_id;
+	`, userId).Scan(&eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maxUses := uint32(1)
+	promoService := &PromoCodeServicePgImpl{db: pgContainer.DB}
+	for _, p := range []models.PromoCode{
+		{OrganizationId: "quack", Code: "HALF", DiscountType: common.DISCOUNT_TYPE_PERCENTAGE, DiscountValue: 50},
+		{OrganizationId: "quack", Code: "FREE", DiscountType: common.DISCOUNT_TYPE_PERCENTAGE, DiscountValue: 100},
+		{OrganizationId: "quack", Code: "ONCE", DiscountType: common.DISCOUNT_TYPE_FIXED, DiscountValue: 100, MaxUses: &maxUses},
+		{OrganizationId: "quack", Code: "RETRY", DiscountType: common.DISCOUNT_TYPE_FIXED, DiscountValue: 100, MaxUses: &maxUses},
+	} {
+		_, err = promoService.CreatePromoCode(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &BillingServicePgImpl{
+		db:            pgContainer.DB,
+		provider:      fakeProvider,
+		appSuccessUrl: "http://app/billing/success",
+	}
+
+	code := func(c string) *string { return &c }
+
+	tests := []struct {
+		name       string
+		promoCode  *string
+		wantAmount uint32
+		wantStatus string
+		wantErr    error
+	}{
+		{"no promo code", nil, 30000, "pending", nil},
+		{"half off", code("half"), 15000, "pending", nil},
+		{"free bypasses the provider", code("FREE"), 0, "complete", nil},
+		{"single use", code("ONCE"), 29900, "pending", nil},
+		{"single use exhausted", code("ONCE"), 0, "", common.ErrPromoCodeInvalid},
+		{"unknown code", code("NOPE"), 0, "", common.ErrPromoCodeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, url, err := s.CreatePayment(ctx, payment.CURRENCY_BRL, 30000, "event", userId, &eventId, tt.promoCode)
+			if err != tt.wantErr {
+				t.Fatalf("BillingServicePgImpl.CreatePayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if p.UnitAmmount != tt.wantAmount || p.PaymentStatus != tt.wantStatus {
+				t.Errorf("BillingServicePgImpl.CreatePayment() = %+v, want amount %d status %s", p, tt.wantAmount, tt.wantStatus)
+			}
+			if tt.wantStatus == "complete" && (url != s.appSuccessUrl || p.CheckoutId != nil) {
+				t.Errorf("BillingServicePgImpl.CreatePayment() free payment went through the provider: %s", url)
+			}
+		})
+	}
+
+	// a failed checkout cancels its payment and gives the promo code use back
+	failing := &BillingServicePgImpl{
+		db:       pgContainer.DB,
+		provider: failingProvider{fakeProvider},
+	}
+	p, _, err := failing.CreatePayment(ctx, payment.CURRENCY_BRL, 30000, "event", userId, &eventId, code("RETRY"))
+	if err != errCheckoutFailed {
+		t.Fatalf("BillingServicePgImpl.CreatePayment() failed checkout error = %v, want %v", err, errCheckoutFailed)
+	}
+	var status string
+	err = pgContainer.DB.QueryRowContext(ctx, `
+		SELECT payment_status FROM payments WHERE payment_id = $1;
+	`, p.PaymentId).Scan(&status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "canceled" {
+		t.Errorf("failed checkout payment status = %s, want canceled", status)
+	}
+	_, _, err = s.CreatePayment(ctx, payment.CURRENCY_BRL, 30000, "event", userId, &eventId, code("RETRY"))
+	if err != nil {
+		t.Errorf("BillingServicePgImpl.CreatePayment() after failed checkout error = %v, want nil", err)
+	}
+}
+
+var errCheckoutFailed = errors.New("checkoutFailedError")
+
+type failingProvider struct {
+	*payment.FakeProvider
+}
+
+func (p failingProvider) CreateCheckout(ctx context.Context, params payment.CheckoutParams) (*payment.Checkout, error) {
+	return nil, errCheckoutFailed
+}
+
+func TestBillingServicePgImpl_GetPayoutOnboardingUrl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	_, err = pgContainer.DB.ExecContext(ctx, `
+		WITH u AS (
+			INSERT INTO users (email, password_hash, first_name, last_name)
+			VALUES ('owner@email.com', 'hashtest', 'Owner', 'One')
+			RETURNING user_id
+		)
+		INSERT INTO organizations (organization_id, organization_name, owner_user_id)
+		SELECT 'quack', 'Quack', user_id FROM u;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &connectProvider{FakeProvider: payment.NewFakeProvider()}
+	s := &BillingServicePgImpl{
+		db:                  pgContainer.DB,
+		provider:            provider,
+		appPayoutsReturnUrl: "http://app/payouts",
+	}
+
+	// concurrent requests must all onboard the same account
+	const requests = 5
+	urls := make([]string, requests)
+	errs := make([]error, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			urls[i], errs[i] = s.GetPayoutOnboardingUrl(ctx, "quack", "owner@email.com")
+		}()
+	}
+	wg.Wait()
+
+	var stored string
+	err = pgContainer.DB.QueryRowContext(ctx, `
+		SELECT payout_account_id FROM organizations WHERE organization_id = 'quack';
+	`).Scan(&stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range requests {
+		if errs[i] != nil {
+			t.Fatalf("BillingServicePgImpl.GetPayoutOnboardingUrl() error = %v", errs[i])
+		}
+		if want := "http://onboarding/" + stored; urls[i] != want {
+			t.Errorf("BillingServicePgImpl.GetPayoutOnboardingUrl() = %s, want %s", urls[i], want)
+		}
+	}
+}
+
+type connectProvider struct {
+	*payment.FakeProvider
+	accounts atomic.Int32
+}
+
+func (p *connectProvider) CreateConnectedAccount(ctx context.Context, email string) (string, error) {
+	return fmt.Sprintf("acct_%d", p.accounts.Add(1)), nil
+}
+
+func (p *connectProvider) GetOnboardingUrl(ctx context.Context, accountId string, refreshUrl string, returnUrl string) (string, error) {
+	return "http://onboarding/" + accountId, nil
+}
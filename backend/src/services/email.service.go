@@ -1,11 +1,44 @@
 package services
 
-import "github.com/patos-ufscar/quack-week/models"
+import (
+	"context"
 
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+// `locale` picks the language of the email, see fiddlers.ResolveLocale
 type EmailService interface {
-	SendEmailConfirmation(email string, name string, otp string) error
-	SendAccountCreated(email string, name string) error
-	SendOrganizationInvite(email string, name string, otp string, orgName string) error
-	SendPasswordReset(email string, name string, otp string) error
-	SendPaymentAccepted(email string, name string, payment models.Payment) error
+	SendEmailConfirmation(ctx context.Context, email string, locale string, name string, otp string) error
+	SendAccountCreated(ctx context.Context, email string, locale string, name string) error
+	SendOrganizationInvite(ctx context.Context, email string, locale string, name string, otp string, orgName string) error
+	SendPasswordReset(ctx context.Context, email string, locale string, name string, otp string) error
+	SendPaymentAccepted(ctx context.Context, email string, locale string, name string, payment models.Payment) error
+}
+
+// EmailSender delivers an already rendered email
+type EmailSender interface {
+	SendMessage(ctx context.Context, email string, subject string, html string, text string) error
+}
+
+// EmailTransport is an EmailService that sends right away, which makes it
+// usable as the delivery backend of the outbox
+type EmailTransport interface {
+	EmailService
+	EmailSender
+}
+
+// EmailOutboxService delivers the emails queued in the `email_outbox` table
+type EmailOutboxService interface {
+	// Sends the due emails, retrying failures with backoff. To be used in a daemon.
+	DeliverPending() error
+	GetDeadEmails(ctx context.Context, limit int, offset int) ([]models.OutboxEmail, error)
+	RetryEmail(ctx context.Context, emailId uint64) error
+}
+
+// EmailPreviewService lets admins see and try the email templates without
+// going through the flows that send them
+type EmailPreviewService interface {
+	GetEmailTemplates() []models.EmailTemplate
+	PreviewEmail(templateName string, locale string, vars map[string]any) (models.EmailPreview, error)
+	SendTestEmail(ctx context.Context, email string, templateName string, locale string, vars map[string]any) error
 }
@@ -2,31 +2,170 @@ package services
 
 import (
 	"bytes"
-	"log/slog"
+	"context"
+	htmlpkg "html"
+	"html/template"
 	"net/url"
 	"path/filepath"
-	"text/template"
+	"strings"
 
 	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/metrics"
 	"github.com/patos-ufscar/quack-week/models"
-	"github.com/resendlabs/resend-go"
+	"github.com/patos-ufscar/quack-week/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-type EmailServiceResendImpl struct {
-	resendClient *resend.Client
+var emailSubjects = map[string]map[string]string{
+	common.LOCALE_PT_BR: {
+		common.EMAIL_TEMPLATE_EMAIL_CONFIRMATION:  "Confirme sua conta!",
+		common.EMAIL_TEMPLATE_ACCOUNT_CREATED:     "Conta criada!",
+		common.EMAIL_TEMPLATE_ORGANIZATION_INVITE: "Convite para Organização",
+		common.EMAIL_TEMPLATE_PASSWORD_RESET:      "Recuperação de Senha",
+		common.EMAIL_TEMPLATE_PAYMENT_ACCEPTED:    "Pagamento Aceito",
+	},
+	common.LOCALE_EN: {
+		common.EMAIL_TEMPLATE_EMAIL_CONFIRMATION:  "Confirm Your Account!",
+		common.EMAIL_TEMPLATE_ACCOUNT_CREATED:     "Account Created!",
+		common.EMAIL_TEMPLATE_ORGANIZATION_INVITE: "Organization Invite",
+		common.EMAIL_TEMPLATE_PASSWORD_RESET:      "Password Reset",
+		common.EMAIL_TEMPLATE_PAYMENT_ACCEPTED:    "Payment Accepted",
+	},
+}
+
+var emailTemplateNames = []string{
+	common.EMAIL_TEMPLATE_EMAIL_CONFIRMATION,
+	common.EMAIL_TEMPLATE_ACCOUNT_CREATED,
+	common.EMAIL_TEMPLATE_ORGANIZATION_INVITE,
+	common.EMAIL_TEMPLATE_PASSWORD_RESET,
+	common.EMAIL_TEMPLATE_PAYMENT_ACCEPTED,
+	common.EMAIL_TEMPLATE_ANNOUNCEMENT,
+}
+
+// emailRenderer renders the email templates by locale and name. Each email in
+// `templatesDir/<locale>/<name>.html` fills the blocks of the shared
+// `templatesDir/layouts/base.html`, with the locale's `partials.html`. The
+// subject comes from emailSubjects, unless the template defines a "subject" block
+type emailRenderer struct {
+	templates map[string]map[string]*template.Template
+}
+
+type emailButton struct {
+	Url   string
+	Label string
+}
+
+var emailTemplateFuncs = template.FuncMap{
+	"button": func(url string, label string) emailButton {
+		return emailButton{Url: url, Label: label}
+	},
+	// splits user text in paragraphs of lines, on blank lines and line breaks
+	"paragraphs": func(text string) [][]string {
+		var paragraphs [][]string
+		for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+			if strings.TrimSpace(p) != "" {
+				paragraphs = append(paragraphs, strings.Split(strings.TrimSpace(p), "\n"))
+			}
+		}
+		return paragraphs
+	},
+}
+
+func newEmailRenderer(templatesDir string) emailRenderer {
+	r := emailRenderer{
+		templates: make(map[string]map[string]*template.Template),
+	}
+	for _, locale := range common.SUPPORTED_LOCALES {
+		r.templates[locale] = make(map[string]*template.Template)
+		for _, name := range emailTemplateNames {
+			r.templates[locale][name] = common.LoadHTMLTemplate(emailTemplateFuncs,
+				filepath.Join(templatesDir, "layouts", "base.html"),
+				filepath.Join(templatesDir, locale, "partials.html"),
+				filepath.Join(templatesDir, locale, name+".html"),
+			)
+		}
+	}
+
+	return r
+}
+
+// Renders the subject, html body (with inlined css) and plain-text body of the
+// `templateName` email, unsupported locales fall back as in fiddlers.ResolveLocale
+func (r *emailRenderer) render(locale string, templateName string, vars any) (string, string, string, error) {
+	locale = fiddlers.ResolveLocale(locale)
+
+	t, ok := r.templates[locale][templateName]
+	if !ok {
+		return "", "", "", common.ErrEmailTemplateNotFound
+	}
+
+	body := new(bytes.Buffer)
+	err := t.ExecuteTemplate(body, "base", vars)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	html, err := fiddlers.InlineCss(body.String())
+	if err != nil {
+		return "", "", "", err
+	}
+
+	text, err := fiddlers.HtmlToText(html)
+	if err != nil {
+		return "", "", "", err
+	}
 
-	emailConfirmationTemplate  *template.Template
-	accountCreationTemplate    *template.Template
-	organizationInviteTemplate *template.Template
-	passwordResetTemplate      *template.Template
-	paymentAcceptedTemplate    *template.Template
+	subject := emailSubjects[locale][templateName]
+	if t.Lookup("subject") != nil {
+		subjectBuf := new(bytes.Buffer)
+		err = t.ExecuteTemplate(subjectBuf, "subject", vars)
+		if err != nil {
+			return "", "", "", err
+		}
+		// the block is escaped as html, the subject header is plain text
+		subject = htmlpkg.UnescapeString(strings.TrimSpace(subjectBuf.String()))
+	}
+
+	return subject, html, text, nil
+}
+
+// Delivery that renders the email and sends it right away
+func (r *emailRenderer) sendWith(sender EmailSender) emailDelivery {
+	return func(ctx context.Context, email string, locale string, templateName string, vars any) (err error) {
+		ctx, span := tracing.Start(ctx, "EmailService.send", attribute.String("email.template", templateName))
+		defer func() { tracing.End(span, err) }()
+
+		subject, html, text, err := r.render(locale, templateName, vars)
+		if err != nil {
+			common.Logger(ctx).Error(err.Error())
+			return err
+		}
+
+		err = sender.SendMessage(ctx, email, subject, html, text)
+		if err != nil {
+			metrics.EmailsSent.WithLabelValues(metrics.EMAIL_OUTCOME_FAILED).Inc()
+			return err
+		}
+
+		metrics.EmailsSent.WithLabelValues(metrics.EMAIL_OUTCOME_SENT).Inc()
+		return nil
+	}
+}
+
+type emailDelivery func(ctx context.Context, email string, locale string, templateName string, vars any) error
+
+// emailComposer builds the template vars shared by every EmailService
+// implementation and hands them to deliver
+type emailComposer struct {
+	deliver emailDelivery
 
 	usersConfirmUrl  string
 	acceptInviteUrl  string
 	passwordResetUrl string
 }
 
-func NewEmailServiceResendImpl(resendApiKey string, templatesDir string) EmailService {
+func newEmailComposer(deliver emailDelivery) emailComposer {
 	usersConfirmUrl, err := url.JoinPath(common.API_HOST_URL, "/v1/users/confirm")
 	if err != nil {
 		panic(err)
@@ -42,16 +181,11 @@ func NewEmailServiceResendImpl(resendApiKey string, templatesDir string) EmailSe
 		panic(err)
 	}
 
-	return &EmailServiceResendImpl{
-		resendClient:               resend.NewClient(resendApiKey),
-		emailConfirmationTemplate:  common.LoadHTMLTemplate(filepath.Join(templatesDir, "email-confirmation.html")),
-		accountCreationTemplate:    common.LoadHTMLTemplate(filepath.Join(templatesDir, "account-created.html")),
-		organizationInviteTemplate: common.LoadHTMLTemplate(filepath.Join(templatesDir, "organization-invite.html")),
-		passwordResetTemplate:      common.LoadHTMLTemplate(filepath.Join(templatesDir, "password-reset.html")),
-		paymentAcceptedTemplate:    common.LoadHTMLTemplate(filepath.Join(templatesDir, "payment-accepted.html")),
-		usersConfirmUrl:            usersConfirmUrl,
-		acceptInviteUrl:            acceptInviteUrl,
-		passwordResetUrl:           passwordResetUrl,
+	return emailComposer{
+		deliver:          deliver,
+		usersConfirmUrl:  usersConfirmUrl,
+		acceptInviteUrl:  acceptInviteUrl,
+		passwordResetUrl: passwordResetUrl,
 	}
 }
 
@@ -61,54 +195,22 @@ type htmlConfirmationVars struct {
 	OtpUrl      string
 }
 
-func (s *EmailServiceResendImpl) SendEmailConfirmation(email string, name string, otp string) error {
-	body := new(bytes.Buffer)
-	err := s.emailConfirmationTemplate.Execute(body, htmlConfirmationVars{
+func (c *emailComposer) SendEmailConfirmation(ctx context.Context, email string, locale string, name string, otp string) error {
+	return c.deliver(ctx, email, locale, common.EMAIL_TEMPLATE_EMAIL_CONFIRMATION, htmlConfirmationVars{
 		ProjectName: common.PROJECT_NAME,
 		FirstName:   name,
-		OtpUrl:      s.usersConfirmUrl + "?otp=" + otp,
+		OtpUrl:      c.usersConfirmUrl + "?otp=" + otp,
 	})
-	if err != nil {
-		slog.Error(err.Error())
-		return err
-	}
-
-	params := &resend.SendEmailRequest{
-		From:    common.NOREPLY_EMAIL,
-		To:      []string{email},
-		Subject: "Confirm Your Account!",
-		Html:    body.String(),
-	}
-
-	_, err = s.resendClient.Emails.Send(params)
-
-	return err
 }
 
 type htmlAccountCreatedVars struct {
 	FirstName string
 }
 
-func (s *EmailServiceResendImpl) SendAccountCreated(email string, name string) error {
-	body := new(bytes.Buffer)
-	err := s.accountCreationTemplate.Execute(body, htmlAccountCreatedVars{
+func (c *emailComposer) SendAccountCreated(ctx context.Context, email string, locale string, name string) error {
+	return c.deliver(ctx, email, locale, common.EMAIL_TEMPLATE_ACCOUNT_CREATED, htmlAccountCreatedVars{
 		FirstName: name,
 	})
-	if err != nil {
-		slog.Error(err.Error())
-		return err
-	}
-
-	params := &resend.SendEmailRequest{
-		From:    common.NOREPLY_EMAIL,
-		To:      []string{email},
-		Subject: "Account Created!",
-		Html:    body.String(),
-	}
-
-	_, err = s.resendClient.Emails.Send(params)
-
-	return err
 }
 
 type htmlOrgInviteVars struct {
@@ -118,29 +220,13 @@ type htmlOrgInviteVars struct {
 	OtpUrl           string
 }
 
-func (s *EmailServiceResendImpl) SendOrganizationInvite(email string, name string, otp string, orgName string) error {
-	body := new(bytes.Buffer)
-	err := s.organizationInviteTemplate.Execute(body, htmlOrgInviteVars{
+func (c *emailComposer) SendOrganizationInvite(ctx context.Context, email string, locale string, name string, otp string, orgName string) error {
+	return c.deliver(ctx, email, locale, common.EMAIL_TEMPLATE_ORGANIZATION_INVITE, htmlOrgInviteVars{
 		ProjectName:      common.PROJECT_NAME,
 		OrganizationName: orgName,
 		FirstName:        name,
-		OtpUrl:           s.acceptInviteUrl + "?otp=" + otp,
+		OtpUrl:           c.acceptInviteUrl + "?otp=" + otp,
 	})
-	if err != nil {
-		slog.Error(err.Error())
-		return err
-	}
-
-	params := &resend.SendEmailRequest{
-		From:    common.NOREPLY_EMAIL,
-		To:      []string{email},
-		Subject: "Organization Invite",
-		Html:    body.String(),
-	}
-
-	_, err = s.resendClient.Emails.Send(params)
-
-	return err
 }
 
 type htmlPwResetVars struct {
@@ -149,28 +235,20 @@ type htmlPwResetVars struct {
 	OtpUrl      string
 }
 
-func (s *EmailServiceResendImpl) SendPasswordReset(email string, name string, otp string) error {
-	body := new(bytes.Buffer)
-	err := s.passwordResetTemplate.Execute(body, htmlPwResetVars{
+func (c *emailComposer) SendPasswordReset(ctx context.Context, email string, locale string, name string, otp string) error {
+	return c.deliver(ctx, email, locale, common.EMAIL_TEMPLATE_PASSWORD_RESET, htmlPwResetVars{
 		ProjectName: common.PROJECT_NAME,
 		FirstName:   name,
-		OtpUrl:      s.passwordResetUrl + "?otp=" + otp,
+		OtpUrl:      c.passwordResetUrl + "?otp=" + otp,
 	})
-	if err != nil {
-		slog.Error(err.Error())
-		return err
-	}
-
-	params := &resend.SendEmailRequest{
-		From:    common.NOREPLY_EMAIL,
-		To:      []string{email},
-		Subject: "Password Reset",
-		Html:    body.String(),
-	}
-
-	_, err = s.resendClient.Emails.Send(params)
+}
 
-	return err
+type htmlAnnouncementVars struct {
+	FirstName      string
+	EventName      string
+	Subject        string
+	Body           string
+	UnsubscribeUrl string
 }
 
 type htmlPaymentAccepted struct {
@@ -178,25 +256,48 @@ type htmlPaymentAccepted struct {
 	PaymentId string
 }
 
-func (s *EmailServiceResendImpl) SendPaymentAccepted(email string, name string, payment models.Payment) error {
-	body := new(bytes.Buffer)
-	err := s.paymentAcceptedTemplate.Execute(body, htmlPaymentAccepted{
+func (c *emailComposer) SendPaymentAccepted(ctx context.Context, email string, locale string, name string, payment models.Payment) error {
+	return c.deliver(ctx, email, locale, common.EMAIL_TEMPLATE_PAYMENT_ACCEPTED, newPaymentAcceptedVars(name, payment))
+}
+
+func newPaymentAcceptedVars(name string, payment models.Payment) htmlPaymentAccepted {
+	return htmlPaymentAccepted{
 		FirstName: name,
 		PaymentId: payment.PaymentId,
-	})
-	if err != nil {
-		slog.Error(err.Error())
-		return err
 	}
+}
 
-	params := &resend.SendEmailRequest{
-		From:    common.NOREPLY_EMAIL,
-		To:      []string{email},
-		Subject: "Payment Accepted",
-		Html:    body.String(),
-	}
-
-	_, err = s.resendClient.Emails.Send(params)
-
-	return err
+// Fixed vars to render each email template with, for the golden files and
+// previews, so they do not depend on the environment
+var emailSampleVars = map[string]any{
+	common.EMAIL_TEMPLATE_EMAIL_CONFIRMATION: htmlConfirmationVars{
+		ProjectName: "patos-app",
+		FirstName:   "Ana",
+		OtpUrl:      "https://api.patos.dev/v1/users/confirm?otp=sample",
+	},
+	common.EMAIL_TEMPLATE_ACCOUNT_CREATED: htmlAccountCreatedVars{
+		FirstName: "Ana",
+	},
+	common.EMAIL_TEMPLATE_ORGANIZATION_INVITE: htmlOrgInviteVars{
+		ProjectName:      "patos-app",
+		OrganizationName: "PATOS",
+		FirstName:        "Ana",
+		OtpUrl:           "https://api.patos.dev/v1/organizations/accept-invite?otp=sample",
+	},
+	common.EMAIL_TEMPLATE_PASSWORD_RESET: htmlPwResetVars{
+		ProjectName: "patos-app",
+		FirstName:   "Ana",
+		OtpUrl:      "https://api.patos.dev/v1/users/set-password-reset-cookie?otp=sample",
+	},
+	common.EMAIL_TEMPLATE_PAYMENT_ACCEPTED: htmlPaymentAccepted{
+		FirstName: "Ana",
+		PaymentId: "pay_sample",
+	},
+	common.EMAIL_TEMPLATE_ANNOUNCEMENT: htmlAnnouncementVars{
+		FirstName:      "Ana",
+		EventName:      "Quack Week",
+		Subject:        "Room change",
+		Body:           "The opening talk moved to room 42.\nDoors open at 9:00.\n\nSee you there!",
+		UnsubscribeUrl: "https://api.patos.dev/v1/users/unsubscribe?token=sample",
+	},
 }
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/metrics"
+	"github.com/patos-ufscar/quack-week/models"
+	"github.com/patos-ufscar/quack-week/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// enqueueEmail queues an email in the outbox and returns its id, pass a *sql.Tx
+// so it is only sent if the business change it belongs to is committed
+func enqueueEmail(ctx context.Context, db Querier, priority int, email string, locale string, templateName string, vars any) (uint64, error) {
+	varsJson, err := json.Marshal(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	var emailId uint64
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO email_outbox (recipient, locale, template_name, template_vars, priority)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING email_id;
+		`,
+		email,
+		fiddlers.ResolveLocale(locale),
+		templateName,
+		string(varsJson),
+		priority,
+	).Scan(&emailId)
+
+	return emailId, err
+}
+
+// EmailServiceOutboxImpl queues the emails, see EmailOutboxServicePgImpl for the delivery
+type EmailServiceOutboxImpl struct {
+	emailComposer
+
+	db *sql.DB
+}
+
+func NewEmailServiceOutboxImpl(db *sql.DB) EmailService {
+	s := &EmailServiceOutboxImpl{
+		db: db,
+	}
+	s.emailComposer = newEmailComposer(s.enqueue)
+
+	return s
+}
+
+// enqueue joins the transaction of ctx, if any, so the email is only sent
+// once the change it belongs to is committed
+func (s *EmailServiceOutboxImpl) enqueue(ctx context.Context, email string, locale string, templateName string, vars any) error {
+	_, err := enqueueEmail(ctx, querier(ctx, s.db), common.EMAIL_PRIORITY_TRANSACTIONAL, email, locale, templateName, vars)
+	return err
+}
+
+type EmailOutboxServicePgImpl struct {
+	db       *sql.DB
+	renderer emailRenderer
+	sender   EmailSender
+	// minimum time between two sends, to respect the provider rate limit
+	sendInterval time.Duration
+}
+
+// `ratePerSec` caps the emails sent per second, 0 means no limit
+func NewEmailOutboxServicePgImpl(db *sql.DB, templatesDir string, sender EmailSender, ratePerSec int) EmailOutboxService {
+	var sendInterval time.Duration
+	if ratePerSec > 0 {
+		sendInterval = time.Second / time.Duration(ratePerSec)
+	}
+
+	return &EmailOutboxServicePgImpl{
+		db:           db,
+		renderer:     newEmailRenderer(templatesDir),
+		sender:       sender,
+		sendInterval: sendInterval,
+	}
+}
+
+func (s *EmailOutboxServicePgImpl) DeliverPending() error {
+	ctx := context.Background()
+
+	emails, err := s.claimDueEmails(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	var lastSend time.Time
+	for _, e := range emails {
+		time.Sleep(s.sendInterval - time.Since(lastSend))
+		lastSend = time.Now()
+
+		sendCtx, span := tracing.Start(ctx, "EmailOutboxService.send",
+			attribute.Int64("email.id", int64(e.EmailId)),
+			attribute.String("email.template", e.TemplateName),
+		)
+		sendErr := s.send(sendCtx, e)
+		if sendErr != nil {
+			common.Logger(ctx).Warn(fmt.Sprintf("email %d attempt %d failed: %s", e.EmailId, e.Attempts+1, sendErr.Error()))
+		}
+
+		err := s.recordAttempt(sendCtx, e, sendErr)
+		tracing.End(span, sendErr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recording email %d attempt: %w", e.EmailId, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Leases the due emails by pushing their next attempt forward, so other
+// replicas skip them while they are being sent
+func (s *EmailOutboxServicePgImpl) claimDueEmails(ctx context.Context) ([]models.OutboxEmail, error) {
+	emails := []models.OutboxEmail{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		UPDATE email_outbox
+		SET next_attempt_at = NOW() + $1::INT * INTERVAL '1 minute'
+		WHERE email_id IN (
+			SELECT email_id
+			FROM email_outbox
+			WHERE
+				email_status = $2 AND
+				next_attempt_at <= NOW()
+			ORDER BY priority, next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+outboxEmailColumns+`;
+		`,
+		common.EMAIL_OUTBOX_LEASE_MINS,
+		common.EMAIL_STATUS_PENDING,
+		common.EMAIL_OUTBOX_BATCH,
+	)
+	if err != nil {
+		return emails, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanOutboxEmail(rows)
+		if err != nil {
+			return emails, err
+		}
+		emails = append(emails, e)
+	}
+
+	return emails, rows.Err()
+}
+
+func (s *EmailOutboxServicePgImpl) send(ctx context.Context, e models.OutboxEmail) error {
+	var vars map[string]any
+	err := json.Unmarshal(e.TemplateVars, &vars)
+	if err != nil {
+		return err
+	}
+
+	subject, html, text, err := s.renderer.render(e.Locale, e.TemplateName, vars)
+	if err != nil {
+		return err
+	}
+
+	return s.sender.SendMessage(ctx, e.Recipient, subject, html, text)
+}
+
+// Marks the email as sent, or schedules the retry, dead-lettering it after
+// `common.EMAIL_OUTBOX_MAX_ATTEMPTS` failures
+func (s *EmailOutboxServicePgImpl) recordAttempt(ctx context.Context, e models.OutboxEmail, sendErr error) error {
+	if sendErr == nil {
+		_, err := querier(ctx, s.db).ExecContext(ctx, `
+			UPDATE email_outbox
+			SET
+				email_status = $1,
+				attempts = attempts + 1,
+				sent_at = NOW()
+			WHERE email_id = $2;
+			`,
+			common.EMAIL_STATUS_SENT,
+			e.EmailId,
+		)
+		metrics.EmailsSent.WithLabelValues(metrics.EMAIL_OUTCOME_SENT).Inc()
+		return err
+	}
+
+	attempts := int(e.Attempts) + 1
+	status := common.EMAIL_STATUS_PENDING
+	outcome := metrics.EMAIL_OUTCOME_FAILED
+	if attempts >= common.EMAIL_OUTBOX_MAX_ATTEMPTS {
+		status = common.EMAIL_STATUS_DEAD
+		outcome = metrics.EMAIL_OUTCOME_DEAD
+	}
+	metrics.EmailsSent.WithLabelValues(outcome).Inc()
+
+	_, err := querier(ctx, s.db).ExecContext(ctx, `
+		UPDATE email_outbox
+		SET
+			email_status = $1,
+			attempts = $2,
+			last_error = $3,
+			next_attempt_at = $4
+		WHERE email_id = $5;
+		`,
+		status,
+		attempts,
+		sendErr.Error(),
+		time.Now().Add(fiddlers.GetEmailRetryDelay(attempts)),
+		e.EmailId,
+	)
+
+	return err
+}
+
+func (s *EmailOutboxServicePgImpl) GetDeadEmails(ctx context.Context, limit int, offset int) ([]models.OutboxEmail, error) {
+	emails := []models.OutboxEmail{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT `+outboxEmailColumns+`
+		FROM email_outbox
+		WHERE email_status = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+		OFFSET $3;
+		`,
+		common.EMAIL_STATUS_DEAD,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return emails, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanOutboxEmail(rows)
+		if err != nil {
+			return emails, err
+		}
+		emails = append(emails, e)
+	}
+
+	return emails, rows.Err()
+}
+
+// Gives a dead email a fresh set of attempts
+func (s *EmailOutboxServicePgImpl) RetryEmail(ctx context.Context, emailId uint64) error {
+	res, err := querier(ctx, s.db).ExecContext(ctx, `
+		UPDATE email_outbox
+		SET
+			email_status = $1,
+			attempts = 0,
+			next_attempt_at = NOW()
+		WHERE
+			email_id = $2 AND
+			email_status = $3;
+		`,
+		common.EMAIL_STATUS_PENDING,
+		emailId,
+		common.EMAIL_STATUS_DEAD,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return common.ErrNotFound
+	}
+
+	return nil
+}
+
+const outboxEmailColumns = `
+			email_id,
+			recipient,
+			locale,
+			template_name,
+			template_vars,
+			email_status,
+			attempts,
+			last_error,
+			next_attempt_at,
+			created_at,
+			sent_at`
+
+func scanOutboxEmail(row interface{ Scan(dest ...any) error }) (models.OutboxEmail, error) {
+	e := models.OutboxEmail{}
+	err := row.Scan(
+		&e.EmailId,
+		&e.Recipient,
+		&e.Locale,
+		&e.TemplateName,
+		&e.TemplateVars,
+		&e.EmailStatus,
+		&e.Attempts,
+		&e.LastError,
+		&e.NextAttemptAt,
+		&e.CreatedAt,
+		&e.SentAt,
+	)
+
+	return e, err
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/helpers"
+)
+
+type fakeEmailSender struct {
+	err  error
+	sent []string
+}
+
+func (f *fakeEmailSender) SendMessage(ctx context.Context, email string, subject string, html string, text string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, email+": "+subject+": "+html)
+	return nil
+}
+
+func TestEmailOutboxServicePgImpl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	sender := &fakeEmailSender{err: errors.New("provider down")}
+	emailService := NewEmailServiceOutboxImpl(pgContainer.DB)
+	outbox := NewEmailOutboxServicePgImpl(pgContainer.DB, "../templates", sender, 0)
+
+	err = emailService.SendAccountCreated(ctx, "user@email.com", common.LOCALE_EN, "Ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// failures are retried later
+	err = outbox.DeliverPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status string
+	var attempts int
+	err = pgContainer.DB.QueryRow(`SELECT email_status, attempts FROM email_outbox`).Scan(&status, &attempts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != common.EMAIL_STATUS_PENDING || attempts != 1 {
+		t.Errorf("after failure got status %s attempts %d, want %s 1", status, attempts, common.EMAIL_STATUS_PENDING)
+	}
+
+	// not due yet
+	err = outbox.DeliverPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pgContainer.DB.QueryRow(`SELECT attempts FROM email_outbox`).Scan(&attempts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+
+	// last attempt dead-letters it
+	_, err = pgContainer.DB.Exec(`UPDATE email_outbox SET attempts = $1, next_attempt_at = NOW()`, common.EMAIL_OUTBOX_MAX_ATTEMPTS-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = outbox.DeliverPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dead, err := outbox.GetDeadEmails(ctx, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("GetDeadEmails() len = %d, want 1", len(dead))
+	}
+	if dead[0].LastError == nil || *dead[0].LastError != "provider down" {
+		t.Errorf("LastError = %v, want provider down", dead[0].LastError)
+	}
+
+	// retried by an admin once the provider is back
+	sender.err = nil
+	err = outbox.RetryEmail(ctx, dead[0].EmailId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = outbox.RetryEmail(ctx, dead[0].EmailId)
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("RetryEmail() on pending email error = %v, want %v", err, common.ErrNotFound)
+	}
+
+	err = outbox.DeliverPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(sender.sent))
+	}
+	if !strings.Contains(sender.sent[0], "user@email.com: Account Created!") || !strings.Contains(sender.sent[0], "Ana") {
+		t.Errorf("sent %q", sender.sent[0])
+	}
+
+	err = pgContainer.DB.QueryRow(`SELECT email_status FROM email_outbox`).Scan(&status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != common.EMAIL_STATUS_SENT {
+		t.Errorf("email_status = %s, want %s", status, common.EMAIL_STATUS_SENT)
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type EmailPreviewServiceImpl struct {
+	renderer emailRenderer
+	sender   EmailSender
+}
+
+func NewEmailPreviewServiceImpl(templatesDir string, sender EmailSender) EmailPreviewService {
+	return &EmailPreviewServiceImpl{
+		renderer: newEmailRenderer(templatesDir),
+		sender:   sender,
+	}
+}
+
+func (s *EmailPreviewServiceImpl) GetEmailTemplates() []models.EmailTemplate {
+	templates := []models.EmailTemplate{}
+	for name := range emailSampleVars {
+		sampleVars, _ := sampleVarsWith(name, nil)
+		templates = append(templates, models.EmailTemplate{
+			TemplateName: name,
+			Locales:      common.SUPPORTED_LOCALES,
+			SampleVars:   sampleVars,
+		})
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].TemplateName < templates[j].TemplateName
+	})
+
+	return templates
+}
+
+func (s *EmailPreviewServiceImpl) PreviewEmail(templateName string, locale string, vars map[string]any) (models.EmailPreview, error) {
+	templateVars, err := sampleVarsWith(templateName, vars)
+	if err != nil {
+		return models.EmailPreview{}, err
+	}
+
+	subject, html, text, err := s.renderer.render(locale, templateName, templateVars)
+	if err != nil {
+		return models.EmailPreview{}, err
+	}
+
+	return models.EmailPreview{
+		Subject: subject,
+		Html:    html,
+		Text:    text,
+	}, nil
+}
+
+// Sends the preview right away, skipping the outbox, so the admin sees
+// delivery errors
+func (s *EmailPreviewServiceImpl) SendTestEmail(ctx context.Context, email string, templateName string, locale string, vars map[string]any) error {
+	preview, err := s.PreviewEmail(templateName, locale, vars)
+	if err != nil {
+		return err
+	}
+
+	return s.sender.SendMessage(ctx, email, common.EMAIL_TEST_SUBJECT_PREFIX+preview.Subject, preview.Html, preview.Text)
+}
+
+// The sample vars of `templateName` as a map, overridden by `vars`. The keys are
+// the same ones stored in the outbox.
+func sampleVarsWith(templateName string, vars map[string]any) (map[string]any, error) {
+	sample, ok := emailSampleVars[templateName]
+	if !ok {
+		return nil, common.ErrEmailTemplateNotFound
+	}
+
+	sampleJson, err := json.Marshal(sample)
+	if err != nil {
+		return nil, err
+	}
+
+	templateVars := make(map[string]any)
+	err = json.Unmarshal(sampleJson, &templateVars)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range vars {
+		templateVars[k] = v
+	}
+
+	return templateVars, nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+func TestEmailPreviewServiceImpl(t *testing.T) {
+	tests := []struct {
+		name         string
+		templateName string
+		locale       string
+		vars         map[string]any
+		wantErr      error
+		wantContains string
+	}{
+		{"sample vars", common.EMAIL_TEMPLATE_PAYMENT_ACCEPTED, common.LOCALE_EN, nil, nil, "pay_sample"},
+		{"custom vars", common.EMAIL_TEMPLATE_PAYMENT_ACCEPTED, common.LOCALE_EN, map[string]any{"PaymentId": "pay_custom"}, nil, "pay_custom"},
+		{"partial vars keep the samples", common.EMAIL_TEMPLATE_ORGANIZATION_INVITE, common.LOCALE_PT_BR, map[string]any{"FirstName": "Bia"}, nil, "PATOS"},
+		{"unknown template", "welcome-back", common.LOCALE_EN, nil, common.ErrEmailTemplateNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeEmailSender{}
+			s := NewEmailPreviewServiceImpl("../templates", sender)
+
+			preview, err := s.PreviewEmail(tt.templateName, tt.locale, tt.vars)
+			if err != tt.wantErr {
+				t.Fatalf("PreviewEmail() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !strings.Contains(preview.Html, tt.wantContains) || !strings.Contains(preview.Text, tt.wantContains) {
+				t.Errorf("PreviewEmail() does not contain %q", tt.wantContains)
+			}
+
+			err = s.SendTestEmail(context.Background(), "admin@example.com", tt.templateName, tt.locale, tt.vars)
+			if err != nil {
+				t.Fatalf("SendTestEmail() error = %v", err)
+			}
+			if len(sender.sent) != 1 || !strings.HasPrefix(sender.sent[0], "admin@example.com: [TEST] "+preview.Subject) {
+				t.Errorf("SendTestEmail() sent %v", sender.sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/tracing"
+	"github.com/resendlabs/resend-go"
+)
+
+type EmailServiceResendImpl struct {
+	emailComposer
+
+	resendClient *resend.Client
+}
+
+func NewEmailServiceResendImpl(resendApiKey string, templatesDir string) EmailTransport {
+	s := &EmailServiceResendImpl{
+		resendClient: resend.NewCustomClient(tracing.HttpClient(), resendApiKey),
+	}
+	renderer := newEmailRenderer(templatesDir)
+	s.emailComposer = newEmailComposer(renderer.sendWith(s))
+
+	return s
+}
+
+// Emails.Send takes no context, the request is built by hand so it carries
+// the trace and is canceled along with ctx
+func (s *EmailServiceResendImpl) SendMessage(ctx context.Context, email string, subject string, html string, text string) error {
+	params := &resend.SendEmailRequest{
+		From:    common.NOREPLY_EMAIL,
+		To:      []string{email},
+		Subject: subject,
+		Html:    html,
+		Text:    text,
+	}
+
+	req, err := s.resendClient.NewRequest(http.MethodPost, "emails", params)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.resendClient.Perform(req.WithContext(ctx), &resend.SendEmailResponse{})
+
+	return err
+}
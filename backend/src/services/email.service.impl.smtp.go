@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type SmtpConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// one of common.SMTP_SECURITY_NONE, common.SMTP_SECURITY_STARTTLS or common.SMTP_SECURITY_TLS
+	Security string
+}
+
+// EmailServiceSmtpImpl sends emails through any SMTP relay, e.g. Mailpit in local development
+type EmailServiceSmtpImpl struct {
+	emailComposer
+
+	config    SmtpConfig
+	tlsConfig *tls.Config
+}
+
+func NewEmailServiceSmtpImpl(config SmtpConfig, templatesDir string) EmailTransport {
+	switch config.Security {
+	case common.SMTP_SECURITY_NONE, common.SMTP_SECURITY_STARTTLS, common.SMTP_SECURITY_TLS:
+	default:
+		panic(fmt.Sprintf("unknown SMTP security mode: %s", config.Security))
+	}
+
+	s := &EmailServiceSmtpImpl{
+		config:    config,
+		tlsConfig: &tls.Config{ServerName: config.Host},
+	}
+	renderer := newEmailRenderer(templatesDir)
+	s.emailComposer = newEmailComposer(renderer.sendWith(s))
+
+	return s
+}
+
+func (s *EmailServiceSmtpImpl) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.Host, s.config.Port)
+	timeout := time.Duration(common.SMTP_TIMEOUT_SECS) * time.Second
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if s.config.Security == common.SMTP_SECURITY_TLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// SMTP is not HTTP, the span of the relay conversation is started here
+func (s *EmailServiceSmtpImpl) SendMessage(ctx context.Context, email string, subject string, html string, text string) (err error) {
+	ctx, span := tracing.Start(ctx, "smtp send",
+		attribute.String("server.address", s.config.Host),
+		attribute.String("server.port", s.config.Port),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	messageId, err := common.GenerateRandomString(32)
+	if err != nil {
+		return err
+	}
+	if _, domain, ok := strings.Cut(common.NOREPLY_EMAIL, "@"); ok {
+		messageId += "@" + domain
+	}
+
+	body, err := fiddlers.BuildMimeMessage(common.NOREPLY_EMAIL, email, subject, html, text, time.Now(), messageId)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.config.Security == common.SMTP_SECURITY_STARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return common.ErrSmtpStartTlsUnsupported
+		}
+		err = client.StartTLS(s.tlsConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.config.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(common.NOREPLY_EMAIL)
+	if err != nil {
+		return err
+	}
+
+	err = client.Rcpt(email)
+	if err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(body)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
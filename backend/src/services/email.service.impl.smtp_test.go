@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestEmailServiceSmtpImpl(t *testing.T) {
+	server := helpers.NewFakeSmtpServer()
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		send        func(s EmailService) error
+		wantSubject string
+		wantBody    string
+	}{
+		{
+			"email confirmation",
+			func(s EmailService) error {
+				return s.SendEmailConfirmation(context.Background(), "user@example.com", common.LOCALE_EN, "Ana", "otp123")
+			},
+			"Confirm Your Account!",
+			"/v1/users/confirm?otp=otp123",
+		},
+		{
+			"email confirmation in portuguese",
+			func(s EmailService) error {
+				return s.SendEmailConfirmation(context.Background(), "user@example.com", common.LOCALE_PT_BR, "Ana", "otp123")
+			},
+			"Confirme sua conta!",
+			"CONFIRMAR EMAIL",
+		},
+		{
+			"unsupported locale falls back to the default",
+			func(s EmailService) error {
+				return s.SendPasswordReset(context.Background(), "user@example.com", "fr-FR", "Ana", "otp000")
+			},
+			"Recuperação de Senha",
+			"RECUPERAR SENHA",
+		},
+		{
+			"organization invite",
+			func(s EmailService) error {
+				return s.SendOrganizationInvite(context.Background(), "user@example.com", common.LOCALE_EN, "Ana", "otp456", "Patos")
+			},
+			"Organization Invite",
+			"Patos",
+		},
+		{
+			"payment accepted",
+			func(s EmailService) error {
+				return s.SendPaymentAccepted(context.Background(), "user@example.com", "en-US", "Ana", models.Payment{PaymentId: "pay_789"})
+			},
+			"Payment Accepted",
+			"pay_789",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewEmailServiceSmtpImpl(SmtpConfig{
+				Host:     server.Host,
+				Port:     server.Port,
+				Username: "user",
+				Password: "pass",
+				Security: common.SMTP_SECURITY_NONE,
+			}, "../templates")
+
+			before := len(server.Messages())
+			err := tt.send(s)
+			if err != nil {
+				t.Fatalf("send error = %v", err)
+			}
+
+			messages := server.Messages()
+			if len(messages) != before+1 {
+				t.Fatalf("got %d messages, want %d", len(messages), before+1)
+			}
+			got := messages[len(messages)-1]
+
+			if got.From != common.NOREPLY_EMAIL {
+				t.Errorf("From = %q, want %q", got.From, common.NOREPLY_EMAIL)
+			}
+			if len(got.To) != 1 || got.To[0] != "user@example.com" {
+				t.Errorf("To = %v, want [user@example.com]", got.To)
+			}
+
+			msg, err := mail.ReadMessage(bytes.NewReader(got.Data))
+			if err != nil {
+				t.Fatalf("mail.ReadMessage() error = %v", err)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if err != nil {
+				t.Fatalf("DecodeHeader() error = %v", err)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", subject, tt.wantSubject)
+			}
+			_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			if err != nil {
+				t.Fatalf("ParseMediaType() error = %v", err)
+			}
+			// both the plain-text and the html parts carry the content
+			mr := multipart.NewReader(msg.Body, params["boundary"])
+			for _, contentType := range []string{"text/plain", "text/html"} {
+				part, err := mr.NextRawPart()
+				if err != nil {
+					t.Fatalf("NextRawPart() error = %v", err)
+				}
+				if !strings.HasPrefix(part.Header.Get("Content-Type"), contentType) {
+					t.Errorf("part Content-Type = %q, want %q", part.Header.Get("Content-Type"), contentType)
+				}
+				body, err := io.ReadAll(quotedprintable.NewReader(part))
+				if err != nil {
+					t.Fatalf("body read error = %v", err)
+				}
+				if !strings.Contains(string(body), tt.wantBody) {
+					t.Errorf("%s body does not contain %q", contentType, tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func TestEmailServiceSmtpImplStartTlsUnsupported(t *testing.T) {
+	server := helpers.NewFakeSmtpServer()
+	defer server.Close()
+
+	s := NewEmailServiceSmtpImpl(SmtpConfig{
+		Host:     server.Host,
+		Port:     server.Port,
+		Security: common.SMTP_SECURITY_STARTTLS,
+	}, "../templates")
+
+	err := s.SendAccountCreated(context.Background(), "user@example.com", common.LOCALE_EN, "Ana")
+	if !errors.Is(err, common.ErrSmtpStartTlsUnsupported) {
+		t.Errorf("SendAccountCreated() error = %v, want %v", err, common.ErrSmtpStartTlsUnsupported)
+	}
+	if len(server.Messages()) != 0 {
+		t.Errorf("got %d messages, want 0", len(server.Messages()))
+	}
+}
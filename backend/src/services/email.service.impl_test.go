@@ -0,0 +1,113 @@
+package services
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Renders every email in every locale and compares it with
+// testdata/emails/<locale>/<name>.{html,txt}, run with -update after changing
+// the templates
+func TestEmailRendererGolden(t *testing.T) {
+	r := newEmailRenderer("../templates")
+
+	for _, locale := range common.SUPPORTED_LOCALES {
+		for name, vars := range emailSampleVars {
+			t.Run(locale+"/"+name, func(t *testing.T) {
+				subject, html, text, err := r.render(locale, name, vars)
+				if err != nil {
+					t.Fatalf("render() error = %v", err)
+				}
+				if subject == "" {
+					t.Errorf("render() subject is empty")
+				}
+				if strings.Contains(html, "<style>") {
+					t.Errorf("render() html still has a <style> element")
+				}
+
+				goldenBase := filepath.Join("testdata", "emails", locale, name)
+				compareGolden(t, goldenBase+".html", html)
+				compareGolden(t, goldenBase+".txt", text)
+			})
+		}
+	}
+}
+
+func TestEmailRendererEscapes(t *testing.T) {
+	r := newEmailRenderer("../templates")
+
+	_, html, text, err := r.render(common.LOCALE_EN, common.EMAIL_TEMPLATE_ORGANIZATION_INVITE, htmlOrgInviteVars{
+		FirstName:        "<script>alert(1)</script>",
+		OrganizationName: "Patos & <b>Co</b>",
+		OtpUrl:           "javascript:alert(1)",
+	})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	for _, unsafe := range []string{"<script>", "<b>Co</b>", `href="javascript:`} {
+		if strings.Contains(html, unsafe) {
+			t.Errorf("render() html contains %q", unsafe)
+		}
+	}
+	if !strings.Contains(text, "Patos & <b>Co</b>") {
+		t.Errorf("render() text = %q, want the organization name as typed", text)
+	}
+}
+
+func TestEmailRendererSubject(t *testing.T) {
+	r := newEmailRenderer("../templates")
+
+	tests := []struct {
+		name         string
+		locale       string
+		templateName string
+		vars         any
+		want         string
+	}{
+		{"fixed subject", common.LOCALE_EN, common.EMAIL_TEMPLATE_PASSWORD_RESET, emailSampleVars[common.EMAIL_TEMPLATE_PASSWORD_RESET], "Password Reset"},
+		{"subject block", common.LOCALE_PT_BR, common.EMAIL_TEMPLATE_ANNOUNCEMENT, htmlAnnouncementVars{EventName: "Quack Week", Subject: "Salas & horários"}, "[Quack Week] Salas & horários"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, _, _, err := r.render(tt.locale, tt.templateName, tt.vars)
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+			if subject != tt.want {
+				t.Errorf("render() subject = %q, want %q", subject, tt.want)
+			}
+		})
+	}
+}
+
+func compareGolden(t *testing.T, path string, got string) {
+	t.Helper()
+
+	if *updateGolden {
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(got), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v, run the test with -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the rendered email, run the test with -update if the change is intended\ngot:\n%s", path, got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/patos-ufscar/quack-week/common"
 	"github.com/patos-ufscar/quack-week/models"
 )
 
@@ -19,7 +20,14 @@ func NewEventServicePgImpl(db *sql.DB) EventService {
 
 func (s *EventServicePgImpl) CreateEvent(ctx context.Context, name string, ownerId uint32, orgId string, description string) (models.Event, error) {
 	e := models.Event{}
-	err := s.db.QueryRowContext(ctx, `
+
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return e, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
 		INSERT INTO events (event_name, owner_user_id, owner_organization_id, description)
 		VALUES
 			($1, $2, $3, $4)
@@ -49,13 +57,21 @@ func (s *EventServicePgImpl) CreateEvent(ctx context.Context, name string, owner
 		&e.Exp,
 		&e.Description,
 	)
+	if err != nil {
+		return e, err
+	}
 
-	return e, err
+	err = enqueueWebhooks(ctx, tx, orgId, common.WEBHOOK_EVENT_EVENT_PUBLISHED, e)
+	if err != nil {
+		return e, err
+	}
+
+	return e, tx.Commit()
 }
 
 func (s *EventServicePgImpl) GetEvent(ctx context.Context, eventId string) (models.Event, error) {
 	e := models.Event{}
-	err := s.db.QueryRowContext(ctx, `
+	err := querier(ctx, s.db).QueryRowContext(ctx, `
 		SELECT
 			event_id,
 			event_name,
@@ -84,7 +100,7 @@ func (s *EventServicePgImpl) GetEvent(ctx context.Context, eventId string) (mode
 }
 
 func (s *EventServicePgImpl) SetCover(ctx context.Context, id string, url string) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := querier(ctx, s.db).ExecContext(ctx, `
 			UPDATE events
 			SET 
 				cover_url = $1
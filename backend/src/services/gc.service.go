@@ -0,0 +1,14 @@
+package services
+
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type GcService interface {
+	// Deletes the objects of the bucket no row references, once older than the
+	// grace period. In dry-run they are only reported.
+	CollectOrphans(ctx context.Context) (models.GcReport, error)
+	RunCollectOrphans() error
+}
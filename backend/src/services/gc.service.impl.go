@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+// referenceObjects records the owner of the objects, pass a *sql.Tx so the
+// references are committed with the owner row
+func referenceObjects(ctx context.Context, db Querier, ref models.ObjectReference, paths ...string) error {
+	for _, p := range paths {
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO object_references
+				(object_path, user_id, event_id, attachment_id, upload_id)
+			VALUES
+				($1, $2, $3, $4, $5)
+			ON CONFLICT (object_path) DO UPDATE SET
+				user_id = EXCLUDED.user_id,
+				event_id = EXCLUDED.event_id,
+				attachment_id = EXCLUDED.attachment_id,
+				upload_id = EXCLUDED.upload_id;
+			`,
+			p,
+			ref.UserId,
+			ref.EventId,
+			ref.AttachmentId,
+			ref.UploadId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unreferenceObjects(ctx context.Context, db Querier, paths ...string) error {
+	for _, p := range paths {
+		_, err := db.ExecContext(ctx, `
+			DELETE FROM object_references
+			WHERE object_path = $1;
+			`,
+			p,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unreferencePrefix forgets the objects under `prefix` but the `keep` ones, so
+// the collector removes what they replaced
+func unreferencePrefix(ctx context.Context, db Querier, prefix string, keep ...string) error {
+	_, err := db.ExecContext(ctx, `
+		DELETE FROM object_references
+		WHERE
+			starts_with(object_path, $1) AND
+			object_path <> ALL($2::TEXT[]);
+		`,
+		prefix,
+		pq.Array(keep),
+	)
+
+	return err
+}
+
+type GcServicePgImpl struct {
+	db         *sql.DB
+	objService ObjectService
+	bucket     string
+	grace      time.Duration
+	dryRun     bool
+}
+
+func NewGcServicePgImpl(db *sql.DB, objService ObjectService, bucket string, grace time.Duration, dryRun bool) GcService {
+	return &GcServicePgImpl{
+		db:         db,
+		objService: objService,
+		bucket:     bucket,
+		grace:      grace,
+		dryRun:     dryRun,
+	}
+}
+
+// The urls set before the references existed protect their objects too
+func (s *GcServicePgImpl) getReferencedPaths(ctx context.Context) (map[string]bool, error) {
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT object_path, false FROM object_references
+		UNION
+		SELECT avatar_url, true FROM users WHERE avatar_url IS NOT NULL
+		UNION
+		SELECT cover_url, true FROM events WHERE cover_url IS NOT NULL;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referenced := map[string]bool{}
+	for rows.Next() {
+		var p string
+		var isUrl bool
+		err := rows.Scan(&p, &isUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		if isUrl {
+			objPath, ok := storage.GetObjPathFromUrl(p)
+			if !ok {
+				continue
+			}
+			p = objPath
+		}
+		referenced[p] = true
+	}
+
+	return referenced, rows.Err()
+}
+
+func (s *GcServicePgImpl) CollectOrphans(ctx context.Context) (models.GcReport, error) {
+	report := models.GcReport{Orphans: []string{}, DryRun: s.dryRun}
+
+	// listed before the references are read, so an object referenced in
+	// between is seen as referenced
+	objects := []models.ObjectInfo{}
+	for _, prefix := range []string{common.OBJECT_GC_PREFIX_PUBLIC, common.OBJECT_GC_PREFIX_PRIVATE} {
+		listed, err := s.objService.List(ctx, s.bucket, prefix)
+		if err != nil {
+			return report, err
+		}
+		objects = append(objects, listed...)
+	}
+	report.Objects = len(objects)
+
+	referenced, err := s.getReferencedPaths(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	// young objects may be uploads whose reference is not committed yet
+	before := time.Now().Add(-s.grace)
+	for _, obj := range objects {
+		if referenced[obj.Path] || obj.ModifiedAt.After(before) {
+			continue
+		}
+		report.Orphans = append(report.Orphans, obj.Path)
+
+		if s.dryRun {
+			continue
+		}
+
+		err = s.objService.Delete(ctx, s.bucket, obj.Path)
+		if err != nil {
+			return report, err
+		}
+		report.Deleted++
+	}
+
+	return report, nil
+}
+
+func (s *GcServicePgImpl) RunCollectOrphans() error {
+	report, err := s.CollectOrphans(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if s.dryRun {
+		for _, p := range report.Orphans {
+			slog.Info(fmt.Sprintf("object gc dry-run, would delete: %s", p))
+		}
+	}
+	slog.Info(fmt.Sprintf("object gc: %d objects, %d orphans, %d deleted", report.Objects, len(report.Orphans), report.Deleted))
+
+	return nil
+}
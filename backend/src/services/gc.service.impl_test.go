@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestGcServicePgImpl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	db := pgContainer.DB
+	userService := &UserServicePgImpl{db: db}
+	err = userService.CreateUser(ctx, models.User{Email: "test@email.com", PasswordHash: "hashtest", FirstName: "Test", LastName: "User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := userService.GetUser(ctx, "test@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	objService := NewObjectServiceFsImpl(root, "http://127.0.0.1:8080/", "secret")
+
+	legacy := storage.GetPublicPath(storage.USER_AVATARS, "legacy")
+	referenced := storage.GetPublicPath(storage.USER_AVATARS, "1-full.webp")
+	orphan := storage.GetPrivatePath(storage.UPLOADS, "orphan")
+	young := storage.GetPrivatePath(storage.UPLOADS, "young")
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, p := range []string{legacy, referenced, orphan, young} {
+		err = objService.Upload(ctx, "bucket", p, 5, bytes.NewReader([]byte("quack")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != young {
+			err = os.Chtimes(filepath.Join(root, "bucket", filepath.FromSlash(p)), old, old)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// set before the references existed
+	legacyUrl, err := storage.GetFullObjUrl(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = userService.SetAvatarUrl(ctx, user.UserId, legacyUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = referenceObjects(ctx, db, models.ObjectReference{UserId: &user.UserId}, referenced)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dryRun := NewGcServicePgImpl(db, objService, "bucket", time.Hour, true)
+	report, err := dryRun.CollectOrphans(ctx)
+	if err != nil {
+		t.Fatalf("CollectOrphans() error = %v", err)
+	}
+	if report.Objects != 4 || !slices.Equal(report.Orphans, []string{orphan}) || report.Deleted != 0 {
+		t.Errorf("CollectOrphans() dry-run = %+v", report)
+	}
+	_, err = objService.Stat(ctx, "bucket", orphan)
+	if err != nil {
+		t.Errorf("dry-run deleted the orphan: %v", err)
+	}
+
+	s := NewGcServicePgImpl(db, objService, "bucket", time.Hour, false)
+	report, err = s.CollectOrphans(ctx)
+	if err != nil {
+		t.Fatalf("CollectOrphans() error = %v", err)
+	}
+	if report.Deleted != 1 {
+		t.Errorf("CollectOrphans() = %+v", report)
+	}
+	_, err = objService.Stat(ctx, "bucket", orphan)
+	if err != common.ErrObjectNotFound {
+		t.Errorf("orphan error = %v, want %v", err, common.ErrObjectNotFound)
+	}
+	for _, p := range []string{legacy, referenced, young} {
+		_, err = objService.Stat(ctx, "bucket", p)
+		if err != nil {
+			t.Errorf("%s was deleted: %v", p, err)
+		}
+	}
+
+	// once unreferenced it is collected too
+	_, err = db.ExecContext(ctx, `DELETE FROM object_references WHERE user_id = $1;`, user.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = s.CollectOrphans(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(report.Orphans, []string{referenced}) {
+		t.Errorf("CollectOrphans() after unreferencing = %+v", report)
+	}
+}
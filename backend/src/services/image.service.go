@@ -0,0 +1,15 @@
+package services
+
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type ImageService interface {
+	// Processes the image and stores its variants at new paths of the upload
+	// `purpose`, replacing the references to the previous image of the target.
+	// Pass a ctx with a transaction so the swap commits with the row using the
+	// image. Returns common.ErrImageFormatInvalid if it is not a PNG or JPEG.
+	StoreImage(ctx context.Context, purpose string, targetId string, data []byte) (models.ImageSrcset, error)
+}
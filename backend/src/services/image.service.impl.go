@@ -0,0 +1,94 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type ImageServicePgImpl struct {
+	db         *sql.DB
+	objService ObjectService
+	bucket     string
+}
+
+func NewImageServicePgImpl(db *sql.DB, objService ObjectService, bucket string) ImageService {
+	return &ImageServicePgImpl{
+		db:         db,
+		objService: objService,
+		bucket:     bucket,
+	}
+}
+
+func (s *ImageServicePgImpl) StoreImage(ctx context.Context, purpose string, targetId string, data []byte) (models.ImageSrcset, error) {
+	variants, err := fiddlers.ProcessImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := models.ObjectReference{}
+	if purpose == common.UPLOAD_PURPOSE_EVENT_BANNER {
+		ref.EventId = &targetId
+	} else {
+		userId, err := strconv.ParseUint(targetId, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		uid := uint32(userId)
+		ref.UserId = &uid
+	}
+
+	imageId := fiddlers.GetImageId(data)
+
+	srcset := models.ImageSrcset{}
+	objPaths := []string{}
+	for _, v := range variants {
+		objPath := fiddlers.GetImageVariantPath(purpose, targetId, imageId, v.Name, v.Ext)
+		err = s.objService.Upload(ctx, s.bucket, objPath, int64(len(v.Data)), bytes.NewReader(v.Data))
+		if err != nil {
+			return nil, err
+		}
+		objPaths = append(objPaths, objPath)
+
+		objUrl, err := storage.GetFullObjUrl(objPath)
+		if err != nil {
+			return nil, err
+		}
+
+		sources := srcset[v.Name]
+		sources.Width = v.Width
+		sources.Height = v.Height
+		if v.Ext == "webp" {
+			sources.Webp = objUrl
+		} else {
+			sources.Jpeg = objUrl
+		}
+		srcset[v.Name] = sources
+	}
+
+	// swapped in one transaction, a rollback keeps the previous image referenced
+	// and leaves the new one to the collector
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = unreferencePrefix(ctx, tx, fiddlers.GetImagePathPrefix(purpose, targetId), objPaths...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = referenceObjects(ctx, tx, ref, objPaths...)
+	if err != nil {
+		return nil, err
+	}
+
+	return srcset, tx.Commit()
+}
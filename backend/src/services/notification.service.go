@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type NotificationService interface {
+	// Notifies the user in-app if their preference for `notificationType` allows
+	// it, returns whether they also want it by email
+	Notify(ctx context.Context, userId uint32, notificationType string, payload any) (bool, error)
+	GetNotifications(ctx context.Context, userId uint32, unreadOnly bool, limit int, offset int) ([]models.Notification, error)
+	GetUnreadCount(ctx context.Context, userId uint32) (uint32, error)
+	MarkRead(ctx context.Context, userId uint32, notificationId uint64) error
+	MarkAllRead(ctx context.Context, userId uint32) error
+
+	GetPreferences(ctx context.Context, userId uint32) ([]models.NotificationPreference, error)
+	SetPreference(ctx context.Context, userId uint32, preference models.NotificationPreference) error
+}
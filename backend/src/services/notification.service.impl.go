@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+// notifyUser stores the in-app notification if the preference of the user for
+// `notificationType` allows it, pushes it to their stream, and returns whether
+// they also want it by email.
+// Pass a *sql.Tx so it is only stored if the change it belongs to is committed.
+func notifyUser(ctx context.Context, db Querier, userId uint32, notificationType string, payload any) (bool, error) {
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+
+	n := models.Notification{
+		UserId:           userId,
+		NotificationType: notificationType,
+		Payload:          payloadJson,
+	}
+
+	var channel string
+	var notificationId *uint64
+	var createdAt *time.Time
+	err = db.QueryRowContext(ctx, `
+		WITH pref AS (
+			SELECT COALESCE(
+				(SELECT channel FROM notification_preferences WHERE user_id = $1 AND notification_type = $2),
+				$4
+			) AS channel
+		), stored AS (
+			INSERT INTO notifications (user_id, notification_type, payload)
+			SELECT $1, $2, $3 FROM pref WHERE channel IN ($5, $4)
+			RETURNING notification_id, created_at
+		)
+		SELECT pref.channel, stored.notification_id, stored.created_at
+		FROM pref
+		LEFT JOIN stored ON TRUE;
+		`,
+		userId,
+		notificationType,
+		string(payloadJson),
+		common.NOTIFICATION_CHANNEL_BOTH,
+		common.NOTIFICATION_CHANNEL_IN_APP,
+	).Scan(&channel, &notificationId, &createdAt)
+	if err != nil {
+		return false, err
+	}
+
+	if notificationId != nil {
+		n.NotificationId = *notificationId
+		n.CreatedAt = *createdAt
+
+		err = publishStreamEvent(ctx, db, fiddlers.GetUserStreamTopic(userId), common.STREAM_EVENT_NOTIFICATION, n)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return channel != common.NOTIFICATION_CHANNEL_IN_APP, nil
+}
+
+type NotificationServicePgImpl struct {
+	db *sql.DB
+}
+
+func NewNotificationServicePgImpl(db *sql.DB) NotificationService {
+	return &NotificationServicePgImpl{
+		db: db,
+	}
+}
+
+func (s *NotificationServicePgImpl) Notify(ctx context.Context, userId uint32, notificationType string, payload any) (bool, error) {
+	return notifyUser(ctx, querier(ctx, s.db), userId, notificationType, payload)
+}
+
+func (s *NotificationServicePgImpl) GetNotifications(ctx context.Context, userId uint32, unreadOnly bool, limit int, offset int) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT
+			notification_id,
+			user_id,
+			notification_type,
+			payload,
+			read_at,
+			created_at
+		FROM notifications
+		WHERE
+			user_id = $1 AND
+			(NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, notification_id DESC
+		LIMIT $3 OFFSET $4;
+		`,
+		userId,
+		unreadOnly,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return notifications, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		n := models.Notification{}
+		err := rows.Scan(
+			&n.NotificationId,
+			&n.UserId,
+			&n.NotificationType,
+			&n.Payload,
+			&n.ReadAt,
+			&n.CreatedAt,
+		)
+		if err != nil {
+			return notifications, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (s *NotificationServicePgImpl) GetUnreadCount(ctx context.Context, userId uint32) (uint32, error) {
+	var count uint32
+	err := querier(ctx, s.db).QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM notifications
+		WHERE user_id = $1 AND read_at IS NULL;
+		`,
+		userId,
+	).Scan(&count)
+
+	return count, err
+}
+
+func (s *NotificationServicePgImpl) MarkRead(ctx context.Context, userId uint32, notificationId uint64) error {
+	var id uint64
+	err := querier(ctx, s.db).QueryRowContext(ctx, `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE notification_id = $1 AND user_id = $2
+		RETURNING notification_id;
+		`,
+		notificationId,
+		userId,
+	).Scan(&id)
+
+	return common.FilterSqlPgError(err)
+}
+
+func (s *NotificationServicePgImpl) MarkAllRead(ctx context.Context, userId uint32) error {
+	_, err := querier(ctx, s.db).ExecContext(ctx, `
+		UPDATE notifications
+		SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL;
+		`,
+		userId,
+	)
+
+	return err
+}
+
+// Every notification type, with the default channel where the user has no preference
+func (s *NotificationServicePgImpl) GetPreferences(ctx context.Context, userId uint32) ([]models.NotificationPreference, error) {
+	preferences := []models.NotificationPreference{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT notification_type, channel
+		FROM notification_preferences
+		WHERE user_id = $1;
+		`,
+		userId,
+	)
+	if err != nil {
+		return preferences, err
+	}
+	defer rows.Close()
+
+	channels := make(map[string]string)
+	for rows.Next() {
+		var notificationType, channel string
+		err := rows.Scan(&notificationType, &channel)
+		if err != nil {
+			return preferences, err
+		}
+		channels[notificationType] = channel
+	}
+	if err := rows.Err(); err != nil {
+		return preferences, err
+	}
+
+	for _, notificationType := range common.NOTIFICATION_TYPES {
+		channel, ok := channels[notificationType]
+		if !ok {
+			channel = common.DEFAULT_NOTIFICATION_CHANNEL
+		}
+		preferences = append(preferences, models.NotificationPreference{
+			NotificationType: notificationType,
+			Channel:          channel,
+		})
+	}
+
+	return preferences, nil
+}
+
+func (s *NotificationServicePgImpl) SetPreference(ctx context.Context, userId uint32, preference models.NotificationPreference) error {
+	_, err := querier(ctx, s.db).ExecContext(ctx, `
+		INSERT INTO notification_preferences (user_id, notification_type, channel)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, notification_type) DO UPDATE
+		SET channel = EXCLUDED.channel;
+		`,
+		userId,
+		preference.NotificationType,
+		preference.Channel,
+	)
+
+	return err
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestNotificationServicePgImpl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	userService := &UserServicePgImpl{db: pgContainer.DB}
+	err = userService.CreateUser(ctx, models.User{Email: "user@email.com", PasswordHash: "hashtest", FirstName: "Test", LastName: "User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := userService.GetUser(ctx, "user@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewNotificationServicePgImpl(pgContainer.DB)
+
+	preferences, err := s.GetPreferences(ctx, user.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preferences) != len(common.NOTIFICATION_TYPES) {
+		t.Fatalf("GetPreferences() len = %d, want %d", len(preferences), len(common.NOTIFICATION_TYPES))
+	}
+	for _, p := range preferences {
+		if p.Channel != common.DEFAULT_NOTIFICATION_CHANNEL {
+			t.Errorf("GetPreferences() %s channel = %s, want %s", p.NotificationType, p.Channel, common.DEFAULT_NOTIFICATION_CHANNEL)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		channel      string
+		wantEmail    bool
+		wantNotified bool
+	}{
+		{"default", "", true, true},
+		{"in-app only", common.NOTIFICATION_CHANNEL_IN_APP, false, true},
+		{"email only", common.NOTIFICATION_CHANNEL_EMAIL, true, false},
+		{"both", common.NOTIFICATION_CHANNEL_BOTH, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.channel != "" {
+				err := s.SetPreference(ctx, user.UserId, models.NotificationPreference{
+					NotificationType: common.NOTIFICATION_TYPE_PAYMENT_ACCEPTED,
+					Channel:          tt.channel,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			before, err := s.GetUnreadCount(ctx, user.UserId)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sendEmail, err := s.Notify(ctx, user.UserId, common.NOTIFICATION_TYPE_PAYMENT_ACCEPTED, models.PaymentAcceptedNotification{PaymentId: "pay_1"})
+			if err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if sendEmail != tt.wantEmail {
+				t.Errorf("Notify() = %v, want %v", sendEmail, tt.wantEmail)
+			}
+
+			after, err := s.GetUnreadCount(ctx, user.UserId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if notified := after == before+1; notified != tt.wantNotified {
+				t.Errorf("Notify() stored = %v, want %v", notified, tt.wantNotified)
+			}
+		})
+	}
+
+	unread, err := s.GetNotifications(ctx, user.UserId, true, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unread) != 3 {
+		t.Fatalf("GetNotifications() unread len = %d, want 3", len(unread))
+	}
+
+	err = s.MarkRead(ctx, user.UserId, unread[0].NotificationId)
+	if err != nil {
+		t.Fatalf("MarkRead() error = %v", err)
+	}
+	err = s.MarkRead(ctx, user.UserId+1, unread[1].NotificationId)
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("MarkRead() of another user error = %v, want %v", err, common.ErrNotFound)
+	}
+
+	count, err := s.GetUnreadCount(ctx, user.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("GetUnreadCount() = %d, want 2", count)
+	}
+
+	err = s.MarkAllRead(ctx, user.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := s.GetNotifications(ctx, user.UserId, false, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range all {
+		if n.ReadAt == nil {
+			t.Errorf("notification %d unread after MarkAllRead()", n.NotificationId)
+		}
+	}
+}
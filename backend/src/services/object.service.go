@@ -4,11 +4,20 @@ import (
 	"context"
 	"io"
 	"time"
+
+	"github.com/patos-ufscar/quack-week/models"
 )
 
 type ObjectService interface {
 	Upload(ctx context.Context, bucket string, path string, size int64, data io.Reader) error
 	Download(ctx context.Context, bucket string, path string) ([]byte, error)
+	// Returns common.ErrObjectNotFound if there is no object at `path`
+	Stat(ctx context.Context, bucket string, path string) (models.ObjectInfo, error)
+	Delete(ctx context.Context, bucket string, path string) error
+	// Lists the objects under the `prefix`, recursively
+	List(ctx context.Context, bucket string, prefix string) ([]models.ObjectInfo, error)
 	SignedUrl(ctx context.Context, bucket string, path string, exp time.Duration) (string, error)
-	UploadUrl(ctx context.Context, bucket string, path string, exp time.Duration) (string, error)
+	// Signs a PUT of at most `size` bytes, only enforced by the filesystem
+	// storage, the others rely on the object being checked once uploaded
+	UploadUrl(ctx context.Context, bucket string, path string, size int64, exp time.Duration) (string, error)
 }
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+// ObjectServiceFsImpl keeps the objects under `root`/<bucket>/<path>, for local
+// development and tests. Its signed urls point to the API, which serves them
+// with ObjectController.
+type ObjectServiceFsImpl struct {
+	root    string
+	baseUrl string
+	secret  []byte
+}
+
+func NewObjectServiceFsImpl(root string, baseUrl string, secret string) ObjectService {
+	if secret == "" {
+		panic("the filesystem object storage needs a secret to sign urls")
+	}
+
+	err := os.MkdirAll(root, 0o755)
+	if err != nil {
+		panic(err)
+	}
+
+	return &ObjectServiceFsImpl{
+		root:    root,
+		baseUrl: baseUrl,
+		secret:  []byte(secret),
+	}
+}
+
+// Resolves the file of the object, refusing anything outside of the bucket
+func (s *ObjectServiceFsImpl) filePath(bucket string, path string) (string, error) {
+	if bucket == "" || bucket != filepath.Base(bucket) || bucket == ".." {
+		return "", common.ErrObjectPathInvalid
+	}
+
+	bucketDir := filepath.Join(s.root, bucket)
+	p := filepath.Join(bucketDir, filepath.FromSlash(path))
+	if !strings.HasPrefix(p, bucketDir+string(filepath.Separator)) {
+		return "", common.ErrObjectPathInvalid
+	}
+
+	return p, nil
+}
+
+// The data is written to a temporary file first, readers never see a partial object
+func (s *ObjectServiceFsImpl) Upload(ctx context.Context, bucket string, path string, size int64, data io.Reader) error {
+	p, err := s.filePath(bucket, path)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	if size >= 0 && written != size {
+		return fmt.Errorf("object %s: got %d bytes, expected %d", path, written, size)
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *ObjectServiceFsImpl) Download(ctx context.Context, bucket string, path string) ([]byte, error) {
+	p, err := s.filePath(bucket, path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, common.ErrObjectNotFound
+	}
+
+	return data, err
+}
+
+// There is no stored metadata, the content type is sniffed from the data
+func (s *ObjectServiceFsImpl) Stat(ctx context.Context, bucket string, path string) (models.ObjectInfo, error) {
+	p, err := s.filePath(bucket, path)
+	if err != nil {
+		return models.ObjectInfo{}, err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return models.ObjectInfo{}, common.ErrObjectNotFound
+	}
+	if err != nil {
+		return models.ObjectInfo{}, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return models.ObjectInfo{}, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return models.ObjectInfo{}, err
+	}
+
+	return models.ObjectInfo{
+		Path:        path,
+		Size:        stat.Size(),
+		ContentType: http.DetectContentType(head[:n]),
+		ModifiedAt:  stat.ModTime(),
+	}, nil
+}
+
+// Deleting a missing object is not an error, as in S3
+func (s *ObjectServiceFsImpl) Delete(ctx context.Context, bucket string, path string) error {
+	p, err := s.filePath(bucket, path)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// The content type is not sniffed, reading every object would be too slow
+func (s *ObjectServiceFsImpl) List(ctx context.Context, bucket string, prefix string) ([]models.ObjectInfo, error) {
+	if bucket == "" || bucket != filepath.Base(bucket) || bucket == ".." {
+		return nil, common.ErrObjectPathInvalid
+	}
+
+	bucketDir := filepath.Join(s.root, bucket)
+	objects := []models.ObjectInfo{}
+	err := filepath.WalkDir(bucketDir, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// the temporary files of Upload are not objects yet
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(bucketDir, p)
+		if err != nil {
+			return err
+		}
+		objPath := filepath.ToSlash(rel)
+		if !strings.HasPrefix(objPath, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, models.ObjectInfo{
+			Path:       objPath,
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime(),
+		})
+		return nil
+	})
+
+	return objects, err
+}
+
+func (s *ObjectServiceFsImpl) SignedUrl(ctx context.Context, bucket string, path string, exp time.Duration) (string, error) {
+	_, err := s.filePath(bucket, path)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.SignObjectUrl(s.baseUrl, s.secret, http.MethodGet, bucket, path, 0, time.Now().Add(exp))
+}
+
+func (s *ObjectServiceFsImpl) UploadUrl(ctx context.Context, bucket string, path string, size int64, exp time.Duration) (string, error) {
+	_, err := s.filePath(bucket, path)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.SignObjectUrl(s.baseUrl, s.secret, http.MethodPut, bucket, path, size, time.Now().Add(exp))
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+)
+
+func TestObjectServiceFsImpl(t *testing.T) {
+	ctx := context.Background()
+	secret := "secret"
+	s := NewObjectServiceFsImpl(t.TempDir(), "http://127.0.0.1:8080/", secret)
+
+	objPath := storage.GetPublicPath(storage.USER_AVATARS, "1")
+	data := []byte("quack")
+
+	err := s.Upload(ctx, "bucket", objPath, int64(len(data)), bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	got, err := s.Download(ctx, "bucket", objPath)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Download() = %s, want %s", got, data)
+	}
+
+	// replacing keeps a single object
+	err = s.Upload(ctx, "bucket", objPath, 3, bytes.NewReader([]byte("new")))
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	got, err = s.Download(ctx, "bucket", objPath)
+	if err != nil || string(got) != "new" {
+		t.Errorf("Download() = %s, %v, want new", got, err)
+	}
+
+	err = s.Upload(ctx, "bucket", objPath, 10, bytes.NewReader([]byte("short")))
+	if err == nil {
+		t.Error("Upload() with a wrong size should fail")
+	}
+
+	_, err = s.Download(ctx, "bucket", "public/missing")
+	if err != common.ErrObjectNotFound {
+		t.Errorf("Download() missing error = %v, want %v", err, common.ErrObjectNotFound)
+	}
+
+	info, err := s.Stat(ctx, "bucket", objPath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size != 3 || info.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("Stat() = %+v", info)
+	}
+	_, err = s.Stat(ctx, "bucket", "public/missing")
+	if err != common.ErrObjectNotFound {
+		t.Errorf("Stat() missing error = %v, want %v", err, common.ErrObjectNotFound)
+	}
+
+	err = s.Upload(ctx, "bucket", "private/uploads/1", 3, bytes.NewReader([]byte("new")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := s.List(ctx, "bucket", "public/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(objects) != 1 || objects[0].Path != objPath || objects[0].Size != 3 {
+		t.Errorf("List() = %+v, want only %s", objects, objPath)
+	}
+	objects, err = s.List(ctx, "missing", "")
+	if err != nil || len(objects) != 0 {
+		t.Errorf("List() missing bucket = %+v, %v", objects, err)
+	}
+
+	err = s.Delete(ctx, "bucket", objPath)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	_, err = s.Download(ctx, "bucket", objPath)
+	if err != common.ErrObjectNotFound {
+		t.Errorf("Download() deleted error = %v, want %v", err, common.ErrObjectNotFound)
+	}
+	err = s.Delete(ctx, "bucket", objPath)
+	if err != nil {
+		t.Errorf("Delete() missing error = %v", err)
+	}
+
+	invalid := []struct {
+		name   string
+		bucket string
+		path   string
+	}{
+		{"traversal", "bucket", "../other/file"},
+		{"bucket traversal", "..", "file"},
+		{"nested bucket", "a/b", "file"},
+		{"empty bucket", "", "file"},
+		{"bucket root", "bucket", "."},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Upload(ctx, tt.bucket, tt.path, 1, bytes.NewReader([]byte("x")))
+			if err != common.ErrObjectPathInvalid {
+				t.Errorf("Upload() error = %v, want %v", err, common.ErrObjectPathInvalid)
+			}
+			_, err = s.Download(ctx, tt.bucket, tt.path)
+			if err != common.ErrObjectPathInvalid {
+				t.Errorf("Download() error = %v, want %v", err, common.ErrObjectPathInvalid)
+			}
+		})
+	}
+
+	urls := []struct {
+		name   string
+		method string
+		size   int64
+		sign   func(context.Context, string, string, time.Duration) (string, error)
+	}{
+		{"SignedUrl", http.MethodGet, 0, s.SignedUrl},
+		{"UploadUrl", http.MethodPut, 1024, func(ctx context.Context, bucket string, path string, exp time.Duration) (string, error) {
+			return s.UploadUrl(ctx, bucket, path, 1024, exp)
+		}},
+	}
+	for _, tt := range urls {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := tt.sign(ctx, "bucket", objPath, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(signed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := u.Query()
+			size, err := storage.VerifyObjectSignature([]byte(secret), tt.method, "bucket", objPath, q.Get(common.OBJECT_URL_EXP_PARAM), q.Get(common.OBJECT_URL_SIZE_PARAM), q.Get(common.OBJECT_URL_SIGNATURE_PARAM), time.Now())
+			if err != nil {
+				t.Errorf("%s() url does not verify: %v", tt.name, err)
+			}
+			if size != tt.size {
+				t.Errorf("%s() url size = %d, want %d", tt.name, size, tt.size)
+			}
+			_, err = storage.VerifyObjectSignature([]byte(secret), tt.method, "bucket", objPath, q.Get(common.OBJECT_URL_EXP_PARAM), q.Get(common.OBJECT_URL_SIZE_PARAM), q.Get(common.OBJECT_URL_SIGNATURE_PARAM), time.Now().Add(2*time.Minute))
+			if err == nil {
+				t.Errorf("%s() url should expire", tt.name)
+			}
+		})
+	}
+}
@@ -3,9 +3,13 @@ package services
 import (
 	"context"
 	"io"
+	"mime"
+	"path/filepath"
 	"time"
 
 	"github.com/minio/minio-go/v7"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
 )
 
 type ObjectServiceMinioImpl struct {
@@ -19,7 +23,9 @@ func NewObjectServiceMinioImpl(client *minio.Client) ObjectService {
 }
 
 func (s *ObjectServiceMinioImpl) Upload(ctx context.Context, bucket string, path string, size int64, data io.Reader) error {
-	_, err := s.client.PutObject(ctx, bucket, path, data, size, minio.PutObjectOptions{})
+	// served as is to browsers, which need the type of the image variants
+	opts := minio.PutObjectOptions{ContentType: mime.TypeByExtension(filepath.Ext(path))}
+	_, err := s.client.PutObject(ctx, bucket, path, data, size, opts)
 	return err
 }
 
@@ -37,6 +43,45 @@ func (s *ObjectServiceMinioImpl) Download(ctx context.Context, bucket string, pa
 	return data, nil
 }
 
+func (s *ObjectServiceMinioImpl) Stat(ctx context.Context, bucket string, path string) (models.ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, bucket, path, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return models.ObjectInfo{}, common.ErrObjectNotFound
+		}
+		return models.ObjectInfo{}, err
+	}
+
+	return models.ObjectInfo{
+		Path:        info.Key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ModifiedAt:  info.LastModified,
+	}, nil
+}
+
+func (s *ObjectServiceMinioImpl) Delete(ctx context.Context, bucket string, path string) error {
+	return s.client.RemoveObject(ctx, bucket, path, minio.RemoveObjectOptions{})
+}
+
+func (s *ObjectServiceMinioImpl) List(ctx context.Context, bucket string, prefix string) ([]models.ObjectInfo, error) {
+	objects := []models.ObjectInfo{}
+	for obj := range s.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+
+		objects = append(objects, models.ObjectInfo{
+			Path:        obj.Key,
+			Size:        obj.Size,
+			ContentType: obj.ContentType,
+			ModifiedAt:  obj.LastModified,
+		})
+	}
+
+	return objects, nil
+}
+
 func (s *ObjectServiceMinioImpl) SignedUrl(ctx context.Context, bucket string, path string, exp time.Duration) (string, error) {
 	url, err := s.client.PresignedGetObject(ctx, bucket, path, exp, nil)
 	if err != nil {
@@ -45,8 +90,8 @@ func (s *ObjectServiceMinioImpl) SignedUrl(ctx context.Context, bucket string, p
 	return url.String(), nil
 }
 
-func (s *ObjectServiceMinioImpl) UploadUrl(ctx context.Context, bucket string, path string, exp time.Duration) (string, error) {
-	url, err := s.client.PresignedPutObject(ctx, bucket, path, time.Minute*5)
+func (s *ObjectServiceMinioImpl) UploadUrl(ctx context.Context, bucket string, path string, size int64, exp time.Duration) (string, error) {
+	url, err := s.client.PresignedPutObject(ctx, bucket, path, exp)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/models"
+	"github.com/patos-ufscar/quack-week/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ObjectServiceTracedImpl wraps any ObjectService with a span per call, so the
+// filesystem storage is traced as well as the S3 one
+type ObjectServiceTracedImpl struct {
+	next ObjectService
+}
+
+func NewObjectServiceTracedImpl(next ObjectService) ObjectService {
+	return &ObjectServiceTracedImpl{
+		next: next,
+	}
+}
+
+func (s *ObjectServiceTracedImpl) start(ctx context.Context, op string, bucket string, path string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "ObjectService."+op,
+		attribute.String("object.bucket", bucket),
+		attribute.String("object.path", path),
+	)
+}
+
+func (s *ObjectServiceTracedImpl) Upload(ctx context.Context, bucket string, path string, size int64, data io.Reader) (err error) {
+	ctx, span := s.start(ctx, "Upload", bucket, path)
+	span.SetAttributes(attribute.Int64("object.size", size))
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Upload(ctx, bucket, path, size, data)
+}
+
+func (s *ObjectServiceTracedImpl) Download(ctx context.Context, bucket string, path string) (data []byte, err error) {
+	ctx, span := s.start(ctx, "Download", bucket, path)
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Download(ctx, bucket, path)
+}
+
+func (s *ObjectServiceTracedImpl) Stat(ctx context.Context, bucket string, path string) (info models.ObjectInfo, err error) {
+	ctx, span := s.start(ctx, "Stat", bucket, path)
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Stat(ctx, bucket, path)
+}
+
+func (s *ObjectServiceTracedImpl) Delete(ctx context.Context, bucket string, path string) (err error) {
+	ctx, span := s.start(ctx, "Delete", bucket, path)
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Delete(ctx, bucket, path)
+}
+
+func (s *ObjectServiceTracedImpl) List(ctx context.Context, bucket string, prefix string) (objects []models.ObjectInfo, err error) {
+	ctx, span := s.start(ctx, "List", bucket, prefix)
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.List(ctx, bucket, prefix)
+}
+
+func (s *ObjectServiceTracedImpl) SignedUrl(ctx context.Context, bucket string, path string, exp time.Duration) (url string, err error) {
+	ctx, span := s.start(ctx, "SignedUrl", bucket, path)
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.SignedUrl(ctx, bucket, path, exp)
+}
+
+func (s *ObjectServiceTracedImpl) UploadUrl(ctx context.Context, bucket string, path string, size int64, exp time.Duration) (url string, err error) {
+	ctx, span := s.start(ctx, "UploadUrl", bucket, path)
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.UploadUrl(ctx, bucket, path, size, exp)
+}
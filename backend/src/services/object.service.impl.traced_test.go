@@ -0,0 +1,53 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestObjectServiceTracedImpl(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	defaultProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(defaultProvider) })
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	s := NewObjectServiceTracedImpl(NewObjectServiceFsImpl(t.TempDir(), "http://127.0.0.1:8080/", "secret"))
+
+	objPath := storage.GetPublicPath(storage.USER_AVATARS, "1")
+	err := s.Upload(ctx, "bucket", objPath, 5, bytes.NewReader([]byte("quack")))
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	_, err = s.Stat(ctx, "bucket", storage.GetPublicPath(storage.USER_AVATARS, "2"))
+	if !errors.Is(err, common.ErrObjectNotFound) {
+		t.Fatalf("Stat() error = %v, want ErrObjectNotFound", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+
+	upload, stat := spans[0], spans[1]
+	if upload.Name() != "ObjectService.Upload" || stat.Name() != "ObjectService.Stat" {
+		t.Errorf("span names = %s, %s", upload.Name(), stat.Name())
+	}
+	if upload.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Upload() span is not a child of the request span")
+	}
+	if upload.Status().Code != codes.Unset || stat.Status().Code != codes.Error {
+		t.Errorf("span statuses = %v, %v", upload.Status(), stat.Status())
+	}
+}
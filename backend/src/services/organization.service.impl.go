@@ -26,7 +26,8 @@ func (s *OrganizationServicePgImpl) GetOrganization(ctx context.Context, orgId s
 			billing_plan_id,
 			created_at,
 			deleted_at,
-			owner_user_id
+			owner_user_id,
+			payout_account_id
 		FROM
 			organizations
 		WHERE
@@ -52,20 +53,21 @@ func (s *OrganizationServicePgImpl) GetOrganization(ctx context.Context, orgId s
 
 	org := models.Organization{}
 
-	err := s.db.QueryRowContext(ctx, query, orgId).Scan(
+	err := querier(ctx, s.db).QueryRowContext(ctx, query, orgId).Scan(
 		&org.OrganizationId,
 		&org.OrganizationName,
 		&org.BillingPlanId,
 		&org.CreatedAt,
 		&org.DeletedAt,
 		&org.OwnerUserId,
+		&org.PayoutAccountId,
 	)
 
 	return org, err
 }
 
 func (s *OrganizationServicePgImpl) CreateOrganization(ctx context.Context, org models.Organization) error {
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := beginTx(ctx, s.db)
 	if err != nil {
 		return err
 	}
@@ -110,7 +112,7 @@ func (s *OrganizationServicePgImpl) CreateOrganizationInvite(ctx context.Context
 		VALUES ($1, $2, $3, $4, $5);
 	`
 
-	_, err := s.db.ExecContext(ctx, query,
+	_, err := querier(ctx, s.db).ExecContext(ctx, query,
 		invite.OrganizationId,
 		invite.UserId,
 		invite.IsAdmin,
@@ -121,7 +123,7 @@ func (s *OrganizationServicePgImpl) CreateOrganizationInvite(ctx context.Context
 }
 
 func (s *OrganizationServicePgImpl) ConfirmOrganizationInvite(ctx context.Context, otp string) error {
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := beginTx(ctx, s.db)
 	if err != nil {
 		return err
 	}
@@ -175,7 +177,7 @@ func (s *OrganizationServicePgImpl) ConfirmOrganizationInvite(ctx context.Contex
 }
 
 func (s *OrganizationServicePgImpl) RemoveUserFromOrg(ctx context.Context, orgId string, userId uint32) error {
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := beginTx(ctx, s.db)
 	if err != nil {
 		return err
 	}
@@ -183,10 +185,11 @@ func (s *OrganizationServicePgImpl) RemoveUserFromOrg(ctx context.Context, orgId
 	defer tx.Rollback()
 
 	var isOwner bool
-	err = s.db.QueryRowContext(ctx, `
+	err = tx.QueryRowContext(ctx, `
 		SELECT owner_user_id = $1
 		FROM organizations
-		WHERE organization_id = $2;
+		WHERE organization_id = $2
+		FOR UPDATE;
 	`,
 		userId,
 		orgId,
@@ -196,10 +199,10 @@ func (s *OrganizationServicePgImpl) RemoveUserFromOrg(ctx context.Context, orgId
 	}
 
 	if isOwner {
-		return common.ErrDbConflict
+		return common.ErrConflict
 	}
 
-	_, err = s.db.ExecContext(ctx, `
+	_, err = tx.ExecContext(ctx, `
 		DELETE FROM organizations_users
 		WHERE organization_id = $1 AND user_id = $2;
 	`,
@@ -214,7 +217,7 @@ func (s *OrganizationServicePgImpl) RemoveUserFromOrg(ctx context.Context, orgId
 }
 
 func (s *OrganizationServicePgImpl) SetOrganizationOwner(ctx context.Context, orgId string, userId uint32) error {
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := beginTx(ctx, s.db)
 	if err != nil {
 		return err
 	}
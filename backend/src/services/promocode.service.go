@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type PromoCodeService interface {
+	CreatePromoCode(ctx context.Context, promoCode models.PromoCode) (models.PromoCode, error)
+	GetPromoCodes(ctx context.Context, orgId string) ([]models.PromoCode, error)
+	DeletePromoCode(ctx context.Context, orgId string, promoCodeId uint32) error
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type PromoCodeServicePgImpl struct {
+	db *sql.DB
+}
+
+func NewPromoCodeServicePgImpl(db *sql.DB) PromoCodeService {
+	return &PromoCodeServicePgImpl{
+		db: db,
+	}
+}
+
+func (s *PromoCodeServicePgImpl) CreatePromoCode(ctx context.Context, promoCode models.PromoCode) (models.PromoCode, error) {
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return promoCode, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO promo_codes
+			(organization_id, code, discount_type, discount_value, max_uses, exp)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		RETURNING
+			promo_code_id,
+			uses,
+			created_at;
+		`,
+		promoCode.OrganizationId,
+		promoCode.Code,
+		promoCode.DiscountType,
+		promoCode.DiscountValue,
+		promoCode.MaxUses,
+		promoCode.Exp,
+	).Scan(
+		&promoCode.PromoCodeId,
+		&promoCode.Uses,
+		&promoCode.CreatedAt,
+	)
+	if err != nil {
+		return promoCode, common.FilterSqlPgError(err)
+	}
+
+	if len(promoCode.EventIds) > 0 {
+		// repeated ids are linked once and would fail the count below
+		eventIds := make([]string, len(promoCode.EventIds))
+		for i, id := range promoCode.EventIds {
+			eventIds[i] = strings.ToLower(id)
+		}
+		slices.Sort(eventIds)
+		promoCode.EventIds = slices.Compact(eventIds)
+
+		// only events owned by the same organization can be linked
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO promo_code_events (promo_code_id, event_id)
+			SELECT $1, event_id
+			FROM events
+			WHERE
+				event_id = ANY($2::UUID[]) AND
+				owner_organization_id = $3;
+			`,
+			promoCode.PromoCodeId,
+			pq.Array(promoCode.EventIds),
+			promoCode.OrganizationId,
+		)
+		if err != nil {
+			return promoCode, common.FilterSqlPgError(err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return promoCode, err
+		}
+		if n != int64(len(promoCode.EventIds)) {
+			return promoCode, common.ErrForeignKey
+		}
+	}
+
+	return promoCode, tx.Commit()
+}
+
+func (s *PromoCodeServicePgImpl) GetPromoCodes(ctx context.Context, orgId string) ([]models.PromoCode, error) {
+	promoCodes := []models.PromoCode{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT
+			pc.promo_code_id,
+			pc.organization_id,
+			pc.code,
+			pc.discount_type,
+			pc.discount_value,
+			pc.max_uses,
+			pc.uses,
+			pc.exp,
+			pc.created_at,
+			ARRAY_REMOVE(ARRAY_AGG(pce.event_id::TEXT), NULL)
+		FROM
+			promo_codes pc
+		LEFT JOIN
+			promo_code_events pce ON pc.promo_code_id = pce.promo_code_id
+		WHERE pc.organization_id = $1
+		GROUP BY pc.promo_code_id
+		ORDER BY pc.created_at DESC;
+		`,
+		orgId,
+	)
+	if err != nil {
+		return promoCodes, common.FilterSqlPgError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := models.PromoCode{}
+		err := rows.Scan(
+			&p.PromoCodeId,
+			&p.OrganizationId,
+			&p.Code,
+			&p.DiscountType,
+			&p.DiscountValue,
+			&p.MaxUses,
+			&p.Uses,
+			&p.Exp,
+			&p.CreatedAt,
+			pq.Array(&p.EventIds),
+		)
+		if err != nil {
+			return promoCodes, err
+		}
+		promoCodes = append(promoCodes, p)
+	}
+
+	return promoCodes, rows.Err()
+}
+
+func (s *PromoCodeServicePgImpl) DeletePromoCode(ctx context.Context, orgId string, promoCodeId uint32) error {
+	res, err := querier(ctx, s.db).ExecContext(ctx, `
+		DELETE FROM promo_codes
+		WHERE organization_id = $1 AND promo_code_id = $2;
+		`,
+		orgId,
+		promoCodeId,
+	)
+	if err != nil {
+		return common.FilterSqlPgError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return common.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestPromoCodeServicePgImpl_CreatePromoCode(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	var userId uint32
+	err = pgContainer.DB.QueryRowContext(ctx, `
+		INSERT INTO users (email, password_hash, first_name, last_name)
+		VALUES ('owner@email.com', 'hashtest', 'Owner', 'One')
+		RETURNING user_id;
+	`).Scan(&userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pgContainer.DB.ExecContext(ctx, `
+		INSERT INTO organizations (organization_id, organization_name, owner_user_id)
+		VALUES ('quack', 'Quack', $1), ('other', 'Other', $1);
+	`, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newEvent := func(orgId string) string {
+		var eventId string
+		err := pgContainer.DB.QueryRowContext(ctx, `
+			INSERT INTO events (event_name, owner_user_id, owner_organization_id, event_description)
+			VALUES ('Quack Week', $1, $2, '')
+			RETURNING event_id;
+		`, userId, orgId).Scan(&eventId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return eventId
+	}
+	eventId, otherEventId, foreignEventId := newEvent("quack"), newEvent("quack"), newEvent("other")
+
+	s := &PromoCodeServicePgImpl{db: pgContainer.DB}
+
+	tests := []struct {
+		name       string
+		code       string
+		eventIds   []string
+		wantLinked int
+		wantErr    error
+	}{
+		{"no events", "ALL", nil, 0, nil},
+		{"events", "BOTH", []string{eventId, otherEventId}, 2, nil},
+		{"duplicate events", "DUP", []string{eventId, eventId, strings.ToUpper(eventId)}, 1, nil},
+		{"event of another organization", "FOREIGN", []string{eventId, foreignEventId}, 0, common.ErrForeignKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := s.CreatePromoCode(ctx, models.PromoCode{
+				OrganizationId: "quack",
+				Code:           tt.code,
+				DiscountType:   common.DISCOUNT_TYPE_PERCENTAGE,
+				DiscountValue:  10,
+				EventIds:       tt.eventIds,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PromoCodeServicePgImpl.CreatePromoCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			var linked int
+			err = pgContainer.DB.QueryRowContext(ctx, `
+				SELECT COUNT(*) FROM promo_code_events WHERE promo_code_id = $1;
+			`, p.PromoCodeId).Scan(&linked)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if linked != tt.wantLinked || len(p.EventIds) != tt.wantLinked {
+				t.Errorf("PromoCodeServicePgImpl.CreatePromoCode() linked = %d, eventIds = %v, want %d", linked, p.EventIds, tt.wantLinked)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type StreamService interface {
+	// Subscribes to the events of `topics`, the ones after `lastEventId` are
+	// replayed first. The channel is closed once ctx is done, or if the
+	// subscriber falls too far behind, clients reconnect with Last-Event-ID
+	Subscribe(ctx context.Context, topics []string, lastEventId uint64) (<-chan models.StreamEvent, error)
+	// Fans the events published by every replica out to the subscribers of
+	// this one, blocks while the LISTEN connection is healthy
+	Listen() error
+	DeleteOldEvents() error
+}
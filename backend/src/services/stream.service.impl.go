@@ -0,0 +1,376 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+// publishStreamEvent stores the event and notifies every replica about it, pass
+// a *sql.Tx so it is only pushed if the change it belongs to is committed
+func publishStreamEvent(ctx context.Context, db Querier, topic string, eventType string, payload any) error {
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// NOTIFY payloads are limited to 8000 bytes, so only the id goes through it
+	var id uint64
+	err = db.QueryRowContext(ctx, `
+		WITH e AS (
+			INSERT INTO stream_events (topic, event_type, payload)
+			VALUES ($1, $2, $3)
+			RETURNING stream_event_id
+		)
+		SELECT stream_event_id, pg_notify($4, stream_event_id::TEXT) FROM e;
+		`,
+		topic,
+		eventType,
+		string(payloadJson),
+		common.STREAM_PG_CHANNEL,
+	).Scan(&id, new(string))
+
+	return err
+}
+
+type streamSubscriber struct {
+	topics map[string]struct{}
+
+	mu     sync.Mutex
+	events chan models.StreamEvent
+	closed bool
+	// live events held while the replay is queried, events is nil until then
+	pending []models.StreamEvent
+	// ids already sent by the replay, so they are not sent again live
+	replayed map[uint64]struct{}
+}
+
+// send queues the event without blocking, returns false if the subscriber had
+// to be closed for being too far behind
+func (sub *streamSubscriber) send(e models.StreamEvent) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.closed {
+		return true
+	}
+	if _, ok := sub.replayed[e.StreamEventId]; ok {
+		return true
+	}
+	if sub.events == nil {
+		if len(sub.pending) >= common.STREAM_SUBSCRIBER_BUFFER {
+			sub.closed = true
+			return false
+		}
+		sub.pending = append(sub.pending, e)
+		return true
+	}
+
+	select {
+	case sub.events <- e:
+		return true
+	default:
+		sub.closed = true
+		close(sub.events)
+		return false
+	}
+}
+
+func (sub *streamSubscriber) close() {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if !sub.closed {
+		sub.closed = true
+		if sub.events != nil {
+			close(sub.events)
+		}
+	}
+}
+
+// start queues the replay followed by the live events held meanwhile, a
+// subscriber dropped during the replay gets a closed channel
+func (sub *streamSubscriber) start(replay []models.StreamEvent) <-chan models.StreamEvent {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	sub.events = make(chan models.StreamEvent, len(replay)+len(sub.pending)+common.STREAM_SUBSCRIBER_BUFFER)
+	if sub.closed {
+		close(sub.events)
+		return sub.events
+	}
+
+	for _, e := range replay {
+		sub.replayed[e.StreamEventId] = struct{}{}
+		sub.events <- e
+	}
+	for _, e := range sub.pending {
+		if _, ok := sub.replayed[e.StreamEventId]; !ok {
+			sub.events <- e
+		}
+	}
+	sub.pending = nil
+
+	return sub.events
+}
+
+type StreamServicePgImpl struct {
+	db       *sql.DB
+	listener *pq.Listener
+
+	mu          sync.Mutex
+	subscribers map[*streamSubscriber]struct{}
+	// highest id fanned out, to catch up after the LISTEN connection drops
+	lastId uint64
+}
+
+func NewStreamServicePgImpl(db *sql.DB, pgConnStr string) StreamService {
+	listener := pq.NewListener(pgConnStr, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	})
+
+	err := listener.Listen(common.STREAM_PG_CHANNEL)
+	if err != nil {
+		panic(err)
+	}
+
+	return &StreamServicePgImpl{
+		db:          db,
+		listener:    listener,
+		subscribers: make(map[*streamSubscriber]struct{}),
+	}
+}
+
+func (s *StreamServicePgImpl) Subscribe(ctx context.Context, topics []string, lastEventId uint64) (<-chan models.StreamEvent, error) {
+	sub := &streamSubscriber{
+		topics:   make(map[string]struct{}),
+		replayed: make(map[uint64]struct{}),
+	}
+	for _, t := range topics {
+		sub.topics[t] = struct{}{}
+	}
+
+	// registered before the replay so nothing published meanwhile is lost, live
+	// events are held by the subscriber until the replay is queued
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	replay := []models.StreamEvent{}
+	if lastEventId > 0 {
+		var err error
+		replay, err = s.getEventsAfter(ctx, lastEventId, topics)
+		if err != nil {
+			s.mu.Lock()
+			delete(s.subscribers, sub)
+			s.mu.Unlock()
+			return nil, err
+		}
+	}
+
+	events := sub.start(replay)
+
+	go func() {
+		<-ctx.Done()
+		s.unsubscribe(sub)
+	}()
+
+	return events, nil
+}
+
+func (s *StreamServicePgImpl) unsubscribe(sub *streamSubscriber) {
+	s.mu.Lock()
+	delete(s.subscribers, sub)
+	s.mu.Unlock()
+
+	sub.close()
+}
+
+func (s *StreamServicePgImpl) getEventsAfter(ctx context.Context, lastEventId uint64, topics []string) ([]models.StreamEvent, error) {
+	events := []models.StreamEvent{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT
+			stream_event_id,
+			topic,
+			event_type,
+			payload,
+			created_at
+		FROM stream_events
+		WHERE
+			stream_event_id > $1 AND
+			($2::TEXT[] IS NULL OR topic = ANY($2))
+		ORDER BY stream_event_id;
+		`,
+		lastEventId,
+		pq.Array(topics),
+	)
+	if err != nil {
+		return events, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := models.StreamEvent{}
+		err := rows.Scan(
+			&e.StreamEventId,
+			&e.Topic,
+			&e.EventType,
+			&e.Payload,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+func (s *StreamServicePgImpl) getEvent(ctx context.Context, id uint64) (models.StreamEvent, error) {
+	e := models.StreamEvent{}
+	err := querier(ctx, s.db).QueryRowContext(ctx, `
+		SELECT
+			stream_event_id,
+			topic,
+			event_type,
+			payload,
+			created_at
+		FROM stream_events
+		WHERE stream_event_id = $1;
+		`,
+		id,
+	).Scan(
+		&e.StreamEventId,
+		&e.Topic,
+		&e.EventType,
+		&e.Payload,
+		&e.CreatedAt,
+	)
+
+	return e, err
+}
+
+func (s *StreamServicePgImpl) fanOut(e models.StreamEvent) {
+	s.mu.Lock()
+	subs := []*streamSubscriber{}
+	for sub := range s.subscribers {
+		if _, ok := sub.topics[e.Topic]; ok {
+			subs = append(subs, sub)
+		}
+	}
+	if e.StreamEventId > s.lastId {
+		s.lastId = e.StreamEventId
+	}
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		if !sub.send(e) {
+			s.unsubscribe(sub)
+		}
+	}
+}
+
+// catchUp fans out what was published while the LISTEN connection was down
+func (s *StreamServicePgImpl) catchUp(ctx context.Context) error {
+	s.mu.Lock()
+	lastId := s.lastId
+	s.mu.Unlock()
+
+	if lastId == 0 {
+		err := querier(ctx, s.db).QueryRowContext(ctx, `
+			SELECT COALESCE(MAX(stream_event_id), 0) FROM stream_events;
+			`,
+		).Scan(&lastId)
+		if err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.lastId = max(s.lastId, lastId)
+		s.mu.Unlock()
+		return nil
+	}
+
+	events, err := s.getEventsAfter(ctx, lastId, nil)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		s.fanOut(e)
+	}
+
+	return nil
+}
+
+func (s *StreamServicePgImpl) Listen() error {
+	ctx := context.Background()
+
+	err := s.catchUp(ctx)
+	if err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(time.Duration(common.STREAM_PG_PING_SECS) * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case n, ok := <-s.listener.Notify:
+			if !ok {
+				return errors.New("stream listener closed")
+			}
+
+			// nil is sent after the connection is re-established
+			if n == nil {
+				err := s.catchUp(ctx)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			id, err := strconv.ParseUint(n.Extra, 10, 64)
+			if err != nil {
+				common.Logger(ctx).Error(fmt.Sprintf("invalid stream notification: %s", n.Extra))
+				continue
+			}
+
+			e, err := s.getEvent(ctx, id)
+			if err != nil {
+				return err
+			}
+			s.fanOut(e)
+
+		case <-ping.C:
+			err := s.listener.Ping()
+			if err != nil {
+				common.Logger(ctx).Error(err.Error())
+			}
+		}
+	}
+}
+
+func (s *StreamServicePgImpl) DeleteOldEvents() error {
+	_, err := s.db.Exec(`
+		DELETE FROM stream_events
+		WHERE created_at < NOW() - $1 * INTERVAL '1 hour';
+		`,
+		common.STREAM_EVENTS_RETENTION_HOURS,
+	)
+
+	return err
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func receiveStreamEvent(t *testing.T, events <-chan models.StreamEvent) models.StreamEvent {
+	t.Helper()
+
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a stream event")
+	}
+
+	return models.StreamEvent{}
+}
+
+func TestStreamServicePgImpl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	userService := &UserServicePgImpl{db: pgContainer.DB}
+	err = userService.CreateUser(ctx, models.User{Email: "user@email.com", PasswordHash: "hashtest", FirstName: "Test", LastName: "User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := userService.GetUser(ctx, "user@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewStreamServicePgImpl(pgContainer.DB, pgContainer.ConnString)
+	go s.Listen()
+
+	userTopic := fiddlers.GetUserStreamTopic(user.UserId)
+	otherTopic := fiddlers.GetOrganizationStreamTopic("other")
+
+	subCtx, cancel := context.WithCancel(ctx)
+	events, err := s.Subscribe(subCtx, []string{userTopic}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the subscribed topics are delivered
+	err = publishStreamEvent(ctx, pgContainer.DB, otherTopic, common.STREAM_EVENT_REGISTRATIONS, models.RegistrationsStreamEvent{EventId: "e", Registrations: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifications := NewNotificationServicePgImpl(pgContainer.DB)
+	_, err = notifications.Notify(ctx, user.UserId, common.NOTIFICATION_TYPE_PAYMENT_ACCEPTED, models.PaymentAcceptedNotification{PaymentId: "pay_1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := receiveStreamEvent(t, events)
+	if first.Topic != userTopic || first.EventType != common.STREAM_EVENT_NOTIFICATION {
+		t.Fatalf("Subscribe() got %s %s, want %s %s", first.Topic, first.EventType, userTopic, common.STREAM_EVENT_NOTIFICATION)
+	}
+
+	cancel()
+	for range events {
+	}
+
+	// missed while disconnected
+	_, err = notifications.Notify(ctx, user.UserId, common.NOTIFICATION_TYPE_PAYMENT_ACCEPTED, models.PaymentAcceptedNotification{PaymentId: "pay_2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err = s.Subscribe(ctx, []string{userTopic}, first.StreamEventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := receiveStreamEvent(t, events)
+	if replayed.StreamEventId <= first.StreamEventId {
+		t.Fatalf("Subscribe() replayed %d, want after %d", replayed.StreamEventId, first.StreamEventId)
+	}
+
+	select {
+	case e := <-events:
+		t.Fatalf("Subscribe() sent %d twice", e.StreamEventId)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	err = s.DeleteOldEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamServicePgImpl_SubscribeReplayError(t *testing.T) {
+	db, err := sql.Open("postgres", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fails every query, the replay included
+	db.Close()
+
+	s := &StreamServicePgImpl{
+		db:          db,
+		subscribers: make(map[*streamSubscriber]struct{}),
+	}
+	topic := fiddlers.GetUserStreamTopic(1)
+
+	done := make(chan error)
+	go func() {
+		_, err := s.Subscribe(context.Background(), []string{topic}, 1)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Subscribe() error = nil, want the replay error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscribe() deadlocked on a replay error")
+	}
+
+	s.mu.Lock()
+	n := len(s.subscribers)
+	s.mu.Unlock()
+	if n != 0 {
+		t.Errorf("Subscribe() left %d subscribers, want 0", n)
+	}
+
+	// nothing is left to deliver to
+	s.fanOut(models.StreamEvent{StreamEventId: 2, Topic: topic})
+}
+
+func TestStreamSubscriber_start(t *testing.T) {
+	sub := &streamSubscriber{replayed: make(map[uint64]struct{})}
+
+	// published during the replay, the first one also read by it
+	for _, id := range []uint64{2, 3} {
+		if !sub.send(models.StreamEvent{StreamEventId: id}) {
+			t.Fatalf("send(%d) = false, want true", id)
+		}
+	}
+
+	events := sub.start([]models.StreamEvent{{StreamEventId: 1}, {StreamEventId: 2}})
+	sub.close()
+
+	got := []uint64{}
+	for e := range events {
+		got = append(got, e.StreamEventId)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("start() sent %v, want [1 2 3]", got)
+	}
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier is satisfied by *sql.DB, *sql.Conn and *sql.Tx, the Pg services run
+// their queries through one so they can join a UnitOfWork
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type UnitOfWork interface {
+	// Runs fn in a transaction, the Pg service calls made with the ctx fn gets
+	// join it and are only committed if fn returns nil. Nested calls join the
+	// outer transaction through a savepoint.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+type txCtxKey struct{}
+
+var savepointSeq atomic.Uint64
+
+// querier returns the transaction of the UnitOfWork ctx belongs to, or db
+func querier(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txCtxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// unitTx is what the services use instead of a bare *sql.Tx, inside a
+// UnitOfWork it is a savepoint of the outer transaction and Commit only
+// releases it, so the outer one decides
+type unitTx struct {
+	*sql.Tx
+
+	ctx       context.Context
+	savepoint string
+	done      bool
+}
+
+// beginTx starts a transaction, or a savepoint when ctx already has one
+func beginTx(ctx context.Context, db *sql.DB) (*unitTx, error) {
+	if tx, ok := ctx.Value(txCtxKey{}).(*sql.Tx); ok {
+		savepoint := fmt.Sprintf("unit_tx_%d", savepointSeq.Add(1))
+		_, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint+";")
+		if err != nil {
+			return nil, err
+		}
+
+		return &unitTx{Tx: tx, ctx: ctx, savepoint: savepoint}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &unitTx{Tx: tx, ctx: ctx}, nil
+}
+
+func (t *unitTx) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+
+	if t.savepoint != "" {
+		_, err := t.Tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint+";")
+		return err
+	}
+
+	return t.Tx.Commit()
+}
+
+// Rollback is a no-op after Commit, so it can be deferred
+func (t *unitTx) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+
+	if t.savepoint != "" {
+		_, err := t.Tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+t.savepoint+";")
+		return err
+	}
+
+	return t.Tx.Rollback()
+}
+
+type UnitOfWorkPgImpl struct {
+	db *sql.DB
+}
+
+func NewUnitOfWorkPgImpl(db *sql.DB) UnitOfWork {
+	return &UnitOfWorkPgImpl{
+		db: db,
+	}
+}
+
+func (u *UnitOfWorkPgImpl) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := beginTx(ctx, u.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(context.WithValue(ctx, txCtxKey{}, tx.Tx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestUnitOfWorkPgImpl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	uow := NewUnitOfWorkPgImpl(pgContainer.DB)
+	userService := NewUserServicePgImpl(pgContainer.DB)
+	orgService := NewOrganizationServicePgImpl(pgContainer.DB)
+
+	err = userService.CreateUser(ctx, models.User{Email: "owner@email.com", PasswordHash: "hash", FirstName: "Owner", LastName: "One"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := userService.GetUser(ctx, "owner@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errAbort := errors.New("abort")
+
+	t.Run("rollback", func(t *testing.T) {
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			err := orgService.CreateOrganization(ctx, models.Organization{OrganizationId: "AAAAA", OrganizationName: "Rolled back", OwnerUserId: owner.UserId})
+			if err != nil {
+				return err
+			}
+			err = userService.SetLocale(ctx, owner.UserId, "en-US")
+			if err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("expected errAbort, got %v", err)
+		}
+
+		_, err = orgService.GetOrganization(ctx, "AAAAA")
+		if err == nil {
+			t.Fatal("expected the organization to be rolled back")
+		}
+		user, err := userService.GetUser(ctx, owner.Email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Locale != owner.Locale {
+			t.Fatalf("expected the locale to be rolled back, got %s", user.Locale)
+		}
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			err := orgService.CreateOrganization(ctx, models.Organization{OrganizationId: "BBBBB", OrganizationName: "Committed", OwnerUserId: owner.UserId})
+			if err != nil {
+				return err
+			}
+			// reads inside the unit see its uncommitted writes
+			_, err = orgService.GetOrganization(ctx, "BBBBB")
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = orgService.GetOrganization(ctx, "BBBBB")
+		if err != nil {
+			t.Fatalf("expected the organization to be committed: %v", err)
+		}
+	})
+
+	t.Run("nested failure only rolls back its savepoint", func(t *testing.T) {
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			err := orgService.CreateOrganization(ctx, models.Organization{OrganizationId: "CCCCC", OrganizationName: "Outer", OwnerUserId: owner.UserId})
+			if err != nil {
+				return err
+			}
+
+			// the owner cannot be removed, the error is handled by the caller
+			err = orgService.RemoveUserFromOrg(ctx, "CCCCC", owner.UserId)
+			if err == nil {
+				t.Error("expected removing the owner to fail")
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = orgService.GetOrganization(ctx, "CCCCC")
+		if err != nil {
+			t.Fatalf("expected the organization to be committed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type UploadService interface {
+	// Registers the pending upload and returns the url the browser PUTs the object to
+	CreateUpload(ctx context.Context, upload models.Upload) (models.Upload, string, error)
+	// Validates the uploaded object against what was declared and stores its
+	// image variants, returning their urls
+	ConfirmUpload(ctx context.Context, userId uint32, uploadId string, purpose string, targetId string) (models.ImageSrcset, error)
+	DeleteExpiredUploads() error
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type UploadServicePgImpl struct {
+	db           *sql.DB
+	objService   ObjectService
+	imageService ImageService
+	bucket       string
+}
+
+func NewUploadServicePgImpl(db *sql.DB, objService ObjectService, imageService ImageService, bucket string) UploadService {
+	return &UploadServicePgImpl{
+		db:           db,
+		objService:   objService,
+		imageService: imageService,
+		bucket:       bucket,
+	}
+}
+
+func (s *UploadServicePgImpl) CreateUpload(ctx context.Context, upload models.Upload) (models.Upload, string, error) {
+	err := fiddlers.ValidateUploadRequest(upload.Purpose, upload.ContentType, upload.Size)
+	if err != nil {
+		return upload, "", err
+	}
+
+	expIn := time.Duration(common.UPLOAD_URL_EXP_MINS) * time.Minute
+	upload.Exp = time.Now().Add(expIn)
+
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return upload, "", err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO uploads (user_id, purpose, target_id, content_type, size, exp)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING upload_id, created_at;
+		`,
+		upload.UserId,
+		upload.Purpose,
+		upload.TargetId,
+		upload.ContentType,
+		upload.Size,
+		upload.Exp,
+	).Scan(
+		&upload.UploadId,
+		&upload.CreatedAt,
+	)
+	if err != nil {
+		return upload, "", common.FilterSqlPgError(err)
+	}
+
+	pendingPath := fiddlers.GetUploadPendingPath(upload.UploadId)
+	err = referenceObjects(ctx, tx, models.ObjectReference{UploadId: &upload.UploadId}, pendingPath)
+	if err != nil {
+		return upload, "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return upload, "", err
+	}
+
+	uploadUrl, err := s.objService.UploadUrl(ctx, s.bucket, pendingPath, int64(upload.Size), expIn)
+	if err != nil {
+		return upload, "", err
+	}
+
+	return upload, uploadUrl, nil
+}
+
+func (s *UploadServicePgImpl) ConfirmUpload(ctx context.Context, userId uint32, uploadId string, purpose string, targetId string) (models.ImageSrcset, error) {
+	tx, err := beginTx(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// locked so a double confirmation cannot process the object twice
+	upload := models.Upload{}
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			upload_id,
+			user_id,
+			purpose,
+			target_id,
+			content_type,
+			size,
+			exp,
+			created_at
+		FROM uploads
+		WHERE
+			upload_id = $1 AND
+			user_id = $2 AND
+			purpose = $3 AND
+			target_id = $4 AND
+			confirmed_at IS NULL AND
+			exp > NOW()
+		FOR UPDATE;
+		`,
+		uploadId,
+		userId,
+		purpose,
+		targetId,
+	).Scan(
+		&upload.UploadId,
+		&upload.UserId,
+		&upload.Purpose,
+		&upload.TargetId,
+		&upload.ContentType,
+		&upload.Size,
+		&upload.Exp,
+		&upload.CreatedAt,
+	)
+	if err != nil {
+		return nil, common.FilterSqlPgError(err)
+	}
+
+	pendingPath := fiddlers.GetUploadPendingPath(upload.UploadId)
+	info, err := s.objService.Stat(ctx, s.bucket, pendingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fiddlers.ValidateUploadedObject(upload, info)
+	if err != nil {
+		return nil, err
+	}
+
+	// the metadata is set by the client, the pipeline decodes the data again
+	data, err := s.objService.Download(ctx, s.bucket, pendingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	srcset, err := s.imageService.StoreImage(ctx, upload.Purpose, upload.TargetId, data)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE uploads
+		SET confirmed_at = NOW()
+		WHERE upload_id = $1;
+		`,
+		upload.UploadId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = unreferenceObjects(ctx, tx, pendingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// the variants are the ones in use
+	err = s.objService.Delete(ctx, s.bucket, pendingPath)
+	if err != nil {
+		common.Logger(ctx).Warn(fmt.Sprintf("deleting confirmed upload %s: %s", upload.UploadId, err.Error()))
+	}
+
+	return srcset, nil
+}
+
+// Forgets the uploads never confirmed, and their objects if they were uploaded
+func (s *UploadServicePgImpl) DeleteExpiredUploads() error {
+	ctx := context.Background()
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		DELETE FROM uploads
+		WHERE
+			exp < NOW() AND
+			confirmed_at IS NULL
+		RETURNING upload_id;
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uploadId string
+		err := rows.Scan(&uploadId)
+		if err != nil {
+			return err
+		}
+
+		err = s.objService.Delete(ctx, s.bucket, fiddlers.GetUploadPendingPath(uploadId))
+		if err != nil {
+			common.Logger(ctx).Warn(fmt.Sprintf("deleting expired upload %s: %s", uploadId, err.Error()))
+		}
+	}
+
+	return rows.Err()
+}
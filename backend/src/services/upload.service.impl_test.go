@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"strconv"
+	"testing"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+func TestUploadServicePgImpl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	db := pgContainer.DB
+	userService := &UserServicePgImpl{db: db}
+	err = userService.CreateUser(ctx, models.User{Email: "test@email.com", PasswordHash: "hashtest", FirstName: "Test", LastName: "User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := userService.GetUser(ctx, "test@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targetId := strconv.Itoa(int(user.UserId))
+	objService := NewObjectServiceFsImpl(t.TempDir(), "http://127.0.0.1:8080/", "secret")
+	s := NewUploadServicePgImpl(db, objService, NewImageServicePgImpl(db, objService, "bucket"), "bucket")
+
+	buf := bytes.Buffer{}
+	err = png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := buf.Bytes()
+
+	_, _, err = s.CreateUpload(ctx, models.Upload{
+		UserId:      user.UserId,
+		Purpose:     common.UPLOAD_PURPOSE_AVATAR,
+		TargetId:    targetId,
+		ContentType: "image/png",
+		Size:        common.UPLOAD_AVATAR_MAX_BYTES + 1,
+	})
+	if err != common.ErrUploadTooLarge {
+		t.Errorf("CreateUpload() too large error = %v, want %v", err, common.ErrUploadTooLarge)
+	}
+
+	upload, uploadUrl, err := s.CreateUpload(ctx, models.Upload{
+		UserId:      user.UserId,
+		Purpose:     common.UPLOAD_PURPOSE_AVATAR,
+		TargetId:    targetId,
+		ContentType: "image/png",
+		Size:        int64(len(img)),
+	})
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	if upload.UploadId == "" || uploadUrl == "" {
+		t.Fatalf("CreateUpload() = %+v, %s", upload, uploadUrl)
+	}
+
+	// nothing was uploaded yet
+	_, err = s.ConfirmUpload(ctx, user.UserId, upload.UploadId, common.UPLOAD_PURPOSE_AVATAR, targetId)
+	if err != common.ErrObjectNotFound {
+		t.Errorf("ConfirmUpload() not uploaded error = %v, want %v", err, common.ErrObjectNotFound)
+	}
+
+	pendingPath := fiddlers.GetUploadPendingPath(upload.UploadId)
+	err = objService.Upload(ctx, "bucket", pendingPath, int64(len(img)), bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.ConfirmUpload(ctx, user.UserId, upload.UploadId, common.UPLOAD_PURPOSE_EVENT_BANNER, targetId)
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("ConfirmUpload() other purpose error = %v, want %v", err, common.ErrNotFound)
+	}
+
+	srcset, err := s.ConfirmUpload(ctx, user.UserId, upload.UploadId, common.UPLOAD_PURPOSE_AVATAR, targetId)
+	if err != nil {
+		t.Fatalf("ConfirmUpload() error = %v", err)
+	}
+	if len(srcset) != len(common.IMAGE_VARIANTS) || srcset[common.IMAGE_VARIANT_FULL].Webp == "" {
+		t.Errorf("ConfirmUpload() = %+v", srcset)
+	}
+
+	thumbnailPath := fiddlers.GetImageVariantPath(common.UPLOAD_PURPOSE_AVATAR, targetId, fiddlers.GetImageId(img), common.IMAGE_VARIANT_THUMBNAIL, "webp")
+	_, err = objService.Stat(ctx, "bucket", thumbnailPath)
+	if err != nil {
+		t.Errorf("thumbnail variant error = %v", err)
+	}
+	_, err = objService.Download(ctx, "bucket", pendingPath)
+	if err != common.ErrObjectNotFound {
+		t.Errorf("pending object error = %v, want %v", err, common.ErrObjectNotFound)
+	}
+
+	_, err = s.ConfirmUpload(ctx, user.UserId, upload.UploadId, common.UPLOAD_PURPOSE_AVATAR, targetId)
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("ConfirmUpload() twice error = %v, want %v", err, common.ErrNotFound)
+	}
+
+	isReferenced := func(objPath string) bool {
+		var referenced bool
+		err := db.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM object_references WHERE object_path = $1);
+		`, objPath).Scan(&referenced)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return referenced
+	}
+
+	// a new image gets new urls and takes the references of the previous one
+	newBuf := bytes.Buffer{}
+	err = png.Encode(&newBuf, image.NewRGBA(image.Rect(0, 0, 8, 8)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newImg := newBuf.Bytes()
+	upload, _, err = s.CreateUpload(ctx, models.Upload{
+		UserId:      user.UserId,
+		Purpose:     common.UPLOAD_PURPOSE_AVATAR,
+		TargetId:    targetId,
+		ContentType: "image/png",
+		Size:        int64(len(newImg)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = objService.Upload(ctx, "bucket", fiddlers.GetUploadPendingPath(upload.UploadId), int64(len(newImg)), bytes.NewReader(newImg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSrcset, err := s.ConfirmUpload(ctx, user.UserId, upload.UploadId, common.UPLOAD_PURPOSE_AVATAR, targetId)
+	if err != nil {
+		t.Fatalf("ConfirmUpload() new image error = %v", err)
+	}
+	if newSrcset[common.IMAGE_VARIANT_FULL].Webp == srcset[common.IMAGE_VARIANT_FULL].Webp {
+		t.Errorf("ConfirmUpload() new image url = %s, want a new one", newSrcset[common.IMAGE_VARIANT_FULL].Webp)
+	}
+	if isReferenced(thumbnailPath) {
+		t.Errorf("previous thumbnail %s still referenced", thumbnailPath)
+	}
+	newThumbnailPath := fiddlers.GetImageVariantPath(common.UPLOAD_PURPOSE_AVATAR, targetId, fiddlers.GetImageId(newImg), common.IMAGE_VARIANT_THUMBNAIL, "webp")
+	if !isReferenced(newThumbnailPath) {
+		t.Errorf("new thumbnail %s not referenced", newThumbnailPath)
+	}
+
+	// a text file declared as an image is refused
+	upload, _, err = s.CreateUpload(ctx, models.Upload{
+		UserId:      user.UserId,
+		Purpose:     common.UPLOAD_PURPOSE_AVATAR,
+		TargetId:    targetId,
+		ContentType: "image/png",
+		Size:        5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = objService.Upload(ctx, "bucket", fiddlers.GetUploadPendingPath(upload.UploadId), 5, bytes.NewReader([]byte("quack")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ConfirmUpload(ctx, user.UserId, upload.UploadId, common.UPLOAD_PURPOSE_AVATAR, targetId)
+	if err != common.ErrImageFormatInvalid {
+		t.Errorf("ConfirmUpload() non image error = %v, want %v", err, common.ErrImageFormatInvalid)
+	}
+
+	_, err = db.ExecContext(ctx, `UPDATE uploads SET exp = NOW() - INTERVAL '1 minute';`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.DeleteExpiredUploads()
+	if err != nil {
+		t.Fatalf("DeleteExpiredUploads() error = %v", err)
+	}
+	_, err = objService.Download(ctx, "bucket", fiddlers.GetUploadPendingPath(upload.UploadId))
+	if err != common.ErrObjectNotFound {
+		t.Errorf("expired object error = %v, want %v", err, common.ErrObjectNotFound)
+	}
+
+	var left int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM uploads;`).Scan(&left)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left != 2 {
+		t.Errorf("uploads left = %d, want only the confirmed ones", left)
+	}
+}
@@ -21,6 +21,10 @@ type UserService interface {
 
 	EditUser(ctx context.Context, userId uint32, user schemas.EditUser) error
 	SetAvatarUrl(ctx context.Context, userId uint32, url string) error
+	SetLocale(ctx context.Context, userId uint32, locale string) error
+	SetAnnouncementsOptOut(ctx context.Context, userId uint32, optOut bool) error
+	// Opts the owner of the `unsubscribe_token` out of announcements
+	Unsubscribe(ctx context.Context, token string) error
 
 	DeleteExpiredPwResets() error
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
 	"github.com/patos-ufscar/quack-week/models"
 	"github.com/patos-ufscar/quack-week/schemas"
 )
@@ -22,16 +23,17 @@ func NewUserServicePgImpl(db *sql.DB) UserService {
 
 func (s *UserServicePgImpl) CreateUser(ctx context.Context, user models.User) error {
 	query := `
-		INSERT INTO users (email, password_hash, first_name, last_name, date_of_birth)
-		VALUES ($1, $2, $3, $4, $5);
+		INSERT INTO users (email, password_hash, first_name, last_name, date_of_birth, locale)
+		VALUES ($1, $2, $3, $4, $5, $6);
 	`
 
-	err := s.db.QueryRowContext(ctx, query,
+	err := querier(ctx, s.db).QueryRowContext(ctx, query,
 		user.Email,
 		user.PasswordHash,
 		user.FirstName,
 		user.LastName,
 		user.DateOfBirth,
+		fiddlers.ResolveLocale(user.Locale),
 	).Err()
 
 	if err != nil {
@@ -42,7 +44,7 @@ func (s *UserServicePgImpl) CreateUser(ctx context.Context, user models.User) er
 }
 
 func (s *UserServicePgImpl) CreateUnconfirmedUser(ctx context.Context, unconfirmedUser models.UnconfirmedUser) error {
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := beginTx(ctx, s.db)
 	if err != nil {
 		return err
 	}
@@ -61,19 +63,20 @@ func (s *UserServicePgImpl) CreateUnconfirmedUser(ctx context.Context, unconfirm
 	}
 
 	if count != 0 {
-		return common.ErrDbConflict
+		return common.ErrConflict
 	}
 
 	err = tx.QueryRowContext(ctx, `
-			INSERT INTO unconfirmed_users (email, otp, password_hash, first_name, last_name, date_of_birth)
-			VALUES ($1, $2, $3, $4, $5, $6)
+			INSERT INTO unconfirmed_users (email, otp, password_hash, first_name, last_name, date_of_birth, locale)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
 			ON CONFLICT (email) DO UPDATE
 			SET 
 				otp = EXCLUDED.otp,
 				password_hash = EXCLUDED.password_hash,
 				first_name = EXCLUDED.first_name,
 				last_name = EXCLUDED.last_name,
-				date_of_birth = EXCLUDED.date_of_birth;
+				date_of_birth = EXCLUDED.date_of_birth,
+				locale = EXCLUDED.locale;
 		`,
 		unconfirmedUser.Email,
 		unconfirmedUser.Otp,
@@ -81,6 +84,7 @@ func (s *UserServicePgImpl) CreateUnconfirmedUser(ctx context.Context, unconfirm
 		unconfirmedUser.FirstName,
 		unconfirmedUser.LastName,
 		unconfirmedUser.DateOfBirth,
+		fiddlers.ResolveLocale(unconfirmedUser.Locale),
 	).Err()
 
 	if err != nil {
@@ -91,7 +95,7 @@ func (s *UserServicePgImpl) CreateUnconfirmedUser(ctx context.Context, unconfirm
 }
 
 func (s *UserServicePgImpl) ConfirmUser(ctx context.Context, otp string) error {
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := beginTx(ctx, s.db)
 	if err != nil {
 		return err
 	}
@@ -99,14 +103,15 @@ func (s *UserServicePgImpl) ConfirmUser(ctx context.Context, otp string) error {
 	defer tx.Rollback()
 
 	unconfirmedUser := models.UnconfirmedUser{}
-	err = s.db.QueryRowContext(ctx, `
+	err = tx.QueryRowContext(ctx, `
 			SELECT
 				email,
 				otp,
 				password_hash,
 				first_name,
 				last_name,
-				date_of_birth
+				date_of_birth,
+				locale
 			FROM
 				unconfirmed_users WHERE otp = $1
 		`, otp).Scan(
@@ -116,20 +121,22 @@ func (s *UserServicePgImpl) ConfirmUser(ctx context.Context, otp string) error {
 		&unconfirmedUser.FirstName,
 		&unconfirmedUser.LastName,
 		&unconfirmedUser.DateOfBirth,
+		&unconfirmedUser.Locale,
 	)
 	if err != nil {
 		return common.FilterSqlPgError(err)
 	}
 
 	_, err = tx.ExecContext(ctx, `
-			INSERT INTO users (email, password_hash, first_name, last_name, date_of_birth)
-			VALUES ($1, $2, $3, $4, $5);
+			INSERT INTO users (email, password_hash, first_name, last_name, date_of_birth, locale)
+			VALUES ($1, $2, $3, $4, $5, $6);
 		`,
 		unconfirmedUser.Email,
 		unconfirmedUser.PasswordHash,
 		unconfirmedUser.FirstName,
 		unconfirmedUser.LastName,
 		unconfirmedUser.DateOfBirth,
+		unconfirmedUser.Locale,
 	)
 	if err != nil {
 		return common.FilterSqlPgError(err)
@@ -159,13 +166,14 @@ func (s *UserServicePgImpl) GetUser(ctx context.Context, email string) (models.U
 			avatar_url,
 			created_at,
 			updated_at,
-			is_active
+			is_active,
+			locale
 		FROM users WHERE email = $1
 	`
 
 	user := models.User{}
 
-	err := s.db.QueryRowContext(ctx, query, email).Scan(
+	err := querier(ctx, s.db).QueryRowContext(ctx, query, email).Scan(
 		&user.UserId,
 		&user.Email,
 		&user.PasswordHash,
@@ -176,6 +184,7 @@ func (s *UserServicePgImpl) GetUser(ctx context.Context, email string) (models.U
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.IsActive,
+		&user.Locale,
 	)
 	if err != nil {
 		return user, common.FilterSqlPgError(err)
@@ -196,13 +205,14 @@ func (s *UserServicePgImpl) GetUserFromId(ctx context.Context, id uint32) (model
 			avatar_url,
 			created_at,
 			updated_at,
-			is_active
+			is_active,
+			locale
 		FROM users WHERE user_id = $1
 	`
 
 	user := models.User{}
 
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	err := querier(ctx, s.db).QueryRowContext(ctx, query, id).Scan(
 		&user.UserId,
 		&user.Email,
 		&user.PasswordHash,
@@ -213,6 +223,7 @@ func (s *UserServicePgImpl) GetUserFromId(ctx context.Context, id uint32) (model
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.IsActive,
+		&user.Locale,
 	)
 	if err != nil {
 		return user, common.FilterSqlPgError(err)
@@ -233,13 +244,14 @@ func (s *UserServicePgImpl) GetUsers(ctx context.Context) ([]models.User, error)
 			avatar_url,
 			created_at,
 			updated_at,
-			is_active
+			is_active,
+			locale
 		FROM users;
 	`
 
 	users := []models.User{}
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := querier(ctx, s.db).QueryContext(ctx, query)
 	if err != nil {
 		return users, common.FilterSqlPgError(err)
 	}
@@ -258,6 +270,7 @@ func (s *UserServicePgImpl) GetUsers(ctx context.Context) ([]models.User, error)
 			&u.CreatedAt,
 			&u.UpdatedAt,
 			&u.IsActive,
+			&u.Locale,
 		)
 		if err != nil {
 			return users, err
@@ -284,7 +297,7 @@ func (s *UserServicePgImpl) GetUserOrgs(ctx context.Context, userId uint32) ([]s
 
 	orgs := []schemas.OrganizationOutput{}
 
-	rows, err := s.db.QueryContext(ctx, query, userId)
+	rows, err := querier(ctx, s.db).QueryContext(ctx, query, userId)
 	if err != nil {
 		return orgs, common.FilterSqlPgError(err)
 	}
@@ -308,7 +321,7 @@ func (s *UserServicePgImpl) InitPasswordReset(ctx context.Context, userId uint32
 		VALUES ($1, $2, $3);
 	`
 
-	_, err := s.db.ExecContext(ctx, query,
+	_, err := querier(ctx, s.db).ExecContext(ctx, query,
 		userId,
 		otp,
 		time.Now().Add(24*time.Hour*time.Duration(common.PASSWORD_RESET_TIMEOUT_DAYS)),
@@ -325,7 +338,7 @@ func (s *UserServicePgImpl) GetPasswordReset(ctx context.Context, otp string) (m
 	`
 	var passReset models.PasswordReset
 
-	err := s.db.QueryRowContext(ctx, query, otp).Scan(
+	err := querier(ctx, s.db).QueryRowContext(ctx, query, otp).Scan(
 		&passReset.UserId,
 		&passReset.Otp,
 		&passReset.Exp,
@@ -335,7 +348,7 @@ func (s *UserServicePgImpl) GetPasswordReset(ctx context.Context, otp string) (m
 }
 
 func (s *UserServicePgImpl) UpdateUserPassword(ctx context.Context, userId uint32, pw string) error {
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := beginTx(ctx, s.db)
 	if err != nil {
 		return err
 	}
@@ -369,7 +382,7 @@ func (s *UserServicePgImpl) UpdateUserPassword(ctx context.Context, userId uint3
 
 func (s *UserServicePgImpl) EditUser(ctx context.Context, userId uint32, user schemas.EditUser) error {
 
-	_, err := s.db.ExecContext(ctx, `
+	_, err := querier(ctx, s.db).ExecContext(ctx, `
 			UPDATE users
 			SET 
 				first_name = $1,
@@ -395,7 +408,7 @@ func (s *UserServicePgImpl) DeleteExpiredPwResets() error {
 }
 
 func (s *UserServicePgImpl) SetAvatarUrl(ctx context.Context, userId uint32, url string) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := querier(ctx, s.db).ExecContext(ctx, `
 			UPDATE users
 			SET 
 				avatar_url = $1
@@ -407,3 +420,46 @@ func (s *UserServicePgImpl) SetAvatarUrl(ctx context.Context, userId uint32, url
 
 	return err
 }
+
+func (s *UserServicePgImpl) SetLocale(ctx context.Context, userId uint32, locale string) error {
+	_, err := querier(ctx, s.db).ExecContext(ctx, `
+			UPDATE users
+			SET 
+				locale = $1
+			WHERE user_id = $2;
+		`,
+		fiddlers.ResolveLocale(locale),
+		userId,
+	)
+
+	return err
+}
+
+func (s *UserServicePgImpl) SetAnnouncementsOptOut(ctx context.Context, userId uint32, optOut bool) error {
+	_, err := querier(ctx, s.db).ExecContext(ctx, `
+			UPDATE users
+			SET 
+				announcements_opt_out = $1
+			WHERE user_id = $2;
+		`,
+		optOut,
+		userId,
+	)
+
+	return err
+}
+
+func (s *UserServicePgImpl) Unsubscribe(ctx context.Context, token string) error {
+	var userId uint32
+	err := querier(ctx, s.db).QueryRowContext(ctx, `
+			UPDATE users
+			SET 
+				announcements_opt_out = true
+			WHERE unsubscribe_token::TEXT = $1
+			RETURNING user_id;
+		`,
+		token,
+	).Scan(&userId)
+
+	return common.FilterSqlPgError(err)
+}
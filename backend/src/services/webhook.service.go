@@ -0,0 +1,19 @@
+package services
+
+import (
+	"context"
+
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type WebhookService interface {
+	// Registers the webhook with a new secret, which is only returned here, fails
+	// with common.ErrWebhookUrlForbidden if the url resolves to a non-public address
+	CreateWebhook(ctx context.Context, orgId string, url string, eventTypes []string) (models.Webhook, error)
+	GetWebhooks(ctx context.Context, orgId string) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, orgId string, webhookId uint32) error
+	GetDeliveries(ctx context.Context, orgId string, webhookId uint32, limit int, offset int) ([]models.WebhookDelivery, error)
+	// Queues a new delivery with the payload of `deliveryId`, the log keeps both
+	Redeliver(ctx context.Context, orgId string, deliveryId uint64) (models.WebhookDelivery, error)
+	DeliverPending() error
+}
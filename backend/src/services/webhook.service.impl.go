@@ -0,0 +1,486 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/models"
+	"github.com/patos-ufscar/quack-week/tracing"
+)
+
+// enqueueWebhooks queues a delivery for every webhook of the organization
+// subscribed to `eventType`, pass a *sql.Tx so they are only sent if the
+// change they belong to is committed
+func enqueueWebhooks(ctx context.Context, db Querier, orgId string, eventType string, data any) error {
+	dataJson, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+		SELECT webhook_id, $2, $3
+		FROM webhooks
+		WHERE
+			organization_id = $1 AND
+			$2 = ANY(event_types);
+		`,
+		orgId,
+		eventType,
+		string(dataJson),
+	)
+
+	return err
+}
+
+// a claimed delivery with what is needed to send it
+type webhookJob struct {
+	delivery models.WebhookDelivery
+	orgId    string
+	url      string
+	secret   string
+}
+
+type WebhookServicePgImpl struct {
+	db     *sql.DB
+	client *http.Client
+	// addresses webhooks may be sent to, checked on create and on every dial
+	allowIp func(ip net.IP) bool
+}
+
+func NewWebhookServicePgImpl(db *sql.DB) WebhookService {
+	s := &WebhookServicePgImpl{
+		db:      db,
+		allowIp: fiddlers.IsWebhookIpAllowed,
+	}
+
+	// checked again once resolved, or a host could point somewhere else after
+	// the webhook was created
+	dialer := &net.Dialer{
+		Timeout: time.Duration(common.WEBHOOK_TIMEOUT_SECS) * time.Second,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !s.allowIp(ip) {
+				return fmt.Errorf("%w: %s", common.ErrWebhookUrlForbidden, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be the one dialed and checked
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	s.client = &http.Client{
+		Transport: tracing.Transport(transport),
+		Timeout:   time.Duration(common.WEBHOOK_TIMEOUT_SECS) * time.Second,
+		// a redirect is reported as a failure instead of followed
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return s
+}
+
+// checkUrl resolves the host of the webhook url, failing with
+// common.ErrWebhookUrlForbidden if any of its addresses is not allowed
+func (s *WebhookServicePgImpl) checkUrl(ctx context.Context, rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return fmt.Errorf("%w: %w", common.ErrWebhookUrlForbidden, err)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %w", common.ErrWebhookUrlForbidden, err)
+	}
+
+	for _, addr := range addrs {
+		if !s.allowIp(addr.IP) {
+			return fmt.Errorf("%w: %s", common.ErrWebhookUrlForbidden, addr.IP)
+		}
+	}
+
+	return nil
+}
+
+func (s *WebhookServicePgImpl) CreateWebhook(ctx context.Context, orgId string, url string, eventTypes []string) (models.Webhook, error) {
+	w := models.Webhook{
+		OrganizationId: orgId,
+		Url:            url,
+		EventTypes:     eventTypes,
+	}
+
+	err := s.checkUrl(ctx, url)
+	if err != nil {
+		return w, err
+	}
+
+	secret, err := fiddlers.NewWebhookSecret()
+	if err != nil {
+		return w, err
+	}
+	w.Secret = secret
+
+	err = querier(ctx, s.db).QueryRowContext(ctx, `
+		INSERT INTO webhooks (organization_id, url, secret, event_types)
+		VALUES ($1, $2, $3, $4)
+		RETURNING webhook_id, created_at;
+		`,
+		orgId,
+		url,
+		secret,
+		pq.Array(eventTypes),
+	).Scan(
+		&w.WebhookId,
+		&w.CreatedAt,
+	)
+	if err != nil {
+		return w, common.FilterSqlPgError(err)
+	}
+
+	return w, nil
+}
+
+func (s *WebhookServicePgImpl) GetWebhooks(ctx context.Context, orgId string) ([]models.Webhook, error) {
+	webhooks := []models.Webhook{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT
+			webhook_id,
+			organization_id,
+			url,
+			event_types,
+			created_at
+		FROM webhooks
+		WHERE organization_id = $1
+		ORDER BY created_at DESC;
+		`,
+		orgId,
+	)
+	if err != nil {
+		return webhooks, common.FilterSqlPgError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		w := models.Webhook{}
+		err := rows.Scan(
+			&w.WebhookId,
+			&w.OrganizationId,
+			&w.Url,
+			pq.Array(&w.EventTypes),
+			&w.CreatedAt,
+		)
+		if err != nil {
+			return webhooks, err
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (s *WebhookServicePgImpl) DeleteWebhook(ctx context.Context, orgId string, webhookId uint32) error {
+	res, err := querier(ctx, s.db).ExecContext(ctx, `
+		DELETE FROM webhooks
+		WHERE organization_id = $1 AND webhook_id = $2;
+		`,
+		orgId,
+		webhookId,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return common.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *WebhookServicePgImpl) GetDeliveries(ctx context.Context, orgId string, webhookId uint32, limit int, offset int) ([]models.WebhookDelivery, error) {
+	deliveries := []models.WebhookDelivery{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d
+		INNER JOIN webhooks w ON w.webhook_id = d.webhook_id
+		WHERE
+			w.organization_id = $1 AND
+			d.webhook_id = $2
+		ORDER BY d.created_at DESC, d.delivery_id DESC
+		LIMIT $3
+		OFFSET $4;
+		`,
+		orgId,
+		webhookId,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return deliveries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (s *WebhookServicePgImpl) Redeliver(ctx context.Context, orgId string, deliveryId uint64) (models.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(querier(ctx, s.db).QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries AS d (webhook_id, event_type, payload)
+		SELECT src.webhook_id, src.event_type, src.payload
+		FROM webhook_deliveries src
+		INNER JOIN webhooks w ON w.webhook_id = src.webhook_id
+		WHERE
+			src.delivery_id = $1 AND
+			w.organization_id = $2
+		RETURNING `+webhookDeliveryColumns+`;
+		`,
+		deliveryId,
+		orgId,
+	))
+	if err != nil {
+		return d, common.FilterSqlPgError(err)
+	}
+
+	return d, nil
+}
+
+func (s *WebhookServicePgImpl) DeliverPending() error {
+	ctx := context.Background()
+
+	jobs, err := s.claimDueDeliveries(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, j := range jobs {
+		responseStatus, sendErr := s.send(ctx, j)
+		if sendErr != nil {
+			common.Logger(ctx).Warn(fmt.Sprintf("webhook delivery %d attempt %d failed: %s", j.delivery.DeliveryId, j.delivery.Attempts+1, sendErr.Error()))
+		}
+
+		err := s.recordAttempt(ctx, j.delivery, responseStatus, sendErr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recording webhook delivery %d attempt: %w", j.delivery.DeliveryId, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Leases the due deliveries by pushing their next attempt forward, so other
+// replicas skip them while they are being sent
+func (s *WebhookServicePgImpl) claimDueDeliveries(ctx context.Context) ([]webhookJob, error) {
+	jobs := []webhookJob{}
+
+	rows, err := querier(ctx, s.db).QueryContext(ctx, `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET next_attempt_at = NOW() + $1::INT * INTERVAL '1 minute'
+			WHERE delivery_id IN (
+				SELECT delivery_id
+				FROM webhook_deliveries
+				WHERE
+					delivery_status = $2 AND
+					next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT `+webhookDeliveryColumns+`, w.organization_id, w.url, w.secret
+		FROM claimed d
+		INNER JOIN webhooks w ON w.webhook_id = d.webhook_id;
+		`,
+		common.WEBHOOK_LEASE_MINS,
+		common.WEBHOOK_STATUS_PENDING,
+		common.WEBHOOK_BATCH,
+	)
+	if err != nil {
+		return jobs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		j := webhookJob{}
+		d := &j.delivery
+		err := rows.Scan(
+			&d.DeliveryId,
+			&d.WebhookId,
+			&d.EventType,
+			&d.Payload,
+			&d.DeliveryStatus,
+			&d.Attempts,
+			&d.ResponseStatus,
+			&d.LastError,
+			&d.NextAttemptAt,
+			&d.CreatedAt,
+			&d.DeliveredAt,
+			&j.orgId,
+			&j.url,
+			&j.secret,
+		)
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, j)
+	}
+
+	return jobs, rows.Err()
+}
+
+// Posts the signed envelope, anything but a 2xx is a failure. The status is
+// nil when there was no response at all.
+func (s *WebhookServicePgImpl) send(ctx context.Context, j webhookJob) (*int, error) {
+	body, err := json.Marshal(models.WebhookEnvelope{
+		DeliveryId:     j.delivery.DeliveryId,
+		Type:           j.delivery.EventType,
+		OrganizationId: j.orgId,
+		CreatedAt:      j.delivery.CreatedAt,
+		Data:           j.delivery.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", common.PROJECT_NAME+"-webhooks")
+	req.Header.Set(common.WEBHOOK_EVENT_HEADER, j.delivery.EventType)
+	req.Header.Set(common.WEBHOOK_DELIVERY_HEADER, strconv.FormatUint(j.delivery.DeliveryId, 10))
+	req.Header.Set(common.WEBHOOK_SIGNATURE_HEADER, fiddlers.SignWebhookPayload(j.secret, time.Now(), body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	// drained so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	return &res.StatusCode, nil
+}
+
+// Marks the delivery as delivered, or schedules the retry, dead-lettering it
+// after `common.WEBHOOK_MAX_ATTEMPTS` failures
+func (s *WebhookServicePgImpl) recordAttempt(ctx context.Context, d models.WebhookDelivery, responseStatus *int, sendErr error) error {
+	if sendErr == nil {
+		_, err := querier(ctx, s.db).ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET
+				delivery_status = $1,
+				attempts = attempts + 1,
+				response_status = $2,
+				last_error = NULL,
+				delivered_at = NOW()
+			WHERE delivery_id = $3;
+			`,
+			common.WEBHOOK_STATUS_DELIVERED,
+			responseStatus,
+			d.DeliveryId,
+		)
+		return err
+	}
+
+	attempts := int(d.Attempts) + 1
+	status := common.WEBHOOK_STATUS_PENDING
+	if attempts >= common.WEBHOOK_MAX_ATTEMPTS {
+		status = common.WEBHOOK_STATUS_DEAD
+	}
+
+	_, err := querier(ctx, s.db).ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET
+			delivery_status = $1,
+			attempts = $2,
+			response_status = $3,
+			last_error = $4,
+			next_attempt_at = $5
+		WHERE delivery_id = $6;
+		`,
+		status,
+		attempts,
+		responseStatus,
+		sendErr.Error(),
+		time.Now().Add(fiddlers.GetWebhookRetryDelay(attempts)),
+		d.DeliveryId,
+	)
+
+	return err
+}
+
+const webhookDeliveryColumns = `
+			d.delivery_id,
+			d.webhook_id,
+			d.event_type,
+			d.payload,
+			d.delivery_status,
+			d.attempts,
+			d.response_status,
+			d.last_error,
+			d.next_attempt_at,
+			d.created_at,
+			d.delivered_at`
+
+func scanWebhookDelivery(row interface{ Scan(dest ...any) error }) (models.WebhookDelivery, error) {
+	d := models.WebhookDelivery{}
+	err := row.Scan(
+		&d.DeliveryId,
+		&d.WebhookId,
+		&d.EventType,
+		&d.Payload,
+		&d.DeliveryStatus,
+		&d.Attempts,
+		&d.ResponseStatus,
+		&d.LastError,
+		&d.NextAttemptAt,
+		&d.CreatedAt,
+		&d.DeliveredAt,
+	)
+
+	return d, err
+}
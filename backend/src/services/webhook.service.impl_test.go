@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/patos-ufscar/quack-week/common"
+	"github.com/patos-ufscar/quack-week/fiddlers"
+	"github.com/patos-ufscar/quack-week/helpers"
+	"github.com/patos-ufscar/quack-week/models"
+)
+
+type fakeWebhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (f *fakeWebhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	f.requests = append(f.requests, r)
+	f.bodies = append(f.bodies, body)
+
+	status := http.StatusOK
+	if len(f.statuses) > 0 {
+		status = f.statuses[0]
+		f.statuses = f.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestWebhookServicePgImpl(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := helpers.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	db := pgContainer.DB
+	userService := &UserServicePgImpl{db: db}
+	err = userService.CreateUser(ctx, models.User{Email: "owner@email.com", PasswordHash: "hashtest", FirstName: "Test", LastName: "User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := userService.GetUser(ctx, "owner@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO organizations (organization_id, organization_name, owner_user_id)
+		VALUES ('ORG01', 'Patos', $1), ('ORG02', 'Marrecos', $1);
+	`, owner.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiver := &fakeWebhookReceiver{statuses: []int{http.StatusInternalServerError}}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	s := NewWebhookServicePgImpl(db).(*WebhookServicePgImpl)
+	// the receiver listens on the loopback
+	s.allowIp = func(ip net.IP) bool { return true }
+
+	webhook, err := s.CreateWebhook(ctx, "ORG01", server.URL, []string{common.WEBHOOK_EVENT_EVENT_PUBLISHED})
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	if !strings.HasPrefix(webhook.Secret, common.WEBHOOK_SECRET_PREFIX) {
+		t.Errorf("CreateWebhook() secret = %s", webhook.Secret)
+	}
+
+	webhooks, err := s.GetWebhooks(ctx, "ORG01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 1 || webhooks[0].Secret != "" {
+		t.Fatalf("GetWebhooks() = %+v, want 1 without secret", webhooks)
+	}
+
+	// only the subscribed event types of the organization are queued
+	for _, e := range []struct {
+		orgId     string
+		eventType string
+	}{
+		{"ORG01", common.WEBHOOK_EVENT_EVENT_PUBLISHED},
+		{"ORG01", common.WEBHOOK_EVENT_PAYMENT_COMPLETED},
+		{"ORG02", common.WEBHOOK_EVENT_EVENT_PUBLISHED},
+	} {
+		err = enqueueWebhooks(ctx, db, e.orgId, e.eventType, map[string]string{"eventName": "Quack Week"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deliveries, err := s.GetDeliveries(ctx, "ORG01", webhook.WebhookId, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("GetDeliveries() len = %d, want 1", len(deliveries))
+	}
+
+	// failures are retried later
+	err = s.DeliverPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err = s.GetDeliveries(ctx, "ORG01", webhook.WebhookId, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := deliveries[0]
+	if d.DeliveryStatus != common.WEBHOOK_STATUS_PENDING || d.Attempts != 1 || d.ResponseStatus == nil || *d.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("after failure got %+v", d)
+	}
+
+	_, err = db.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = NOW();`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.DeliverPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err = s.GetDeliveries(ctx, "ORG01", webhook.WebhookId, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deliveries[0].DeliveryStatus != common.WEBHOOK_STATUS_DELIVERED || deliveries[0].DeliveredAt == nil {
+		t.Fatalf("after success got %+v", deliveries[0])
+	}
+
+	// the receiver can verify the signature with the secret
+	if len(receiver.requests) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(receiver.requests))
+	}
+	req, body := receiver.requests[1], receiver.bodies[1]
+	signature := req.Header.Get(common.WEBHOOK_SIGNATURE_HEADER)
+	ts, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature != fiddlers.SignWebhookPayload(webhook.Secret, time.Unix(ts, 0), body) {
+		t.Errorf("signature %s does not match the body", signature)
+	}
+	if req.Header.Get(common.WEBHOOK_EVENT_HEADER) != common.WEBHOOK_EVENT_EVENT_PUBLISHED {
+		t.Errorf("event header = %s", req.Header.Get(common.WEBHOOK_EVENT_HEADER))
+	}
+
+	envelope := models.WebhookEnvelope{}
+	err = json.Unmarshal(body, &envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.DeliveryId != d.DeliveryId || envelope.OrganizationId != "ORG01" || envelope.Type != common.WEBHOOK_EVENT_EVENT_PUBLISHED {
+		t.Errorf("envelope = %+v", envelope)
+	}
+
+	_, err = s.Redeliver(ctx, "ORG02", d.DeliveryId)
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("Redeliver() other organization error = %v, want %v", err, common.ErrNotFound)
+	}
+
+	redelivery, err := s.Redeliver(ctx, "ORG01", d.DeliveryId)
+	if err != nil {
+		t.Fatalf("Redeliver() error = %v", err)
+	}
+	if redelivery.DeliveryId == d.DeliveryId || redelivery.DeliveryStatus != common.WEBHOOK_STATUS_PENDING || string(redelivery.Payload) != string(d.Payload) {
+		t.Errorf("Redeliver() = %+v", redelivery)
+	}
+
+	err = s.DeleteWebhook(ctx, "ORG02", webhook.WebhookId)
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("DeleteWebhook() other organization error = %v, want %v", err, common.ErrNotFound)
+	}
+	err = s.DeleteWebhook(ctx, "ORG01", webhook.WebhookId)
+	if err != nil {
+		t.Errorf("DeleteWebhook() error = %v", err)
+	}
+}
+
+func TestWebhookServicePgImpl_ForbiddenUrl(t *testing.T) {
+	ctx := context.Background()
+
+	receiver := &fakeWebhookReceiver{}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	s := NewWebhookServicePgImpl(nil)
+
+	for _, url := range []string{
+		server.URL,
+		"http://localhost/hooks",
+		"http://10.0.0.1/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]:8080/hooks",
+	} {
+		_, err := s.CreateWebhook(ctx, "ORG01", url, []string{common.WEBHOOK_EVENT_EVENT_PUBLISHED})
+		if !errors.Is(err, common.ErrWebhookUrlForbidden) {
+			t.Errorf("CreateWebhook(%s) error = %v, want %v", url, err, common.ErrWebhookUrlForbidden)
+		}
+	}
+
+	// a host resolving somewhere else after being created is caught on dial
+	status, err := s.(*WebhookServicePgImpl).send(ctx, webhookJob{url: server.URL, secret: "whsec_test"})
+	if !errors.Is(err, common.ErrWebhookUrlForbidden) || status != nil {
+		t.Errorf("send() = %v, %v, want %v", status, err, common.ErrWebhookUrlForbidden)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.requests) != 0 {
+		t.Errorf("receiver got %d requests, want 0", len(receiver.requests))
+	}
+}